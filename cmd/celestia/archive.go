@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/adlerjohn/celestia-node-stateless/pkg/stateless"
+	"github.com/celestiaorg/celestia-app/v3/pkg/da"
+	"github.com/celestiaorg/rsmt2d"
+	tmbytes "github.com/tendermint/tendermint/libs/bytes"
+)
+
+// archivedBlock reports the files archive wrote for a height.
+type archivedBlock struct {
+	Height   int64            `json:"height"`
+	CAR      string           `json:"car"`
+	Header   string           `json:"header"`
+	DataRoot tmbytes.HexBytes `json:"data_root"`
+}
+
+func (a *archivedBlock) String() string {
+	return fmt.Sprintf("height %d: wrote %s and %s, data root %s", a.Height, a.CAR, a.Header, a.DataRoot)
+}
+
+// archiveBlock writes the extended square eds of the block eh heads into
+// dir as <height>.car, and eh as <height>.header.json in celestia-node's
+// JSON encoding. Neither file is moved into place until the CAR has been
+// read back and the DAH of the square it holds matches the DAH of the
+// header decoded from its JSON, so that the two always agree.
+func archiveBlock(dir string, eh *stateless.ExtendedHeader, eds *rsmt2d.ExtendedDataSquare) (*archivedBlock, error) {
+	headerJSON, err := encodeNodeHeader(eh, "json")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	base := filepath.Join(dir, strconv.FormatInt(eh.Height, 10))
+	carFile, err := createAtomicFile(base + ".car")
+	if err != nil {
+		return nil, err
+	}
+	headerFile, err := createAtomicFile(base + ".header.json")
+	if err != nil {
+		carFile.abort()
+		return nil, err
+	}
+	if err := writeArchive(carFile, headerFile, eds, headerJSON); err != nil {
+		carFile.abort()
+		headerFile.abort()
+		return nil, err
+	}
+	if err := carFile.commit(); err != nil {
+		headerFile.abort()
+		return nil, err
+	}
+	if err := headerFile.commit(); err != nil {
+		return nil, err
+	}
+	return &archivedBlock{
+		Height:   eh.Height,
+		CAR:      carFile.path,
+		Header:   headerFile.path,
+		DataRoot: eh.DAH.Hash(),
+	}, nil
+}
+
+// writeArchive writes eds and headerJSON to their files and checks the CAR
+// read back against the header's DAH.
+func writeArchive(carFile, headerFile *atomicFile, eds *rsmt2d.ExtendedDataSquare, headerJSON []byte) error {
+	if err := stateless.WriteCAR(carFile, eds); err != nil {
+		return err
+	}
+	if _, err := headerFile.Write(append(headerJSON, '\n')); err != nil {
+		return err
+	}
+
+	archived, err := readCARFile(carFile.Name())
+	if err != nil {
+		return err
+	}
+	carDAH, err := da.NewDataAvailabilityHeader(archived)
+	if err != nil {
+		return err
+	}
+	nodeHeader, err := nodeHeaderFormats["json"].unmarshal(headerJSON)
+	if err != nil {
+		return err
+	}
+	if !carDAH.Equals(nodeHeader.DAH) {
+		return verificationFailed(fmt.Errorf("CAR roots hash to %X, but the header's DAH hashes to %X",
+			carDAH.Hash(), nodeHeader.DAH.Hash()))
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// atomicFile is written under a temporary name next to its destination and
+// only renamed into place by commit, so that an interrupted write never
+// leaves a partial file at the destination.
+type atomicFile struct {
+	*os.File
+	path    string
+	written int64
+}
+
+func createAtomicFile(path string) (*atomicFile, error) {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return nil, err
+	}
+	return &atomicFile{File: f, path: path}, nil
+}
+
+func (f *atomicFile) Write(p []byte) (int, error) {
+	n, err := f.File.Write(p)
+	f.written += int64(n)
+	return n, err
+}
+
+// commit flushes the file to disk and moves it to its destination.
+func (f *atomicFile) commit() error {
+	if err := f.Sync(); err != nil {
+		f.abort()
+		return err
+	}
+	if err := f.Chmod(0o644); err != nil {
+		f.abort()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), f.path); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
+
+// abort discards the file, leaving the destination untouched.
+func (f *atomicFile) abort() {
+	f.Close()
+	os.Remove(f.Name())
+}
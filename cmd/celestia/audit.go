@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/celestiaorg/celestia-app/v3/pkg/da"
+	"github.com/celestiaorg/celestia-app/v3/pkg/wrapper"
+	"github.com/celestiaorg/rsmt2d"
+	tmbytes "github.com/tendermint/tendermint/libs/bytes"
+)
+
+// axisAudit is the root of one row or column of an extended square,
+// computed independently, next to the root the DAH has for it.
+type axisAudit struct {
+	Index   uint             `json:"index"`
+	Root    tmbytes.HexBytes `json:"root"`
+	DAHRoot tmbytes.HexBytes `json:"dah_root"`
+	OK      bool             `json:"ok"`
+}
+
+// axisAudits is the audit of every row, or every column, of a square.
+type axisAudits struct {
+	Axis  rsmt2d.Axis `json:"-"`
+	Roots []axisAudit `json:"roots"`
+}
+
+// auditAxes rebuilds the NMT of every row of eds, or every column if axis
+// is rsmt2d.Col, with celestia-app's wrapper and compares its root to the
+// one dah has for it. The wrapper is used whatever the --nmt-* flags, so
+// that the DAH is checked against celestia-app's construction rather than
+// against the trees that built it.
+func auditAxes(eds *rsmt2d.ExtendedDataSquare, dah *da.DataAvailabilityHeader, axis rsmt2d.Axis) (*axisAudits, error) {
+	width := eds.Width()
+	dahRoots, axisShares := dah.RowRoots, eds.Row
+	if axis == rsmt2d.Col {
+		dahRoots, axisShares = dah.ColumnRoots, eds.Col
+	}
+	if len(dahRoots) != int(width) {
+		return nil, fmt.Errorf("DAH has %d %s roots, square is %d wide", len(dahRoots), axis, width)
+	}
+	audits := &axisAudits{Axis: axis, Roots: make([]axisAudit, width)}
+	for i := uint(0); i < width; i++ {
+		root, err := axisRoot(axisShares(i), i)
+		if err != nil {
+			return nil, fmt.Errorf("%s %d: %w", axis, i, err)
+		}
+		audits.Roots[i] = axisAudit{
+			Index:   i,
+			Root:    root,
+			DAHRoot: dahRoots[i],
+			OK:      bytes.Equal(root, dahRoots[i]),
+		}
+	}
+	return audits, nil
+}
+
+// axisRoot returns the NMT root of shares, the row or column at axisIndex
+// of an extended square, built with celestia-app's wrapper.
+func axisRoot(shares [][]byte, axisIndex uint) ([]byte, error) {
+	tree := wrapper.NewErasuredNamespacedMerkleTree(uint64(len(shares)/2), axisIndex)
+	for _, sh := range shares {
+		if err := tree.Push(sh); err != nil {
+			return nil, err
+		}
+	}
+	return tree.Root()
+}
+
+// mismatches returns the number of roots that don't match the DAH.
+func (a *axisAudits) mismatches() int {
+	n := 0
+	for _, root := range a.Roots {
+		if !root.OK {
+			n++
+		}
+	}
+	return n
+}
+
+func (a *axisAudits) String() string {
+	var b strings.Builder
+	for i, root := range a.Roots {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		if root.OK {
+			fmt.Fprintf(&b, "%s %d: OK", a.Axis, root.Index)
+		} else {
+			fmt.Fprintf(&b, "%s %d: MISMATCH, computed %X, DAH has %X", a.Axis, root.Index, root.Root, root.DAHRoot)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// authTokenEnv is the environment variable holding the bearer token for
+// core endpoints behind an authenticating gateway. Reading it from the
+// environment keeps the token out of shell history and process listings.
+const authTokenEnv = "CELESTIA_CORE_TOKEN"
+
+// withAuthToken returns dial options installing unary and stream client
+// interceptors that attach token as an `authorization` header to every
+// outgoing call, including the BlockByHeight stream.
+func withAuthToken(token string) []grpc.DialOption {
+	if !strings.HasPrefix(token, "Bearer ") {
+		token = "Bearer " + token
+	}
+	unary := func(
+		ctx context.Context,
+		method string,
+		req, reply any,
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", token)
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	stream := func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		streamer grpc.Streamer,
+		opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", token)
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(unary),
+		grpc.WithChainStreamInterceptor(stream),
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/adlerjohn/celestia-node-stateless/pkg/stateless"
+)
+
+// readHeightFile reads the heights listed in path, one per line. Blank
+// lines and lines starting with # are ignored. Other lines that aren't a
+// positive height are logged and skipped, and their count is returned
+// with the heights.
+func readHeightFile(path string) ([]int64, int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	var heights []int64
+	skipped := 0
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		height, err := strconv.ParseInt(text, 10, 64)
+		if err != nil || height <= 0 {
+			slog.Warn("skipping malformed height", "file", path, "line", line, "text", text)
+			skipped++
+			continue
+		}
+		heights = append(heights, height)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, err
+	}
+	return heights, skipped, nil
+}
+
+// prefetchedBlock is a blockSource serving a block fetched ahead of time at
+// height, and passing every other fetch on to the source it wraps.
+type prefetchedBlock struct {
+	blockSource
+	height string
+	block  *stateless.SignedBlock
+}
+
+func (p *prefetchedBlock) GetSignedBlock(ctx context.Context, h string) (*stateless.SignedBlock, error) {
+	if h == p.height {
+		return p.block, nil
+	}
+	return p.blockSource.GetSignedBlock(ctx, h)
+}
+
+// prefetchBlocks calls fetch for every height in heights, with up to
+// concurrency calls in flight, and passes each block or fetch error to use
+// in the order of heights. An error from use stops the batch.
+func prefetchBlocks(
+	heights []int64,
+	concurrency int,
+	fetch func(height int64) (*stateless.SignedBlock, error),
+	use func(height int64, block *stateless.SignedBlock, err error) error,
+) error {
+	if concurrency < 1 {
+		return fmt.Errorf("concurrency must be at least 1, got %d", concurrency)
+	}
+	type fetched struct {
+		block *stateless.SignedBlock
+		err   error
+	}
+	// Queued in order like the blocks of a range, bounding those in flight
+	queue := make(chan chan fetched, concurrency-1)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		defer close(queue)
+		for _, height := range heights {
+			res := make(chan fetched, 1)
+			select {
+			case queue <- res:
+			case <-done:
+				return
+			}
+			go func(height int64) {
+				block, err := fetch(height)
+				res <- fetched{block, err}
+			}(height)
+		}
+	}()
+
+	i := 0
+	for res := range queue {
+		r := <-res
+		if err := use(heights[i], r.block, r.err); err != nil {
+			return err
+		}
+		i++
+	}
+	return nil
+}
+
+// heightsSummary is the outcome of a batch or range over many heights.
+type heightsSummary struct {
+	Succeeded int     `json:"succeeded"`
+	Failed    []int64 `json:"failed"`
+	// Skipped is the number of malformed lines of a batch's height file.
+	Skipped int `json:"skipped,omitempty"`
+}
+
+func (b *heightsSummary) String() string {
+	s := fmt.Sprintf("%d succeeded, %d failed", b.Succeeded, len(b.Failed))
+	if b.Skipped > 0 {
+		s += fmt.Sprintf(", %d malformed lines skipped", b.Skipped)
+	}
+	if len(b.Failed) > 0 {
+		failed := make([]string, len(b.Failed))
+		for i, height := range b.Failed {
+			failed[i] = strconv.FormatInt(height, 10)
+		}
+		s += "\nfailed heights: " + strings.Join(failed, ", ")
+	}
+	return s
+}
+
+// runBatch runs the command line args against every height in heights,
+// in order, with the height as the command's first argument. The blocks
+// are fetched from src up to concurrency at a time ahead of the command
+// that needs them. A height whose block can't be fetched or whose command
+// fails is logged and counted in the summary, and the batch goes on.
+func (s *session) runBatch(src blockSource, heights []int64, concurrency int, args []string) (*heightsSummary, error) {
+	summary := new(heightsSummary)
+	saved := s.source
+	defer func() { s.source = saved }()
+	fetch := func(height int64) (*stateless.SignedBlock, error) {
+		return s.getSignedBlock(src, strconv.FormatInt(height, 10))
+	}
+	err := prefetchBlocks(heights, concurrency, fetch, func(height int64, block *stateless.SignedBlock, err error) error {
+		if errors.Is(err, errDryRun) {
+			return err
+		}
+		h := strconv.FormatInt(height, 10)
+		if err == nil {
+			s.source = &prefetchedBlock{src, h, block}
+			err = s.runCommand(append([]string{args[0], h}, args[1:]...))
+		}
+		if err != nil {
+			slog.Error("batch command failed", "height", height, "err", err)
+			summary.Failed = append(summary.Failed, height)
+			return nil
+		}
+		summary.Succeeded++
+		return nil
+	})
+	return summary, err
+}
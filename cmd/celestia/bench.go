@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adlerjohn/celestia-node-stateless/pkg/stateless"
+	"github.com/celestiaorg/celestia-app/v3/pkg/da"
+	libsquare "github.com/celestiaorg/go-square/v2"
+	libshare "github.com/celestiaorg/go-square/v2/share"
+	"github.com/celestiaorg/rsmt2d"
+)
+
+// extendFunc extends the square bench extend measures.
+type extendFunc func() (*rsmt2d.ExtendedDataSquare, error)
+
+// benchReport is the throughput of repeated extensions of one square.
+type benchReport struct {
+	Codec string `json:"codec"`
+	// Concurrency is --extend-concurrency, 0 if unbounded.
+	Concurrency  int           `json:"concurrency"`
+	SquareSize   int           `json:"square_size"`
+	Iterations   int           `json:"iterations"`
+	SharesPerSec float64       `json:"shares_per_sec"`
+	Average      time.Duration `json:"average_ns"`
+	P95          time.Duration `json:"p95_ns"`
+}
+
+// benchExtend runs extend iterations times, after one untimed run to warm
+// up, and reports its latency and the original shares extended per second.
+// Each run includes computing the DAH, since the row and column trees are
+// only built once their roots are asked for.
+func (s *session) benchExtend(iterations int, extend extendFunc) (*benchReport, error) {
+	if iterations <= 0 {
+		return nil, usageError(fmt.Errorf("--iterations must be positive, got %d", iterations))
+	}
+	eds, err := extend()
+	if err != nil {
+		return nil, err
+	}
+	squareSize := int(eds.Width() / 2)
+	durations := make([]time.Duration, iterations)
+	var total time.Duration
+	for i := range durations {
+		start := time.Now()
+		eds, err := extend()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := da.NewDataAvailabilityHeader(eds); err != nil {
+			return nil, err
+		}
+		durations[i] = time.Since(start)
+		total += durations[i]
+	}
+	slices.Sort(durations)
+	return &benchReport{
+		Codec:        s.extendCodec.Name(),
+		Concurrency:  s.extendConcurrency,
+		SquareSize:   squareSize,
+		Iterations:   iterations,
+		SharesPerSec: float64(squareSize*squareSize*iterations) / total.Seconds(),
+		Average:      total / time.Duration(iterations),
+		// The smallest duration no less than 95% of them
+		P95: durations[(iterations*95+99)/100-1],
+	}, nil
+}
+
+func (r *benchReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "codec: %s\n", r.Codec)
+	if r.Concurrency > 0 {
+		fmt.Fprintf(&b, "concurrency: %d\n", r.Concurrency)
+	} else {
+		b.WriteString("concurrency: unbounded\n")
+	}
+	fmt.Fprintf(&b, "square size: %d (%d shares)\n", r.SquareSize, r.SquareSize*r.SquareSize)
+	fmt.Fprintf(&b, "iterations: %d\n", r.Iterations)
+	fmt.Fprintf(&b, "shares/sec: %.0f\n", r.SharesPerSec)
+	fmt.Fprintf(&b, "average: %s\n", r.Average.Round(time.Microsecond))
+	fmt.Fprintf(&b, "p95: %s", r.P95.Round(time.Microsecond))
+	return b.String()
+}
+
+// extendSignedBlock returns an extendFunc extending the data of block.
+func (s *session) extendSignedBlock(block *stateless.SignedBlock) extendFunc {
+	extender := s.extenderFor(s.appVersion(block.Header))
+	return func() (*rsmt2d.ExtendedDataSquare, error) {
+		return extender.Extend(block.Data)
+	}
+}
+
+// randomSquare returns the shares of a squareSize-wide original data square
+// of random data, under random namespaces in ascending order as the row
+// and column trees require.
+func randomSquare(squareSize int, rng *rand.Rand) ([][]byte, error) {
+	if squareSize <= 0 || !libsquare.IsPowerOfTwo(squareSize) {
+		return nil, usageError(fmt.Errorf("--size must be a positive power of 2, got %d", squareSize))
+	}
+	shares := make([][]byte, squareSize*squareSize)
+	for i := range shares {
+		shares[i] = make([]byte, libshare.ShareSize)
+		// A version 0 namespace: a zero version byte and zero prefix
+		ns := shares[i][:libshare.NamespaceSize]
+		rng.Read(ns[libshare.NamespaceSize-libshare.NamespaceVersionZeroIDSize:])
+		rng.Read(shares[i][libshare.NamespaceSize:])
+	}
+	slices.SortFunc(shares, func(a, b []byte) int {
+		return bytes.Compare(a[:libshare.NamespaceSize], b[:libshare.NamespaceSize])
+	})
+	return shares, nil
+}
+
+// extendRandomSquare returns an extendFunc extending the shares
+// of a random squareSize-wide square under the latest app version, or the
+// --app-version override.
+func (s *session) extendRandomSquare(squareSize int, seed int64) (extendFunc, error) {
+	shares, err := randomSquare(squareSize, rand.New(rand.NewSource(seed)))
+	if err != nil {
+		return nil, err
+	}
+	extender := s.extenderFor(s.headerlessAppVersion())
+	return func() (*rsmt2d.ExtendedDataSquare, error) {
+		return extender.ExtendShares(shares)
+	}, nil
+}
+
+// fetchBenchReport is the wall-clock time of fetching one block from core
+// with the pipelined and the sequential reassembly of its parts.
+type fetchBenchReport struct {
+	Height     int64         `json:"height"`
+	Iterations int           `json:"iterations"`
+	Pipelined  time.Duration `json:"pipelined_ns"`
+	Sequential time.Duration `json:"sequential_ns"`
+}
+
+// benchFetch fetches the block at height h from core iterations times with
+// each reassembly, after one untimed fetch to warm up, and reports their
+// average. The two alternate so that both see the same network conditions.
+// core is left reassembling sequentially or not as it was found.
+func (s *session) benchFetch(core *stateless.CoreAccessor, h string, iterations int, sequential bool) (*fetchBenchReport, error) {
+	if iterations <= 0 {
+		return nil, usageError(fmt.Errorf("--iterations must be positive, got %d", iterations))
+	}
+	defer core.SetSequentialReassembly(sequential)
+	fetch := func(h string) (*stateless.SignedBlock, error) {
+		return s.fetchOnce(func(ctx context.Context) (*stateless.SignedBlock, error) {
+			return core.GetSignedBlock(ctx, h)
+		})
+	}
+	block, err := fetch(h)
+	if err != nil {
+		return nil, err
+	}
+	// Fetch the same block even if h is "latest"
+	h = strconv.FormatInt(block.Header.Height, 10)
+
+	var pipelined, seq time.Duration
+	for range iterations {
+		for _, sequential := range []bool{false, true} {
+			core.SetSequentialReassembly(sequential)
+			start := time.Now()
+			if _, err := fetch(h); err != nil {
+				return nil, err
+			}
+			if sequential {
+				seq += time.Since(start)
+			} else {
+				pipelined += time.Since(start)
+			}
+		}
+	}
+	return &fetchBenchReport{
+		Height:     block.Header.Height,
+		Iterations: iterations,
+		Pipelined:  pipelined / time.Duration(iterations),
+		Sequential: seq / time.Duration(iterations),
+	}, nil
+}
+
+func (r *fetchBenchReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "height: %d\n", r.Height)
+	fmt.Fprintf(&b, "iterations: %d\n", r.Iterations)
+	fmt.Fprintf(&b, "pipelined: %s\n", r.Pipelined.Round(time.Microsecond))
+	fmt.Fprintf(&b, "sequential: %s\n", r.Sequential.Round(time.Microsecond))
+	fmt.Fprintf(&b, "speedup: %.2fx", float64(r.Sequential)/float64(r.Pipelined))
+	return b.String()
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/celestiaorg/celestia-app/v3/pkg/proof"
+	"github.com/celestiaorg/go-square/v2/inclusion"
+	libshare "github.com/celestiaorg/go-square/v2/share"
+	"github.com/celestiaorg/rsmt2d"
+	"github.com/tendermint/tendermint/crypto/merkle"
+	tmbytes "github.com/tendermint/tendermint/libs/bytes"
+)
+
+// blobsByNamespace reconstructs the blobs under ns from the original data
+// square of eds. A square holding no shares of ns yields an empty list.
+func blobsByNamespace(eds *rsmt2d.ExtendedDataSquare, ns libshare.Namespace) ([]*libshare.Blob, error) {
+	shares, err := originalShares(eds)
+	if err != nil {
+		return nil, err
+	}
+	var matching []libshare.Share
+	for _, s := range shares {
+		if s.Namespace().Equals(ns) {
+			matching = append(matching, s)
+		}
+	}
+	if len(matching) == 0 {
+		return []*libshare.Blob{}, nil
+	}
+	return libshare.ParseBlobs(matching)
+}
+
+// blobSummary describes a reconstructed blob.
+type blobSummary struct {
+	Namespace  tmbytes.HexBytes `json:"namespace"`
+	Commitment tmbytes.HexBytes `json:"commitment"`
+	DataLen    int              `json:"data_len"`
+}
+
+type blobSummaries []blobSummary
+
+// summarizeBlobs computes the share commitment of every blob under the
+// given subtree root threshold.
+func summarizeBlobs(blobs []*libshare.Blob, subtreeRootThreshold int) (blobSummaries, error) {
+	summaries := make(blobSummaries, len(blobs))
+	for i, blob := range blobs {
+		commitment, err := inclusion.CreateCommitment(blob, merkle.HashFromByteSlices, subtreeRootThreshold)
+		if err != nil {
+			return nil, fmt.Errorf("blob %d: %w", i, err)
+		}
+		summaries[i] = blobSummary{
+			Namespace:  blob.Namespace().Bytes(),
+			Commitment: commitment,
+			DataLen:    len(blob.Data()),
+		}
+	}
+	return summaries, nil
+}
+
+func (s blobSummaries) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "blobs: %d", len(s))
+	for i, blob := range s {
+		fmt.Fprintf(&b, "\n%d: namespace %x commitment %x data length %d",
+			i, []byte(blob.Namespace), []byte(blob.Commitment), blob.DataLen)
+	}
+	return b.String()
+}
+
+// committedBlob is the blob found under a share commitment.
+type committedBlob struct {
+	Index      int              `json:"index"`
+	Namespace  tmbytes.HexBytes `json:"namespace"`
+	Commitment tmbytes.HexBytes `json:"commitment"`
+	Data       tmbytes.HexBytes `json:"data"`
+}
+
+// blobByCommitment returns the blob among blobs whose share commitment,
+// under the given subtree root threshold, is commitment, or nil if none
+// has it.
+func blobByCommitment(blobs []*libshare.Blob, commitment []byte, subtreeRootThreshold int) (*committedBlob, error) {
+	for i, blob := range blobs {
+		c, err := inclusion.CreateCommitment(blob, merkle.HashFromByteSlices, subtreeRootThreshold)
+		if err != nil {
+			return nil, fmt.Errorf("blob %d: %w", i, err)
+		}
+		if bytes.Equal(c, commitment) {
+			return &committedBlob{
+				Index:      i,
+				Namespace:  blob.Namespace().Bytes(),
+				Commitment: c,
+				Data:       blob.Data(),
+			}, nil
+		}
+	}
+	return nil, nil
+}
+
+func (b *committedBlob) String() string {
+	return fmt.Sprintf("blob %d: namespace %x commitment %x data length %d\ndata: %x",
+		b.Index, []byte(b.Namespace), []byte(b.Commitment), len(b.Data), []byte(b.Data))
+}
+
+// blobShareRange returns the range of shares of the original data square
+// of eds that hold the blob at index among the blobs under ns.
+func blobShareRange(eds *rsmt2d.ExtendedDataSquare, ns libshare.Namespace, index int) (libshare.Range, error) {
+	shares, err := originalShares(eds)
+	if err != nil {
+		return libshare.Range{}, err
+	}
+	found := 0
+	for i, s := range shares {
+		if !s.Namespace().Equals(ns) || !s.IsSequenceStart() || s.IsPadding() {
+			continue
+		}
+		if found == index {
+			return libshare.NewRange(i, i+libshare.SparseSharesNeeded(s.SequenceLen())), nil
+		}
+		found++
+	}
+	return libshare.Range{}, fmt.Errorf("no blob %d under namespace %x, which has %d blobs", index, ns.Bytes(), found)
+}
+
+// blobProof proves a blob included under a block's data root. It encodes
+// to JSON as the ShareProof celestia-app and celestia-node verifiers take:
+// the blob's shares, an NMT proof of them for every row they span, and a
+// Merkle proof of each of those row roots to the data root.
+type blobProof struct {
+	proof.ShareProof
+	index    int
+	dataRoot []byte
+}
+
+// newBlobProof proves the blob at index under ns in eds, and checks the
+// proof against dataRoot.
+func newBlobProof(eds *rsmt2d.ExtendedDataSquare, ns libshare.Namespace, index int, dataRoot []byte) (*blobProof, error) {
+	shareRange, err := blobShareRange(eds, ns, index)
+	if err != nil {
+		return nil, err
+	}
+	sp, err := proof.NewShareInclusionProofFromEDS(eds, ns, shareRange)
+	if err != nil {
+		return nil, err
+	}
+	if err := sp.Validate(dataRoot); err != nil {
+		return nil, fmt.Errorf("blob %d proof doesn't verify against data root %X: %w", index, dataRoot, err)
+	}
+	return &blobProof{ShareProof: sp, index: index, dataRoot: dataRoot}, nil
+}
+
+func (p *blobProof) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "blob %d: %d shares in rows %d to %d\n",
+		p.index, len(p.Data), p.RowProof.StartRow, p.RowProof.EndRow)
+	fmt.Fprintf(&b, "data root: %X", p.dataRoot)
+	for i, sp := range p.ShareProofs {
+		fmt.Fprintf(&b, "\nrow %d: root %x, shares %d to %d, siblings %d",
+			p.RowProof.StartRow+uint32(i), p.RowProof.RowRoots[i], sp.Start, sp.End, len(sp.Nodes))
+	}
+	return b.String()
+}
+
+// blobMinProof is the least a verifier needs to reconstruct one blob and
+// check it against a block's data root, rather than every share of its
+// namespace: the blob's own shares, and for each row they span an NMT range
+// proof, whose siblings are the O(log n) subtree roots of the rest of the
+// row, and a Merkle proof of the row root. The blob's share commitment
+// comes with the subtree roots it is the Merkle root of, the subtrees of
+// the row trees the blob's shares are aligned to in the square.
+type blobMinProof struct {
+	Index        int                `json:"index"`
+	Commitment   tmbytes.HexBytes   `json:"commitment"`
+	SubtreeRoots []tmbytes.HexBytes `json:"subtree_roots"`
+	Proof        proof.ShareProof   `json:"proof"`
+	dataRoot     []byte
+}
+
+// newBlobMinProof proves the blob at index under ns in eds, and checks
+// that the proof verifies against dataRoot and reconstructs the blob and
+// its commitment under the given subtree root threshold.
+func newBlobMinProof(
+	eds *rsmt2d.ExtendedDataSquare,
+	ns libshare.Namespace,
+	index int,
+	dataRoot []byte,
+	subtreeRootThreshold int,
+) (*blobMinProof, error) {
+	blobs, err := blobsByNamespace(eds, ns)
+	if err != nil {
+		return nil, err
+	}
+	if index >= len(blobs) {
+		return nil, fmt.Errorf("no blob %d under namespace %x, which has %d blobs", index, ns.Bytes(), len(blobs))
+	}
+	subtreeRoots, err := inclusion.GenerateSubtreeRoots(blobs[index], subtreeRootThreshold)
+	if err != nil {
+		return nil, fmt.Errorf("blob %d: %w", index, err)
+	}
+	bp, err := newBlobProof(eds, ns, index, dataRoot)
+	if err != nil {
+		return nil, err
+	}
+	p := &blobMinProof{
+		Index:        index,
+		Commitment:   merkle.HashFromByteSlices(subtreeRoots),
+		SubtreeRoots: make([]tmbytes.HexBytes, len(subtreeRoots)),
+		Proof:        bp.ShareProof,
+		dataRoot:     dataRoot,
+	}
+	for i, root := range subtreeRoots {
+		p.SubtreeRoots[i] = root
+	}
+	if _, err := p.verify(dataRoot, subtreeRootThreshold); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// verify checks that p's shares are included under dataRoot and hold
+// exactly one blob, whose subtree roots and share commitment are p's, and
+// returns the blob.
+func (p *blobMinProof) verify(dataRoot []byte, subtreeRootThreshold int) (*libshare.Blob, error) {
+	if err := p.Proof.Validate(dataRoot); err != nil {
+		return nil, fmt.Errorf("blob %d proof doesn't verify against data root %X: %w", p.Index, dataRoot, err)
+	}
+	shares, err := libshare.FromBytes(p.Proof.Data)
+	if err != nil {
+		return nil, err
+	}
+	blobs, err := libshare.ParseBlobs(shares)
+	if err != nil {
+		return nil, fmt.Errorf("blob %d shares don't parse: %w", p.Index, err)
+	}
+	if len(blobs) != 1 {
+		return nil, fmt.Errorf("blob %d proof holds %d blobs, not one", p.Index, len(blobs))
+	}
+	subtreeRoots, err := inclusion.GenerateSubtreeRoots(blobs[0], subtreeRootThreshold)
+	if err != nil {
+		return nil, err
+	}
+	if len(subtreeRoots) != len(p.SubtreeRoots) {
+		return nil, fmt.Errorf("blob %d has %d subtree roots, the proof %d", p.Index, len(subtreeRoots), len(p.SubtreeRoots))
+	}
+	for i, root := range subtreeRoots {
+		if !bytes.Equal(root, p.SubtreeRoots[i]) {
+			return nil, fmt.Errorf("blob %d subtree root %d is %x, the proof's %x", p.Index, i, root, []byte(p.SubtreeRoots[i]))
+		}
+	}
+	// The share commitment is the Merkle root of the subtree roots
+	if commitment := merkle.HashFromByteSlices(subtreeRoots); !bytes.Equal(commitment, p.Commitment) {
+		return nil, fmt.Errorf("blob %d commitment is %x, the proof's %x", p.Index, commitment, []byte(p.Commitment))
+	}
+	return blobs[0], nil
+}
+
+func (p *blobMinProof) String() string {
+	nodes := 0
+	for _, sp := range p.Proof.ShareProofs {
+		nodes += len(sp.Nodes)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "blob %d: %d shares in rows %d to %d, %d NMT proof nodes\n",
+		p.Index, len(p.Proof.Data), p.Proof.RowProof.StartRow, p.Proof.RowProof.EndRow, nodes)
+	fmt.Fprintf(&b, "commitment: %x, from %d subtree roots\n", []byte(p.Commitment), len(p.SubtreeRoots))
+	fmt.Fprintf(&b, "data root: %X", p.dataRoot)
+	return b.String()
+}
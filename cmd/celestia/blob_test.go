@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/adlerjohn/celestia-node-stateless/pkg/stateless"
+	"github.com/celestiaorg/celestia-app/v3/pkg/appconsts"
+	"github.com/celestiaorg/celestia-app/v3/pkg/da"
+	"github.com/celestiaorg/go-square/v2/inclusion"
+	libshare "github.com/celestiaorg/go-square/v2/share"
+	"github.com/celestiaorg/go-square/v2/tx"
+	"github.com/tendermint/tendermint/crypto/merkle"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestBlobMinProof(t *testing.T) {
+	ns := libshare.MustNewV0Namespace([]byte("minproof"))
+	var (
+		blobs []*libshare.Blob
+		txs   types.Txs
+	)
+	for i, size := range []int{100, 5000, 40000} {
+		blob, err := libshare.NewV0Blob(ns, bytes.Repeat([]byte{byte(i + 1)}, size))
+		if err != nil {
+			t.Fatal(err)
+		}
+		blobTx, err := tx.MarshalBlobTx([]byte{byte(i)}, blob)
+		if err != nil {
+			t.Fatal(err)
+		}
+		blobs, txs = append(blobs, blob), append(txs, blobTx)
+	}
+	eds, err := stateless.ExtendBlock(&types.Data{Txs: txs}, appconsts.LatestVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dah, err := da.NewDataAvailabilityHeader(eds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	threshold := stateless.SubtreeRootThreshold(appconsts.LatestVersion)
+
+	for index, want := range blobs {
+		p, err := newBlobMinProof(eds, ns, index, dah.Hash(), threshold)
+		if err != nil {
+			t.Fatalf("blob %d: %v", index, err)
+		}
+		if got, want := len(p.Proof.Data), libshare.SparseSharesNeeded(uint32(len(want.Data()))); got != want {
+			t.Errorf("blob %d: %d shares, want %d", index, got, want)
+		}
+		commitment, err := inclusion.CreateCommitment(want, merkle.HashFromByteSlices, threshold)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(p.Commitment, commitment) {
+			t.Errorf("blob %d: commitment %x, want %x", index, []byte(p.Commitment), commitment)
+		}
+		blob, err := p.verify(dah.Hash(), threshold)
+		if err != nil {
+			t.Fatalf("blob %d: %v", index, err)
+		}
+		if !bytes.Equal(blob.Data(), want.Data()) {
+			t.Errorf("blob %d: reconstructed data differs", index)
+		}
+	}
+
+	if _, err := newBlobMinProof(eds, ns, len(blobs), dah.Hash(), threshold); err == nil {
+		t.Error("proved a blob past the last")
+	}
+	for name, tamper := range map[string]func(p *blobMinProof){
+		"share": func(p *blobMinProof) {
+			p.Proof.Data[1] = bytes.Clone(p.Proof.Data[1])
+			p.Proof.Data[1][100] ^= 1
+		},
+		"commitment": func(p *blobMinProof) { p.Commitment = bytes.Repeat([]byte{1}, 32) },
+		"subtree root": func(p *blobMinProof) {
+			p.SubtreeRoots[0] = bytes.Clone(p.SubtreeRoots[0])
+			p.SubtreeRoots[0][0] ^= 1
+		},
+		"extra share": func(p *blobMinProof) { p.Proof.Data = append(p.Proof.Data, p.Proof.Data[0]) },
+	} {
+		p, err := newBlobMinProof(eds, ns, 1, dah.Hash(), threshold)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tamper(p)
+		if _, err := p.verify(dah.Hash(), threshold); err == nil {
+			t.Errorf("tampered %s verifies", name)
+		}
+	}
+}
+
+// TestBlobArgs checks that the blob commands reject bad arguments as usage
+// errors before they fetch the block, from a core that has none.
+func TestBlobArgs(t *testing.T) {
+	addr := startFakeCore(t)
+	ns := hex.EncodeToString(libshare.MustNewV0Namespace([]byte("args")).Bytes())
+	for _, tc := range []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"blob-proof index not a number", []string{"blob-proof", "1", ns, "first"}, `invalid blob index "first"`},
+		{"blob-proof negative index", []string{"blob-proof", "--", "1", ns, "-1"}, `invalid blob index "-1"`},
+		{"blob-min-proof negative index", []string{"blob-min-proof", "--", "1", ns, "-1"}, `invalid blob index "-1"`},
+		{"blob-by-commitment not hex", []string{"blob-by-commitment", "1", ns, "0xab"}, `invalid commitment "0xab": not hex`},
+		{"blob-by-commitment short", []string{"blob-by-commitment", "1", ns, "abcd"}, "wrong length, 2 bytes instead of 32"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := runCLI(t, addr, tc.args...)
+			checkUsageError(t, err, tc.want)
+		})
+	}
+}
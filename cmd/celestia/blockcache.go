@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/adlerjohn/celestia-node-stateless/pkg/stateless"
+)
+
+// blockCache is a blockSource that saves the blocks it fetches from core
+// under dir, by chain ID and height, and serves them from there while
+// core still has the same block at that height. Checking takes only the
+// block's commit, so a reorg can't serve a stale block from the cache.
+// It is safe for concurrent use.
+type blockCache struct {
+	core *stateless.CoreAccessor
+	dir  string
+
+	mu sync.Mutex
+	// chainID is core's chain, queried on first use.
+	chainID string
+}
+
+func newBlockCache(core *stateless.CoreAccessor, dir string) *blockCache {
+	return &blockCache{core: core, dir: dir}
+}
+
+// GetSignedBlock returns the block at height h from the cache if core
+// still has it, and fetches and caches it otherwise.
+func (c *blockCache) GetSignedBlock(ctx context.Context, h string) (*stateless.SignedBlock, error) {
+	height, err := c.height(ctx, h)
+	if err != nil {
+		return nil, err
+	}
+	path, err := c.path(ctx, height)
+	if err != nil {
+		return nil, err
+	}
+	if block, err := c.lookup(ctx, path, height); err != nil {
+		return nil, err
+	} else if block != nil {
+		return block, nil
+	}
+	block, err := c.core.GetSignedBlock(ctx, strconv.FormatInt(height, 10))
+	if err != nil {
+		return nil, err
+	}
+	c.store(path, block)
+	return block, nil
+}
+
+// GetSignedHeader returns the block at height h without its data, from
+// the cache if core still has it. Otherwise only the header is fetched
+// from core, and nothing is cached.
+func (c *blockCache) GetSignedHeader(ctx context.Context, h string) (*stateless.SignedBlock, error) {
+	height, err := c.height(ctx, h)
+	if err != nil {
+		return nil, err
+	}
+	path, err := c.path(ctx, height)
+	if err != nil {
+		return nil, err
+	}
+	if block, err := c.lookup(ctx, path, height); err != nil {
+		return nil, err
+	} else if block != nil {
+		headerOnly := *block
+		headerOnly.Data = nil
+		return &headerOnly, nil
+	}
+	return c.core.GetSignedHeader(ctx, strconv.FormatInt(height, 10))
+}
+
+// height parses the height h, resolving "latest" to core's.
+func (c *blockCache) height(ctx context.Context, h string) (int64, error) {
+	if h == "latest" {
+		return c.core.LatestHeight(ctx)
+	}
+	return strconv.ParseInt(h, 10, 64)
+}
+
+// GetSignedBlockByHash fetches the block with the hex-encoded hash h from
+// core, caching it by its height.
+func (c *blockCache) GetSignedBlockByHash(ctx context.Context, h string) (*stateless.SignedBlock, error) {
+	block, err := c.core.GetSignedBlockByHash(ctx, h)
+	if err != nil {
+		return nil, err
+	}
+	if path, err := c.path(ctx, block.Header.Height); err == nil {
+		c.store(path, block)
+	}
+	return block, nil
+}
+
+// LatestHeight returns core's latest height.
+func (c *blockCache) LatestHeight(ctx context.Context) (int64, error) {
+	return c.core.LatestHeight(ctx)
+}
+
+// EarliestHeight returns core's earliest height.
+func (c *blockCache) EarliestHeight(ctx context.Context) (int64, error) {
+	return c.core.EarliestHeight(ctx)
+}
+
+// ScheduledUpgrade returns the upgrade core's chain has scheduled.
+func (c *blockCache) ScheduledUpgrade(ctx context.Context) (*stateless.Upgrade, error) {
+	return c.core.ScheduledUpgrade(ctx)
+}
+
+func (c *blockCache) Close() error {
+	return c.core.Close()
+}
+
+// path returns the file the block at height is cached in.
+func (c *blockCache) path(ctx context.Context, height int64) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.chainID == "" {
+		status, err := c.core.Status(ctx)
+		if err != nil {
+			return "", fmt.Errorf("querying core's chain ID for the block cache: %w", err)
+		}
+		c.chainID = status.ChainID
+	}
+	return filepath.Join(c.dir, filepath.Base(c.chainID), strconv.FormatInt(height, 10)+".json"), nil
+}
+
+// lookup returns the block cached at path if core has the same block at
+// height, and nil if there is none or it was replaced.
+func (c *blockCache) lookup(ctx context.Context, path string, height int64) (*stateless.SignedBlock, error) {
+	bz, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	block, err := decodeSignedBlock(bz)
+	if err != nil {
+		slog.Warn("ignoring unreadable cached block", "path", path, "err", err)
+		return nil, nil
+	}
+	hash, err := c.core.BlockHash(ctx, height)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(block.Header.Hash(), hash) {
+		slog.Info("cached block was replaced on chain, fetching it again",
+			"height", height, "cached_hash", block.Header.Hash().String(), "hash", fmt.Sprintf("%X", hash))
+		return nil, nil
+	}
+	slog.Debug("serving block from cache", "height", height, "path", path)
+	return block, nil
+}
+
+// store caches block at path. Failing to is logged rather than failing
+// the command, which already has its block.
+func (c *blockCache) store(path string, block *stateless.SignedBlock) {
+	err := func() error {
+		bz, err := json.Marshal(block)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		f, err := createAtomicFile(path)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(bz); err != nil {
+			f.abort()
+			return err
+		}
+		return f.commit()
+	}()
+	if err != nil {
+		slog.Warn("caching block failed", "height", block.Header.Height, "path", path, "err", err)
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+
+	"github.com/adlerjohn/celestia-node-stateless/pkg/stateless"
+	"github.com/celestiaorg/celestia-app/v3/app"
+	"github.com/celestiaorg/celestia-app/v3/app/encoding"
+	"github.com/celestiaorg/celestia-app/v3/pkg/appconsts"
+	"github.com/celestiaorg/celestia-app/v3/pkg/da"
+	blobtypes "github.com/celestiaorg/celestia-app/v3/x/blob/types"
+	libsquare "github.com/celestiaorg/go-square/v2"
+	libshare "github.com/celestiaorg/go-square/v2/share"
+	"github.com/celestiaorg/nmt"
+	"github.com/celestiaorg/rsmt2d"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+// txDecoder returns a decoder for celestia-app transactions.
+func txDecoder() sdk.TxDecoder {
+	return encoding.MakeConfig(app.ModuleEncodingRegisters...).TxConfig.TxDecoder()
+}
+
+// pfbDecoder returns a go-square PFBDecoder that reads the blob sizes out
+// of the MsgPayForBlobs carried by a wrapped PFB transaction.
+func pfbDecoder() libsquare.PFBDecoder {
+	decode := txDecoder()
+	return func(txBytes []byte) ([]uint32, error) {
+		sdkTx, err := decode(txBytes)
+		if err != nil {
+			return nil, err
+		}
+		return pfbBlobSizes(sdkTx.GetMsgs())
+	}
+}
+
+func pfbBlobSizes(msgs []sdk.Msg) ([]uint32, error) {
+	for _, msg := range msgs {
+		if pfb, ok := msg.(*blobtypes.MsgPayForBlobs); ok {
+			return pfb.BlobSizes, nil
+		}
+	}
+	return nil, fmt.Errorf("transaction has no %s message", blobtypes.URLMsgPayForBlobs)
+}
+
+// appVersion returns the app version to extend and parse the block with
+// header h under, warning if an override makes it differ from the header's.
+func (s *session) appVersion(h *types.Header) uint64 {
+	if s.appVersionOverride == 0 || s.appVersionOverride == h.Version.App {
+		return h.Version.App
+	}
+	slog.Warn("overriding block app version, its DAH may not match the data root",
+		"height", h.Height, "header_app_version", h.Version.App, "app_version", s.appVersionOverride)
+	return s.appVersionOverride
+}
+
+// headerlessAppVersion returns the app version to extend shares that come
+// without a block header under: the latest, or the --app-version override.
+func (s *session) headerlessAppVersion() uint64 {
+	if s.appVersionOverride != 0 {
+		return s.appVersionOverride
+	}
+	return appconsts.LatestVersion
+}
+
+// setNMTOptions sets nmtOptions to build trees that ignore the max
+// namespace or not and have namespaces of nsSize bytes, leaving them unset
+// for celestia-app's configuration.
+func (s *session) setNMTOptions(ignoreMaxNamespace bool, nsSize int) error {
+	if nsSize < 1 || nsSize > libshare.NamespaceSize {
+		return fmt.Errorf("NMT namespace size %d is not between 1 and %d", nsSize, libshare.NamespaceSize)
+	}
+	s.nmtOptions = nil
+	s.extenders.Clear()
+	if !ignoreMaxNamespace {
+		s.nmtOptions = append(s.nmtOptions, nmt.IgnoreMaxNamespace(false))
+	}
+	if nsSize != libshare.NamespaceSize {
+		s.nmtOptions = append(s.nmtOptions, nmt.NamespaceIDSize(nsSize))
+	}
+	if len(s.nmtOptions) > 0 {
+		slog.Warn("extending blocks with custom NMT options, their DAH will not match the data root",
+			"ignore_max_namespace", ignoreMaxNamespace, "namespace_size", nsSize)
+	}
+	return nil
+}
+
+// extenderFor returns the Extender for blocks of the given app version,
+// which appVersion and headerlessAppVersion pick.
+func (s *session) extenderFor(version uint64) *stateless.Extender {
+	if e, ok := s.extenders.Load(version); ok {
+		return e.(*stateless.Extender)
+	}
+	e := stateless.NewExtenderWithCodec(version, s.extendCodec, s.nmtOptions...)
+	e.SetConcurrency(s.extendConcurrency)
+	stored, _ := s.extenders.LoadOrStore(version, e)
+	return stored.(*stateless.Extender)
+}
+
+// extendBlock extends the data of block into its extended data square.
+func (s *session) extendBlock(block *stateless.SignedBlock) (*rsmt2d.ExtendedDataSquare, error) {
+	eds, err := s.extenderFor(s.appVersion(block.Header)).Extend(block.Data)
+	if err != nil {
+		return nil, decodeFailed(fmt.Errorf("extending block %d: %w", block.Header.Height, err))
+	}
+	return eds, nil
+}
+
+// blockDAH fetches the block at height h from src and builds the DAH of
+// its extended square.
+func (s *session) blockDAH(src blockSource, h string) (*da.DataAvailabilityHeader, error) {
+	block, err := s.getSignedBlock(src, h)
+	if err != nil {
+		return nil, err
+	}
+	eds, err := s.extendBlock(block)
+	if err != nil {
+		return nil, err
+	}
+	dah, err := da.NewDataAvailabilityHeader(eds)
+	if err != nil {
+		return nil, err
+	}
+	return &dah, nil
+}
+
+// verifyBlockData runs the block data through a full round trip: it
+// extends the block, checks the resulting data root against the header,
+// parses the block's transactions back out of the original square,
+// rebuilds and re-extends the square from them, and checks that the data
+// root is unchanged. The returned error names the stage that diverged.
+func (s *session) verifyBlockData(block *stateless.SignedBlock) error {
+	extender := s.extenderFor(s.appVersion(block.Header))
+	eds, err := extender.Extend(block.Data)
+	if err != nil {
+		return fmt.Errorf("extend: %w", err)
+	}
+	dah, err := da.NewDataAvailabilityHeader(eds)
+	if err != nil {
+		return fmt.Errorf("extend: %w", err)
+	}
+	if err := stateless.VerifyDAH(block.Header, &dah); err != nil {
+		return fmt.Errorf("extend: %w", err)
+	}
+
+	square, err := originalShares(eds)
+	if err != nil {
+		return fmt.Errorf("parse: %w", err)
+	}
+	txs, err := libsquare.Deconstruct(square, pfbDecoder())
+	if err != nil {
+		return fmt.Errorf("parse: %w", err)
+	}
+	original := block.Data.Txs.ToSliceOfBytes()
+	if len(txs) != len(original) {
+		return fmt.Errorf("parse: reconstructed %d transactions, block has %d", len(txs), len(original))
+	}
+	for i := range txs {
+		if !bytes.Equal(txs[i], original[i]) {
+			return fmt.Errorf("parse: reconstructed transaction %d differs from the block", i)
+		}
+	}
+
+	reconstructed, err := extender.Extend(&types.Data{Txs: types.ToTxs(txs)})
+	if err != nil {
+		return fmt.Errorf("reconstruct: %w", err)
+	}
+	redah, err := da.NewDataAvailabilityHeader(reconstructed)
+	if err != nil {
+		return fmt.Errorf("reconstruct: %w", err)
+	}
+	if !redah.Equals(&dah) {
+		return fmt.Errorf("reconstruct: data root %X does not match original data root %X", redah.Hash(), dah.Hash())
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/adlerjohn/celestia-node-stateless/pkg/stateless"
+	"github.com/celestiaorg/celestia-app/v3/pkg/appconsts"
+	"github.com/celestiaorg/celestia-app/v3/pkg/da"
+	libshare "github.com/celestiaorg/go-square/v2/share"
+	"github.com/celestiaorg/go-square/v2/tx"
+	"github.com/celestiaorg/rsmt2d"
+	"github.com/tendermint/tendermint/types"
+)
+
+// testSession returns a session with the settings setup gives it when no
+// flags are set.
+func testSession(t testing.TB) *session {
+	t.Helper()
+	s := new(session)
+	if err := s.setCodec(rsmt2d.Leopard); err != nil {
+		t.Fatal(err)
+	}
+	s.resultWriter = new(bytes.Buffer)
+	return s
+}
+
+// testSignedBlock returns a block at height of appVersion holding txs,
+// committed to by a random validator set, with dataRoot as its DataHash.
+// Blocks are 6s apart.
+func testSignedBlock(t testing.TB, height int64, appVersion uint64, dataRoot []byte, txs ...[]byte) *stateless.SignedBlock {
+	t.Helper()
+	return testSignedBlockAt(t, height, time.Unix(1700000000+height*6, 0).UTC(), appVersion, dataRoot, txs...)
+}
+
+// testSignedBlockAt is testSignedBlock with the block time at.
+func testSignedBlockAt(t testing.TB, height int64, at time.Time, appVersion uint64, dataRoot []byte, txs ...[]byte) *stateless.SignedBlock {
+	t.Helper()
+	validators, privs := types.RandValidatorSet(4, 10)
+	block := types.MakeBlock(height, types.Data{Txs: types.ToTxs(txs)}, &types.Commit{}, nil)
+	block.Header.ChainID = "test"
+	block.Header.Version.App = appVersion
+	block.Header.Time = at
+	block.Header.ValidatorsHash = validators.Hash()
+	block.Header.ProposerAddress = validators.Validators[0].Address
+	block.Header.DataHash = dataRoot
+	blockID := types.BlockID{Hash: block.Hash(), PartSetHeader: block.MakePartSet(types.BlockPartSizeBytes).Header()}
+	votes := types.NewVoteSet(block.Header.ChainID, height, 0, 2, validators)
+	commit, err := types.MakeCommit(blockID, height, 0, votes, privs, block.Header.Time)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &stateless.SignedBlock{Header: &block.Header, Commit: commit, Data: &block.Data, ValidatorSet: validators}
+}
+
+// testBlock returns testSignedBlock of the latest app version holding txs,
+// with their data root as its DataHash.
+func testBlock(t testing.TB, height int64, txs ...[]byte) *stateless.SignedBlock {
+	t.Helper()
+	eds, err := stateless.ExtendBlock(&types.Data{Txs: types.ToTxs(txs)}, appconsts.LatestVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dah, err := da.NewDataAvailabilityHeader(eds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testSignedBlock(t, height, appconsts.LatestVersion, dah.Hash(), txs...)
+}
+
+// TestExtendBlockAppVersions extends fixture blocks under every app
+// version, and one this build doesn't know, and checks their DAH against
+// the data root each hashes to.
+func TestExtendBlockAppVersions(t *testing.T) {
+	blob, err := libshare.NewV0Blob(libshare.MustNewV0Namespace([]byte("fixture")), bytes.Repeat([]byte{0xb1}, 3000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	blobTx, err := tx.MarshalBlobTx([]byte("pay for blob"), blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixtures := []struct {
+		name string
+		txs  [][]byte
+		// dataRoot is the hex DAH hash of the block, the same under every
+		// app version as their square constants are.
+		dataRoot string
+		// width is the width of the extended square.
+		width int
+	}{
+		{"empty", nil, "3D96B7D238E7E0456F6AF8E7CDF0A67BD6CF9C2089ECB559C659DCAA1F880353", 2},
+		{"one tx", [][]byte{[]byte("transfer")}, "647108185D6E1B54302CED81FE9293F234E0705260B6AC85F809B77A3AD43EEE", 2},
+		{"txs and blob", [][]byte{[]byte("transfer"), []byte("delegate"), blobTx}, "99621214B413D5C5560C68D11838F745708855C0F377180C22701889125C0E24", 8},
+		{"many shares", [][]byte{bytes.Repeat([]byte{0x7a}, 20000)}, "C8C9B4BCAA1BF16B58A7E0D1ACF2B5C8823C58B0AEE1B14FAD0F93CD77FB6387", 16},
+	}
+	for _, version := range []uint64{1, 2, 3, 4} {
+		for _, fx := range fixtures {
+			t.Run(fmt.Sprintf("%s/v%d", fx.name, version), func(t *testing.T) {
+				dataRoot, err := hex.DecodeString(fx.dataRoot)
+				if err != nil {
+					t.Fatal(err)
+				}
+				s := testSession(t)
+				block := testSignedBlock(t, 1, version, dataRoot, fx.txs...)
+				eds, err := s.extendBlock(block)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := int(eds.Width()); got != fx.width {
+					t.Errorf("extended square width %d, want %d", got, fx.width)
+				}
+				eh, err := s.makeExtendedHeader(block, eds)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(eh.DAH.Hash(), dataRoot) {
+					t.Errorf("DAH hash %X, want %s", eh.DAH.Hash(), fx.dataRoot)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/adlerjohn/celestia-node-stateless/pkg/stateless"
+	"github.com/celestiaorg/celestia-app/v3/pkg/da"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/types"
+)
+
+// blockSource is where commands get their blocks from: a core node, or a
+// block saved to a file.
+type blockSource interface {
+	GetSignedBlock(ctx context.Context, h string) (*stateless.SignedBlock, error)
+	GetSignedBlockByHash(ctx context.Context, h string) (*stateless.SignedBlock, error)
+	Close() error
+}
+
+// blockFile is a blockSource serving the single block saved in a file.
+type blockFile struct {
+	path  string
+	block *stateless.SignedBlock
+}
+
+// openBlockFile reads a SignedBlock saved as JSON, as printed by
+// `--json block`.
+func openBlockFile(path string) (*blockFile, error) {
+	bz, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, err := decodeSignedBlock(bz)
+	if err != nil {
+		return nil, decodeFailed(fmt.Errorf("decoding block file %s: %w", path, err))
+	}
+	return &blockFile{path: path, block: block}, nil
+}
+
+// GetSignedBlock returns the block in the file if h is its height or
+// "latest".
+func (f *blockFile) GetSignedBlock(_ context.Context, h string) (*stateless.SignedBlock, error) {
+	height := f.block.Header.Height
+	if h != "latest" && h != strconv.FormatInt(height, 10) {
+		return nil, blockNotFound(fmt.Errorf("%s holds block %d, not %s", f.path, height, h))
+	}
+	return f.block, nil
+}
+
+// GetSignedBlockByHash returns the block in the file if its header hashes
+// to the hex-encoded hash h.
+func (f *blockFile) GetSignedBlockByHash(_ context.Context, h string) (*stateless.SignedBlock, error) {
+	hash, err := hex.DecodeString(h)
+	if err != nil {
+		return nil, fmt.Errorf("invalid block hash %q: %w", h, err)
+	}
+	if got := f.block.Header.Hash(); !bytes.Equal(got, hash) {
+		return nil, blockNotFound(fmt.Errorf("%s holds block %X, not %X", f.path, got, hash))
+	}
+	return f.block, nil
+}
+
+func (f *blockFile) Close() error {
+	return nil
+}
+
+// jsonValidator is a types.Validator as encoding/json renders it: the
+// public key loses its type and is left as the raw ed25519 key bytes.
+type jsonValidator struct {
+	Address          types.Address `json:"address"`
+	PubKey           []byte        `json:"pub_key"`
+	VotingPower      int64         `json:"voting_power"`
+	ProposerPriority int64         `json:"proposer_priority"`
+}
+
+func (v *jsonValidator) validator() (*types.Validator, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if len(v.PubKey) != ed25519.PubKeySize {
+		return nil, fmt.Errorf("validator %s: public key is %d bytes, expected a %d-byte ed25519 key",
+			v.Address, len(v.PubKey), ed25519.PubKeySize)
+	}
+	return &types.Validator{
+		Address:          v.Address,
+		PubKey:           ed25519.PubKey(v.PubKey),
+		VotingPower:      v.VotingPower,
+		ProposerPriority: v.ProposerPriority,
+	}, nil
+}
+
+// jsonValidatorSet is a types.ValidatorSet as encoding/json renders it.
+type jsonValidatorSet struct {
+	Validators []*jsonValidator `json:"validators"`
+	Proposer   *jsonValidator   `json:"proposer"`
+}
+
+func (s *jsonValidatorSet) validatorSet() (*types.ValidatorSet, error) {
+	if s == nil {
+		return nil, nil
+	}
+	vals := &types.ValidatorSet{Validators: make([]*types.Validator, len(s.Validators))}
+	for i, v := range s.Validators {
+		val, err := v.validator()
+		if err != nil {
+			return nil, err
+		}
+		vals.Validators[i] = val
+	}
+	proposer, err := s.Proposer.validator()
+	if err != nil {
+		return nil, err
+	}
+	vals.Proposer = proposer
+	if err := vals.ValidateBasic(); err != nil {
+		return nil, fmt.Errorf("validator set: %w", err)
+	}
+	return vals, nil
+}
+
+// decodeSignedBlock decodes a JSON-encoded SignedBlock.
+func decodeSignedBlock(bz []byte) (*stateless.SignedBlock, error) {
+	var aux struct {
+		Header       *types.Header     `json:"header"`
+		Commit       *types.Commit     `json:"commit"`
+		Data         *types.Data       `json:"data"`
+		ValidatorSet *jsonValidatorSet `json:"validator_set"`
+	}
+	if err := json.Unmarshal(bz, &aux); err != nil {
+		return nil, err
+	}
+	if aux.Header == nil || aux.Data == nil {
+		return nil, fmt.Errorf("block has no header or data")
+	}
+	vals, err := aux.ValidatorSet.validatorSet()
+	if err != nil {
+		return nil, err
+	}
+	return &stateless.SignedBlock{Header: aux.Header, Commit: aux.Commit, Data: aux.Data, ValidatorSet: vals}, nil
+}
+
+// decodeExtendedHeader decodes an ExtendedHeader encoded as printed by
+// `--json eds`, with hex-encoded DAH roots.
+func decodeExtendedHeader(bz []byte) (*stateless.ExtendedHeader, error) {
+	var aux struct {
+		Header       *types.Header     `json:"header"`
+		Commit       *types.Commit     `json:"commit"`
+		ValidatorSet *jsonValidatorSet `json:"validator_set"`
+		DAH          *dahJSON          `json:"dah"`
+	}
+	if err := json.Unmarshal(bz, &aux); err != nil {
+		return nil, err
+	}
+	if aux.Header == nil || aux.DAH == nil {
+		return nil, fmt.Errorf("extended header has no header or DAH")
+	}
+	vals, err := aux.ValidatorSet.validatorSet()
+	if err != nil {
+		return nil, err
+	}
+	dah := &da.DataAvailabilityHeader{
+		RowRoots:    make([][]byte, len(aux.DAH.RowRoots)),
+		ColumnRoots: make([][]byte, len(aux.DAH.ColumnRoots)),
+	}
+	for i, root := range aux.DAH.RowRoots {
+		dah.RowRoots[i] = root
+	}
+	for i, root := range aux.DAH.ColumnRoots {
+		dah.ColumnRoots[i] = root
+	}
+	if err := dah.ValidateBasic(); err != nil {
+		return nil, fmt.Errorf("DAH: %w", err)
+	}
+	return &stateless.ExtendedHeader{Header: *aux.Header, Commit: aux.Commit, ValidatorSet: vals, DAH: dah}, nil
+}
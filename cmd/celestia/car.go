@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/adlerjohn/celestia-node-stateless/pkg/stateless"
+	"github.com/celestiaorg/rsmt2d"
+)
+
+func writeCARFile(path string, eds *rsmt2d.ExtendedDataSquare) error {
+	f, err := createAtomicFile(path)
+	if err != nil {
+		return err
+	}
+	if err := stateless.WriteCAR(f, eds); err != nil {
+		f.abort()
+		return err
+	}
+	return f.commit()
+}
+
+func readCARFile(path string) (*rsmt2d.ExtendedDataSquare, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	eds, err := stateless.ReadCAR(f)
+	if err != nil {
+		return nil, decodeFailed(fmt.Errorf("decoding CAR %s: %w", path, err))
+	}
+	return eds, nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/adlerjohn/celestia-node-stateless/pkg/stateless"
+)
+
+// setCodec sets extendCodec to the codec with the given name, dropping the
+// extenders built with the previous one.
+func (s *session) setCodec(name string) error {
+	codec, err := stateless.CodecByName(name)
+	if err != nil {
+		return usageError(fmt.Errorf("invalid --codec: %w", err))
+	}
+	s.extendCodec = codec
+	s.extenders.Clear()
+	return nil
+}
+
+// checkCodecMemory rejects any use of --codec-memory, which would cap the
+// working memory of the Reed-Solomon codec during extension at the cost of
+// speed. The codecs rsmt2d provides build their encoders with fixed options
+// and rsmt2d.ComputeExtendedDataSquare takes no codec parameters, so even a
+// valid value is rejected as unsupported rather than silently ignored.
+func (s *session) checkCodecMemory(value string) error {
+	if n, err := strconv.ParseUint(value, 10, 64); err != nil || n == 0 {
+		return usageError(fmt.Errorf("invalid --codec-memory %q: must be a positive number of bytes", value))
+	}
+	return usageError(fmt.Errorf("--codec-memory is not supported by the %s codec: rsmt2d takes no codec parameters",
+		s.extendCodec.Name()))
+}
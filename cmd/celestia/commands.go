@@ -0,0 +1,2102 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"os/signal"
+	"runtime"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/adlerjohn/celestia-node-stateless/pkg/stateless"
+	"github.com/celestiaorg/celestia-app/v3/app"
+	"github.com/celestiaorg/celestia-app/v3/pkg/da"
+	libshare "github.com/celestiaorg/go-square/v2/share"
+	"github.com/celestiaorg/rsmt2d"
+	"github.com/spf13/cobra"
+	"github.com/tendermint/tendermint/crypto/tmhash"
+	tmmath "github.com/tendermint/tendermint/libs/math"
+)
+
+// commands returns the commands of the CLI, bound to s.
+func (s *session) commands() []*cobra.Command {
+	return []*cobra.Command{
+		s.edsCmd(),
+		s.statusCmd(),
+		s.doctorCmd(),
+		s.summaryCmd(),
+		s.sizeCmd(),
+		s.coordsCmd(),
+		s.dahCmd(),
+		s.rootsCmd(),
+		s.diffCmd(),
+		s.shareCmd(),
+		s.rowsCmd(),
+		s.proofCmd(),
+		s.colProofCmd(),
+		s.blobCmd(),
+		s.blobProofCmd(),
+		s.blobMinProofCmd(),
+		s.blobByCommitmentCmd(),
+		s.txProofCmd(),
+		s.blockCmd(),
+		s.rangeCmd(),
+		s.followCmd(),
+		s.batchCmd(),
+		s.txsCmd(),
+		s.verifyDataCommitmentCmd(),
+		s.utilizationCmd(),
+		s.paddingCmd(),
+		s.namespacesCmd(),
+		s.visualizeCmd(),
+		s.verifyShareAgainstDAHCmd(),
+		s.verifyProofCmd(),
+		s.verifyRowCmd(),
+		s.valsetCmd(),
+		s.verifyParityCmd(),
+		s.verifyEDSCmd(),
+		s.auditRowsCmd(),
+		s.verifyBlockDataCmd(),
+		s.verifyDataRootCmd(),
+		s.isEmptyCmd(),
+		s.reconstructCmd(),
+		s.verifyCmd(),
+		s.verifyHeaderCmd(),
+		s.verifyEHCmd(),
+		s.sampleCmd(),
+		s.receiptCmd(),
+		s.verifyReceiptCmd(),
+		s.namespaceProofCmd(),
+		s.verifyNamespaceProofCmd(),
+		s.absenceProofCmd(),
+		s.getNamespaceDataCmd(),
+		s.exportNamespaceProofsCmd(),
+		s.verifyNamespaceProofsCmd(),
+		s.exportCmd(),
+		s.archiveCmd(),
+		s.importCmd(),
+		s.sharesCmd(),
+		s.extendSharesCmd(),
+		s.watchDirCmd(),
+		s.serveCmd(),
+		s.benchCmd(),
+		s.replCmd(),
+	}
+}
+
+// parseNamespace parses a hex-encoded namespace argument, checking that it
+// has the namespace size and a supported version: 0, whose ID starts with
+// zero bytes, or 255.
+func parseNamespace(arg string) (libshare.Namespace, error) {
+	invalid := func(format string, a ...any) (libshare.Namespace, error) {
+		return libshare.Namespace{}, usageError(fmt.Errorf("invalid namespace %q: "+format, append([]any{arg}, a...)...))
+	}
+	nsBytes, err := hex.DecodeString(arg)
+	if err != nil {
+		return invalid("not hex: %w", err)
+	}
+	if len(nsBytes) != libshare.NamespaceSize {
+		return invalid("wrong length, %d bytes instead of %d", len(nsBytes), libshare.NamespaceSize)
+	}
+	switch version := nsBytes[libshare.VersionIndex]; version {
+	case libshare.NamespaceVersionZero:
+		prefix := nsBytes[libshare.NamespaceVersionSize : libshare.NamespaceVersionSize+libshare.NamespaceVersionZeroPrefixSize]
+		if !bytes.Equal(prefix, libshare.NamespaceVersionZeroPrefix) {
+			return invalid("version 0 IDs must start with %d zero bytes", libshare.NamespaceVersionZeroPrefixSize)
+		}
+	case libshare.NamespaceVersionMax:
+	default:
+		return invalid("unsupported version %d, expected %d or %d",
+			version, libshare.NamespaceVersionZero, libshare.NamespaceVersionMax)
+	}
+	ns, err := libshare.NewNamespaceFromBytes(nsBytes)
+	if err != nil {
+		return invalid("%w", err)
+	}
+	return ns, nil
+}
+
+// parseBlobNamespace parses a namespace argument like parseNamespace,
+// rejecting the namespaces reserved for other than blobs.
+func parseBlobNamespace(arg string) (libshare.Namespace, error) {
+	ns, err := parseNamespace(arg)
+	if err != nil {
+		return libshare.Namespace{}, err
+	}
+	if ns.IsReserved() || !ns.IsUsableNamespace() {
+		return libshare.Namespace{}, usageError(fmt.Errorf("invalid namespace %q: reserved, so it holds no blobs", arg))
+	}
+	return ns, nil
+}
+
+// parseCommitment parses a hex-encoded share commitment argument, checking
+// that it has the size of the merkle root it is. Blobstream data
+// commitments are merkle roots too.
+func parseCommitment(arg string) ([]byte, error) {
+	commitment, err := hex.DecodeString(arg)
+	if err != nil {
+		return nil, usageError(fmt.Errorf("invalid commitment %q: not hex: %w", arg, err))
+	}
+	if len(commitment) != tmhash.Size {
+		return nil, usageError(fmt.Errorf("invalid commitment %q: wrong length, %d bytes instead of %d",
+			arg, len(commitment), tmhash.Size))
+	}
+	return commitment, nil
+}
+
+func (s *session) edsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "eds <height|latest>",
+		Short: "Print the extended header of a block",
+		Args:  cobra.ExactArgs(1),
+	}
+	nodeFormat := cmd.Flags().String("node-format", "",
+		"encode the header as celestia-node's ExtendedHeader instead: json, as its API serves it, "+
+			"or binary, the protobuf it stores and gossips")
+	cmd.RunE = s.needsCore(func(src blockSource, args []string) error {
+		if _, ok := nodeHeaderFormats[*nodeFormat]; *nodeFormat != "" && !ok {
+			return usageError(fmt.Errorf("unknown --node-format %q, expected json or binary", *nodeFormat))
+		}
+		block, err := s.getSignedBlock(src, args[0])
+		if err != nil {
+			return err
+		}
+		eds, err := s.extendBlock(block)
+		if err != nil {
+			return err
+		}
+		// create extended header
+		eh, err := s.makeExtendedHeader(block, eds)
+		if err != nil {
+			return err
+		}
+		if *nodeFormat == "" {
+			return s.printResult(eh)
+		}
+		bz, err := encodeNodeHeader(eh, *nodeFormat)
+		if err != nil {
+			return err
+		}
+		if *nodeFormat == "json" {
+			bz = append(bz, '\n')
+		}
+		_, err = s.resultWriter.Write(bz)
+		return err
+	})
+	return cmd
+}
+
+func (s *session) statusCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Check that core is reachable and synced, and print its chain and height",
+		Args:  cobra.NoArgs,
+		RunE: s.needsCore(func(src blockSource, _ []string) error {
+			if cache, ok := src.(*blockCache); ok {
+				src = cache.core
+			}
+			core, ok := src.(*stateless.CoreAccessor)
+			if !ok {
+				return errors.New("status needs a core endpoint, not a block file")
+			}
+			return s.checkStatus(core)
+		}),
+	}
+}
+
+func (s *session) doctorCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "doctor",
+		Short: "Check that core is reachable and serves blocks that extend and verify, with hints on what failed",
+		Args:  cobra.NoArgs,
+		RunE: s.needsCore(func(src blockSource, _ []string) error {
+			if cache, ok := src.(*blockCache); ok {
+				src = cache.core
+			}
+			core, ok := src.(*stateless.CoreAccessor)
+			if !ok {
+				return errors.New("doctor needs a core endpoint, not a block file")
+			}
+			report := s.doctor(core)
+			if err := s.printResult(report); err != nil {
+				return err
+			}
+			return report.failure()
+		}),
+	}
+}
+
+func (s *session) summaryCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "summary <height|latest>",
+		Short: "Print the chain, app version, time, transactions, square size and data root of a block",
+		Args:  cobra.ExactArgs(1),
+		RunE: s.needsCore(func(src blockSource, args []string) error {
+			block, err := s.getSignedBlock(src, args[0])
+			if err != nil {
+				return err
+			}
+			eds, err := s.extendBlock(block)
+			if err != nil {
+				return err
+			}
+			dah, err := da.NewDataAvailabilityHeader(eds)
+			if err != nil {
+				return err
+			}
+			shares, err := originalShares(eds)
+			if err != nil {
+				return err
+			}
+			summary := s.newBlockSummary(block, &dah, shares)
+			upgrade, known := s.scheduledUpgrade(src)
+			summary.Upgrade, summary.NoUpgrade = upgrade, known && upgrade == nil
+			return s.printResult(summary)
+		}),
+	}
+}
+
+func (s *session) sizeCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "size <height|latest>",
+		Short: "Print the widths of a block's original and extended square and its share count",
+		Args:  cobra.ExactArgs(1),
+		RunE: s.needsCore(func(src blockSource, args []string) error {
+			block, err := s.getSignedBlock(src, args[0])
+			if err != nil {
+				return err
+			}
+			eds, err := s.extendBlock(block)
+			if err != nil {
+				return err
+			}
+			return s.printResult(stateless.EDSSize(eds))
+		}),
+	}
+}
+
+func (s *session) coordsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "coords <index> | coords <row> <col>",
+		Short: "Convert between the row-major index of a share in the extended square and its row and column",
+		Args:  cobra.RangeArgs(1, 2),
+	}
+	squareSize := cmd.Flags().Int("size", 0, "width of the original data square, as size prints it")
+	cmd.MarkFlagRequired("size")
+	cmd.RunE = func(_ *cobra.Command, args []string) error {
+		ints := make([]int, len(args))
+		for i, arg := range args {
+			n, err := strconv.Atoi(arg)
+			if err != nil {
+				return usageError(fmt.Errorf("invalid coordinate %q: %w", arg, err))
+			}
+			ints[i] = n
+		}
+		idx := ints[0]
+		if len(ints) == 2 {
+			var err error
+			if idx, err = stateless.IndexFromCoords(ints[0], ints[1], *squareSize); err != nil {
+				return usageError(err)
+			}
+		}
+		coords, err := stateless.CoordsFromIndex(idx, *squareSize)
+		if err != nil {
+			return usageError(err)
+		}
+		return s.printResult(coords)
+	}
+	return cmd
+}
+
+func (s *session) dahCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "dah <height|latest>",
+		Short: "Print the data root and DAH shape of a block",
+		Args:  cobra.ExactArgs(1),
+		RunE: s.needsCore(func(src blockSource, args []string) error {
+			block, err := s.getSignedBlock(src, args[0])
+			if err != nil {
+				return err
+			}
+			eds, err := s.extendBlock(block)
+			if err != nil {
+				return err
+			}
+			eh, err := s.makeExtendedHeader(block, eds)
+			if err != nil {
+				return err
+			}
+			return s.printResult(newDAHSummary(eh.DAH))
+		}),
+	}
+}
+
+func (s *session) rootsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "roots <height|latest>",
+		Short: "Print every row and column root of a block's DAH and the DAH hash",
+		Args:  cobra.ExactArgs(1),
+		RunE: s.needsCore(func(src blockSource, args []string) error {
+			dah, err := s.blockDAH(src, args[0])
+			if err != nil {
+				return err
+			}
+			return s.printResult(newDAHRoots(dah))
+		}),
+	}
+}
+
+func (s *session) diffCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff <height-a> <height-b>",
+		Short: "Compare the DAHs of two blocks, failing if they differ",
+		Args:  cobra.ExactArgs(2),
+	}
+	otherCore := cmd.Flags().StringSlice("other-core", nil,
+		"fetch the second block from this core address or block file instead of --core")
+	cmd.RunE = s.needsCore(func(src blockSource, args []string) error {
+		other := src
+		if len(*otherCore) > 0 {
+			var err error
+			if other, err = s.openSourceAt(*otherCore); err != nil {
+				return err
+			}
+			defer other.Close()
+		}
+		a, err := s.blockDAH(src, args[0])
+		if err != nil {
+			return err
+		}
+		b, err := s.blockDAH(other, args[1])
+		if err != nil {
+			return err
+		}
+		diff := newDAHDiff(a, b)
+		if err := s.printResult(diff); err != nil {
+			return err
+		}
+		if !diff.match() {
+			return verificationFailed(errors.New("the DAHs differ"))
+		}
+		return nil
+	})
+	return cmd
+}
+
+func (s *session) shareCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "share <height|latest> (<row> <col> | --row <n> | --col <n>)",
+		Short: "Print a share of a block's extended square, or a whole row or column of them",
+		Args:  cobra.RangeArgs(1, 3),
+	}
+	encoding := cmd.Flags().String("encoding", "hex",
+		"how to print the share: hex or base64 with its namespace, or raw to write its exact bytes")
+	rowFlag := cmd.Flags().String("row", "", "print every share of this row instead of a single cell")
+	colFlag := cmd.Flags().String("col", "", "print every share of this column instead of a single cell")
+	half := cmd.Flags().String("half", "full",
+		"with --row or --col, print only the data (original) half, the parity half, or the full axis")
+	cmd.MarkFlagsMutuallyExclusive("row", "col")
+	cmd.RunE = s.needsCore(func(src blockSource, args []string) error {
+		encode, ok := shareEncodings[*encoding]
+		if !ok && *encoding != "raw" {
+			return usageError(fmt.Errorf("invalid --encoding %q, expected hex, base64 or raw", *encoding))
+		}
+		wholeAxis := cmd.Flags().Changed("row") || cmd.Flags().Changed("col")
+		switch {
+		case wholeAxis && len(args) != 1:
+			return usageError(errors.New("--row and --col take the place of the <row> <col> arguments"))
+		case !wholeAxis && len(args) != 3:
+			return usageError(errors.New("expected <row> <col>, or --row or --col for a whole row or column"))
+		case !wholeAxis && cmd.Flags().Changed("half"):
+			return usageError(errors.New("--half applies to --row and --col only"))
+		}
+		if err := checkAxisHalf(*half); err != nil {
+			return err
+		}
+		// The indices are checked against the square once it is extended
+		var (
+			axisName string
+			// index is the index of the axis, r and c those of the cell
+			index, r, c uint
+			err         error
+		)
+		switch {
+		case cmd.Flags().Changed("row"):
+			axisName = "row"
+			index, err = parseCellArg("row", *rowFlag)
+		case wholeAxis:
+			axisName = "col"
+			index, err = parseCellArg("column", *colFlag)
+		default:
+			if r, err = parseCellArg("row", args[1]); err == nil {
+				c, err = parseCellArg("column", args[2])
+			}
+		}
+		if err != nil {
+			return err
+		}
+		block, err := s.getSignedBlock(src, args[0])
+		if err != nil {
+			return err
+		}
+		eds, err := s.extendBlock(block)
+		if err != nil {
+			return err
+		}
+		width := eds.Width()
+		if app.IsEmptyBlockRef(block.Data, s.appVersion(block.Header)) {
+			return fmt.Errorf("block %d has no user data: its extended square is the %dx%d empty square",
+				block.Header.Height, width, width)
+		}
+		if wholeAxis {
+			axis, err := newShareAxis(eds, axisName, index, *half, encode)
+			if err != nil {
+				return err
+			}
+			if *encoding == "raw" {
+				for _, cell := range axis.cells {
+					if _, err := s.resultWriter.Write(cell); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+			return s.printResult(axis)
+		}
+		if err := checkCellIndex("row", r, width); err != nil {
+			return err
+		}
+		if err := checkCellIndex("column", c, width); err != nil {
+			return err
+		}
+		cell := eds.GetCell(r, c)
+		switch {
+		case *encoding == "raw":
+			_, err := s.resultWriter.Write(cell)
+			return err
+		case s.jsonOutput || s.outputTemplate != nil:
+			// JSON and templates keep getting the cell bytes
+			return s.printResult(cell)
+		default:
+			return s.printResult(&encodedShare{cell: cell, parity: r >= width/2 || c >= width/2, encode: encode})
+		}
+	})
+	return cmd
+}
+
+func (s *session) rowsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "rows <height|latest>",
+		Short: "Write the shares of a block's extended square one row at a time",
+		Args:  cobra.ExactArgs(1),
+		RunE: s.needsCore(func(src blockSource, args []string) error {
+			block, err := s.getSignedBlock(src, args[0])
+			if err != nil {
+				return err
+			}
+			eds, err := s.extendBlock(block)
+			if err != nil {
+				return err
+			}
+			return s.writeRows(s.resultWriter, eds)
+		}),
+	}
+}
+
+func (s *session) proofCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "proof <height|latest> <row> <col>",
+		Short: "Print a share of a block's extended square with its proof against the row root",
+		Args:  cobra.ExactArgs(3),
+		RunE: s.needsCore(func(src blockSource, args []string) error {
+			r, err := parseCellArg("row", args[1])
+			if err != nil {
+				return err
+			}
+			c, err := parseCellArg("column", args[2])
+			if err != nil {
+				return err
+			}
+			block, err := s.getSignedBlock(src, args[0])
+			if err != nil {
+				return err
+			}
+			eds, err := s.extendBlock(block)
+			if err != nil {
+				return err
+			}
+			proof, err := newShareProof(eds, r, c)
+			if err != nil {
+				return err
+			}
+			return s.printResult(proof)
+		}),
+	}
+}
+
+func (s *session) colProofCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "col-proof <height|latest> <col> <row>",
+		Short: "Print a share of a block's extended square with its proof against the column root",
+		Args:  cobra.ExactArgs(3),
+		RunE: s.needsCore(func(src blockSource, args []string) error {
+			c, err := parseCellArg("column", args[1])
+			if err != nil {
+				return err
+			}
+			r, err := parseCellArg("row", args[2])
+			if err != nil {
+				return err
+			}
+			block, err := s.getSignedBlock(src, args[0])
+			if err != nil {
+				return err
+			}
+			eds, err := s.extendBlock(block)
+			if err != nil {
+				return err
+			}
+			proof, err := newColShareProof(eds, r, c)
+			if err != nil {
+				return err
+			}
+			return s.printResult(proof)
+		}),
+	}
+}
+
+func (s *session) blobCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "blob <height> <namespace>",
+		Short: "List the blobs of a namespace in a block",
+		Args:  cobra.ExactArgs(2),
+		RunE: s.needsCore(func(src blockSource, args []string) error {
+			ns, err := parseBlobNamespace(args[1])
+			if err != nil {
+				return err
+			}
+			block, err := s.getSignedBlock(src, args[0])
+			if err != nil {
+				return err
+			}
+			eds, err := s.extendBlock(block)
+			if err != nil {
+				return err
+			}
+			blobs, err := blobsByNamespace(eds, ns)
+			if err != nil {
+				return err
+			}
+			summaries, err := summarizeBlobs(blobs, stateless.SubtreeRootThreshold(s.appVersion(block.Header)))
+			if err != nil {
+				return err
+			}
+			return s.printResult(summaries)
+		}),
+	}
+}
+
+func (s *session) blobByCommitmentCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "blob-by-commitment <height> <namespace> <commitment>",
+		Short: "Print the blob of a namespace in a block with a share commitment, failing if there is none",
+		Args:  cobra.ExactArgs(3),
+		RunE: s.needsCore(func(src blockSource, args []string) error {
+			ns, err := parseBlobNamespace(args[1])
+			if err != nil {
+				return err
+			}
+			commitment, err := parseCommitment(args[2])
+			if err != nil {
+				return err
+			}
+			block, err := s.getSignedBlock(src, args[0])
+			if err != nil {
+				return err
+			}
+			eds, err := s.extendBlock(block)
+			if err != nil {
+				return err
+			}
+			blobs, err := blobsByNamespace(eds, ns)
+			if err != nil {
+				return err
+			}
+			blob, err := blobByCommitment(blobs, commitment, stateless.SubtreeRootThreshold(s.appVersion(block.Header)))
+			if err != nil {
+				return err
+			}
+			if blob == nil {
+				return verificationFailed(fmt.Errorf("none of the %d blobs under namespace %x in block %d has commitment %x",
+					len(blobs), ns.Bytes(), block.Header.Height, commitment))
+			}
+			return s.printResult(blob)
+		}),
+	}
+}
+
+func (s *session) blobProofCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "blob-proof <height> <namespace> <index>",
+		Short: "Print the inclusion proof of a blob of a namespace against a block's data root",
+		Args:  cobra.ExactArgs(3),
+		RunE: s.needsCore(func(src blockSource, args []string) error {
+			ns, err := parseBlobNamespace(args[1])
+			if err != nil {
+				return err
+			}
+			index, err := strconv.Atoi(args[2])
+			if err != nil || index < 0 {
+				return usageError(fmt.Errorf("invalid blob index %q", args[2]))
+			}
+			block, err := s.getSignedBlock(src, args[0])
+			if err != nil {
+				return err
+			}
+			eds, err := s.extendBlock(block)
+			if err != nil {
+				return err
+			}
+			proof, err := newBlobProof(eds, ns, index, block.Header.DataHash)
+			if err != nil {
+				return err
+			}
+			return s.printResult(proof)
+		}),
+	}
+}
+
+func (s *session) blobMinProofCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "blob-min-proof <height> <namespace> <index>",
+		Short: "Print the minimal shares and proofs to reconstruct one blob of a namespace and verify it against a block",
+		Args:  cobra.ExactArgs(3),
+		RunE: s.needsCore(func(src blockSource, args []string) error {
+			ns, err := parseBlobNamespace(args[1])
+			if err != nil {
+				return err
+			}
+			index, err := strconv.Atoi(args[2])
+			if err != nil || index < 0 {
+				return usageError(fmt.Errorf("invalid blob index %q", args[2]))
+			}
+			block, err := s.getSignedBlock(src, args[0])
+			if err != nil {
+				return err
+			}
+			eds, err := s.extendBlock(block)
+			if err != nil {
+				return err
+			}
+			proof, err := newBlobMinProof(eds, ns, index, block.Header.DataHash,
+				stateless.SubtreeRootThreshold(s.appVersion(block.Header)))
+			if err != nil {
+				return err
+			}
+			return s.printResult(proof)
+		}),
+	}
+}
+
+func (s *session) txProofCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "tx-proof <height> <index>",
+		Short: "Print the inclusion proof of a block's transaction against its data root",
+		Args:  cobra.ExactArgs(2),
+		RunE: s.needsCore(func(src blockSource, args []string) error {
+			index, err := strconv.Atoi(args[1])
+			if err != nil || index < 0 {
+				return usageError(fmt.Errorf("invalid transaction index %q", args[1]))
+			}
+			block, err := s.getSignedBlock(src, args[0])
+			if err != nil {
+				return err
+			}
+			eds, err := s.extendBlock(block)
+			if err != nil {
+				return err
+			}
+			version := s.appVersion(block.Header)
+			proof, err := newTxProof(eds, block.Data, index,
+				stateless.SquareSizeUpperBound(version), stateless.SubtreeRootThreshold(version), block.Header.DataHash)
+			if err != nil {
+				return err
+			}
+			return s.printResult(proof)
+		}),
+	}
+}
+
+func (s *session) blockCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "block <height|latest>",
+		Short: "Print a block",
+		Args:  cobra.ExactArgs(1),
+	}
+	headerOnly := cmd.Flags().Bool("header-only", false,
+		"print only the header, commit and validator set, receiving the block from core only until its header")
+	cmd.RunE = s.needsCore(func(src blockSource, args []string) error {
+		fetch := s.getSignedBlock
+		if *headerOnly {
+			fetch = s.getSignedHeader
+		}
+		block, err := fetch(src, args[0])
+		if err != nil {
+			return err
+		}
+		return s.printResult(block)
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "hash <hex-hash>",
+		Short: "Print the block with the given hash",
+		Args:  cobra.ExactArgs(1),
+		RunE: s.needsCore(func(src blockSource, args []string) error {
+			block, err := s.getSignedBlockByHash(src, args[0])
+			if err != nil {
+				return err
+			}
+			return s.printResult(block)
+		}),
+	})
+	return cmd
+}
+
+func (s *session) rangeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "range <start> <end>",
+		Short: "Print the extended headers of the blocks from start to end inclusive",
+		Args:  cobra.ExactArgs(2),
+	}
+	concurrency := cmd.Flags().Int("concurrency", runtime.NumCPU(),
+		"number of blocks each stage fetches, extends or verifies in parallel")
+	checkLinks := cmd.Flags().Bool("check-links", true,
+		"fail on a suspected re-org: a block whose last block ID isn't the hash of the block before it")
+	verifyCommits := cmd.Flags().Bool("verify-commit", true,
+		"check every block's commit against its validator set, besides its data root")
+	failFast := cmd.Flags().Bool("fail-fast", false,
+		"stop at the first height that fails instead of summarizing the failures at the end")
+	summaryOnly := cmd.Flags().Bool("summary-only", false,
+		"print no headers, only the totals of the range once it is done")
+	blockTimes := cmd.Flags().Bool("block-times", false,
+		"print each block's time and the interval since the block before in place of its header, and the intervals' statistics at the end")
+	cmd.MarkFlagsMutuallyExclusive("summary-only", "fail-fast")
+	cmd.MarkFlagsMutuallyExclusive("summary-only", "block-times")
+	cmd.RunE = s.needsCore(func(src blockSource, args []string) error {
+		heights, err := s.resolveHeights(src, args[0], args[1])
+		if err != nil {
+			return err
+		}
+		start, end := heights[0], heights[1]
+		totals := newRangeSummary()
+		stages := rangeStages{
+			fetch: func(height int64) (*stateless.SignedBlock, error) {
+				return s.getSignedBlock(src, strconv.FormatInt(height, 10))
+			},
+			extend: func(block *stateless.SignedBlock) (*rsmt2d.ExtendedDataSquare, error) {
+				begin := time.Now()
+				eds, err := s.extendBlock(block)
+				if err == nil {
+					totals.extended(block, s.appVersion(block.Header), eds, time.Since(begin))
+				}
+				return eds, err
+			},
+			verify: func(block *stateless.SignedBlock, eds *rsmt2d.ExtendedDataSquare) (*stateless.ExtendedHeader, error) {
+				if s.validateAll {
+					return s.makeExtendedHeader(block, eds)
+				}
+				if *verifyCommits {
+					if err := stateless.VerifyCommit(block); err != nil {
+						return nil, verificationFailed(err)
+					}
+				}
+				eh, err := s.extendedHeader(block.Header, block.Commit, block.ValidatorSet, eds)
+				if errors.Is(err, stateless.ErrDAHMismatch) {
+					return nil, verificationFailed(err)
+				}
+				return eh, err
+			},
+		}
+		var (
+			links    linkChecker
+			versions = appVersionTracker{overridden: s.appVersionOverride != 0}
+			times    blockTimer
+			summary  heightsSummary
+		)
+		err = pipelineRange(start, end, *concurrency, stages, func(height int64, eh *stateless.ExtendedHeader, err error) error {
+			if err == nil {
+				err = versions.check(eh)
+			}
+			if err == nil && *checkLinks {
+				err = links.check(eh)
+			}
+			if err == nil {
+				record := times.add(eh)
+				switch {
+				case *blockTimes:
+					err = s.printResult(record)
+				case !*summaryOnly:
+					err = s.printResult(eh)
+				}
+			}
+			totals.add(height, err)
+			switch {
+			case err == nil:
+				summary.Succeeded++
+				return nil
+			case *failFast || errors.Is(err, errDryRun):
+				return atHeight(height, fmt.Errorf("height %d: %w", height, err))
+			default:
+				slog.Error("range height failed", "height", height, "err", err)
+				if s.ndjsonOutput && !*summaryOnly {
+					// Keep the failure in the stream, in height order
+					s.printError(s.resultWriter, atHeight(height, err), exitCode(err))
+				}
+				summary.Failed = append(summary.Failed, height)
+				return nil
+			}
+		})
+		if err != nil {
+			return err
+		}
+		if *blockTimes {
+			if err := s.printResult(times.stats()); err != nil {
+				return err
+			}
+		}
+		if *failFast {
+			return nil
+		}
+		if *summaryOnly {
+			totals.finish(times.stats())
+			if err := s.printResult(totals); err != nil {
+				return err
+			}
+		} else {
+			// The summary goes to stderr to keep stdout a stream of headers
+			fmt.Fprintln(os.Stderr, &summary)
+		}
+		if len(summary.Failed) > 0 {
+			return fmt.Errorf("%d of %d heights failed", len(summary.Failed), summary.Succeeded+len(summary.Failed))
+		}
+		return nil
+	})
+	return cmd
+}
+
+func (s *session) followCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "follow [<height|latest>]",
+		Short: "Print the extended header of every new block as core produces it, until interrupted",
+		Args:  cobra.MaximumNArgs(1),
+	}
+	pollInterval := cmd.Flags().Duration("poll-interval", 5*time.Second, "how often to ask core for new blocks")
+	checkLinks := cmd.Flags().Bool("check-links", true,
+		"stop on a suspected re-org: a block whose last block ID isn't the hash of the block before it")
+	cmd.RunE = s.needsCore(func(src blockSource, args []string) error {
+		var start int64
+		if len(args) > 0 && args[0] != "latest" {
+			heights, err := s.resolveHeights(src, args[0])
+			if err != nil {
+				return err
+			}
+			if start = heights[0]; start <= 0 {
+				return usageError(fmt.Errorf("invalid start height %q", args[0]))
+			}
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		var (
+			links    linkChecker
+			versions = appVersionTracker{overridden: s.appVersionOverride != 0}
+		)
+		return s.follow(ctx, src, start, *pollInterval, func(eh *stateless.ExtendedHeader) error {
+			if err := versions.check(eh); err != nil {
+				return err
+			}
+			if *checkLinks {
+				if err := links.check(eh); err != nil {
+					return err
+				}
+			}
+			return s.printResult(eh)
+		})
+	})
+	return cmd
+}
+
+func (s *session) batchCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "batch --height-file <file> <command> [<args>...]",
+		Short: "Run a command against every height listed in a file, with the height as its first argument",
+		Args:  cobra.MinimumNArgs(1),
+	}
+	// Flags after the command name are the command's own
+	cmd.Flags().SetInterspersed(false)
+	heightFile := cmd.Flags().String("height-file", "", "file of heights to run the command against, one per line")
+	concurrency := cmd.Flags().Int("concurrency", runtime.NumCPU(), "number of blocks fetched ahead in parallel")
+	cmd.MarkFlagRequired("height-file")
+	cmd.RunE = s.needsCore(func(src blockSource, args []string) error {
+		// Catch a mistyped command line once rather than at every height
+		target, rest, err := s.subcommands().Find(args)
+		if err != nil || !target.Runnable() {
+			return usageError(fmt.Errorf("unknown command %q", args[0]))
+		}
+		if name := target.Name(); name == "batch" || name == "repl" {
+			return usageError(fmt.Errorf("%s can't be run in a batch", name))
+		}
+		if err := target.ParseFlags(rest); err != nil {
+			return usageError(err)
+		}
+		heights, skipped, err := readHeightFile(*heightFile)
+		if err != nil {
+			return err
+		}
+		summary, err := s.runBatch(src, heights, *concurrency, args)
+		if err != nil {
+			return err
+		}
+		summary.Skipped = skipped
+		if err := s.printResult(summary); err != nil {
+			return err
+		}
+		if len(summary.Failed) > 0 {
+			return fmt.Errorf("%d of %d heights failed", len(summary.Failed), len(heights))
+		}
+		return nil
+	})
+	return cmd
+}
+
+func (s *session) txsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "txs <height|latest>",
+		Short: "Summarize the transactions of a block and the blobs they pay for",
+		Args:  cobra.ExactArgs(1),
+		RunE: s.needsCore(func(src blockSource, args []string) error {
+			block, err := s.getSignedBlock(src, args[0])
+			if err != nil {
+				return err
+			}
+			return s.printResult(summarizeTxs(block.Data.Txs))
+		}),
+	}
+}
+
+func (s *session) verifyDataCommitmentCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify-data-commitment <start> <end> --commitment <hex> --proof <file>",
+		Short: "Verify a block's data root against a Blobstream data commitment over [start, end)",
+		Args:  cobra.ExactArgs(2),
+	}
+	commitmentHex := cmd.Flags().String("commitment", "", "hex-encoded Blobstream data commitment")
+	proofPath := cmd.Flags().String("proof", "", "path to a data root inclusion proof, as core's DataRootInclusionProof endpoint returns it")
+	cmd.MarkFlagRequired("commitment")
+	cmd.MarkFlagRequired("proof")
+	cmd.RunE = s.needsCore(func(src blockSource, args []string) error {
+		commitment, err := parseCommitment(*commitmentHex)
+		if err != nil {
+			return err
+		}
+		// The height range is end-exclusive
+		heights, err := s.resolveHeights(src, args[0], args[1])
+		if err != nil {
+			return err
+		}
+		start, end := uint64(heights[0]), uint64(heights[1])
+		proof, err := readDataRootInclusionProof(*proofPath)
+		if err != nil {
+			return err
+		}
+		height, err := dataCommitmentHeight(start, end, proof)
+		if err != nil {
+			return err
+		}
+		block, err := s.getSignedBlock(src, strconv.FormatUint(height, 10))
+		if err != nil {
+			return err
+		}
+		err = verifyDataRootInclusion(height, block.Header.DataHash, commitment, proof)
+		if err != nil {
+			return verificationFailed(fmt.Errorf("block %d: %w", height, err))
+		}
+		result := newCheckResult("verify-data-commitment", int64(height))
+		result.Detail = strconv.FormatUint(height, 10)
+		return s.printResult(result)
+	})
+	return cmd
+}
+
+func (s *session) utilizationCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "utilization <height> [<end-height>]",
+		Short: "Report how the shares of a block, or of a range of blocks, are used",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: s.needsCore(func(src blockSource, args []string) error {
+			heights, err := s.resolveHeights(src, args...)
+			if err != nil {
+				return err
+			}
+			// An optional end height aggregates over the inclusive range
+			start, end := heights[0], heights[len(heights)-1]
+			if start > end {
+				return usageError(fmt.Errorf("start height %d is above end height %d", start, end))
+			}
+			u := new(utilization)
+			for height := start; height <= end; height++ {
+				block, err := s.getSignedBlock(src, strconv.FormatInt(height, 10))
+				if err != nil {
+					return err
+				}
+				eds, err := s.extendBlock(block)
+				if err != nil {
+					return err
+				}
+				shares, err := originalShares(eds)
+				if err != nil {
+					return err
+				}
+				u.add(shares)
+			}
+			return s.printResult(u)
+		}),
+	}
+}
+
+func (s *session) paddingCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "padding <height>",
+		Short: "Report where the padding shares of a block are",
+		Args:  cobra.ExactArgs(1),
+		RunE: s.needsCore(func(src blockSource, args []string) error {
+			block, err := s.getSignedBlock(src, args[0])
+			if err != nil {
+				return err
+			}
+			eds, err := s.extendBlock(block)
+			if err != nil {
+				return err
+			}
+			shares, err := originalShares(eds)
+			if err != nil {
+				return err
+			}
+			return s.printResult(newPaddingReport(shares))
+		}),
+	}
+}
+
+func (s *session) namespacesCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "namespaces <height>",
+		Short: "List the namespaces in a block and the shares each uses",
+		Args:  cobra.ExactArgs(1),
+		RunE: s.needsCore(func(src blockSource, args []string) error {
+			block, err := s.getSignedBlock(src, args[0])
+			if err != nil {
+				return err
+			}
+			eds, err := s.extendBlock(block)
+			if err != nil {
+				return err
+			}
+			shares, err := originalShares(eds)
+			if err != nil {
+				return err
+			}
+			return s.printResult(newNamespaceUsages(shares))
+		}),
+	}
+}
+
+func (s *session) visualizeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "visualize <height>",
+		Short: "Draw a block's square with every share colored by its namespace",
+		Args:  cobra.ExactArgs(1),
+	}
+	pngFile := cmd.Flags().String("png", "", "write the map as a PNG image to this file instead of drawing it in the terminal")
+	cellSize := cmd.Flags().Int("cell-size", 8, "width and height in pixels of each share in the PNG image")
+	extended := cmd.Flags().Bool("extended", false, "draw the whole extended square, with the parity quadrants")
+	cmd.RunE = s.needsCore(func(src blockSource, args []string) error {
+		if *cellSize <= 0 {
+			return usageError(fmt.Errorf("--cell-size must be positive, got %d", *cellSize))
+		}
+		block, err := s.getSignedBlock(src, args[0])
+		if err != nil {
+			return err
+		}
+		eds, err := s.extendBlock(block)
+		if err != nil {
+			return err
+		}
+		m, err := newSquareMap(eds, *extended)
+		if err != nil {
+			return err
+		}
+		if *pngFile != "" {
+			return writeSquareMapPNG(*pngFile, m, *cellSize)
+		}
+		return s.printResult(m)
+	})
+	return cmd
+}
+
+func (s *session) verifyShareAgainstDAHCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify-share-against-dah <dah-file> <row> <namespace> <share-hex> <proof-file>",
+		Short: "Verify a share's proof against a row root of a saved DAH",
+		Args:  cobra.ExactArgs(5),
+		RunE: func(_ *cobra.Command, args []string) error {
+			dah, err := readDAH(args[0])
+			if err != nil {
+				return err
+			}
+			row, err := strconv.Atoi(args[1])
+			if err != nil {
+				return err
+			}
+			ns, err := parseNamespace(args[2])
+			if err != nil {
+				return err
+			}
+			sh, err := hex.DecodeString(args[3])
+			if err != nil {
+				return err
+			}
+			proof, err := readNMTProof(args[4])
+			if err != nil {
+				return err
+			}
+			if err := verifyShareAgainstDAH(dah, row, ns, sh, proof); err != nil {
+				return verificationFailed(err)
+			}
+			return s.printResult(newCheckResult("verify-share-against-dah", 0))
+		},
+	}
+}
+
+func (s *session) verifyProofCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify-proof <root-hex> <namespace> <proof-file> [<share-hex>...]",
+		Short: "Verify an NMT proof of shares against a row or column root",
+		Args:  cobra.MinimumNArgs(3),
+	}
+	complete := cmd.Flags().Bool("complete", false,
+		"verify the shares are all of the namespace's under the root, or its absence if none are given")
+	cmd.RunE = func(_ *cobra.Command, args []string) error {
+		root, err := hex.DecodeString(args[0])
+		if err != nil {
+			return usageError(fmt.Errorf("invalid root %q: %w", args[0], err))
+		}
+		ns, err := parseNamespace(args[1])
+		if err != nil {
+			return err
+		}
+		proof, err := readNMTProof(args[2])
+		if err != nil {
+			return err
+		}
+		shares := make([][]byte, 0, len(args)-3)
+		for _, arg := range args[3:] {
+			sh, err := hex.DecodeString(arg)
+			if err != nil {
+				return usageError(fmt.Errorf("invalid share %q: %w", arg, err))
+			}
+			shares = append(shares, sh)
+		}
+		if len(shares) == 0 && !*complete {
+			return usageError(errors.New("give the shares the proof covers, or --complete to verify absence"))
+		}
+		if err := verifyProof(root, ns, shares, proof, *complete); err != nil {
+			return verificationFailed(err)
+		}
+		return s.printResult(newCheckResult("verify-proof", 0))
+	}
+	return cmd
+}
+
+func (s *session) verifyRowCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify-row <height> <row> <shares-file>",
+		Short: "Verify a row of shares against a block's row root",
+		Args:  cobra.ExactArgs(3),
+		RunE: s.needsCore(func(src blockSource, args []string) error {
+			block, err := s.getSignedBlock(src, args[0])
+			if err != nil {
+				return err
+			}
+			row, err := strconv.Atoi(args[1])
+			if err != nil {
+				return err
+			}
+			shares, err := readShares(args[2])
+			if err != nil {
+				return err
+			}
+			eds, err := s.extendBlock(block)
+			if err != nil {
+				return err
+			}
+			dah, err := da.NewDataAvailabilityHeader(eds)
+			if err != nil {
+				return err
+			}
+			if err := verifyRow(&dah, row, shares); err != nil {
+				return verificationFailed(err)
+			}
+			return s.printResult(newCheckResult("verify-row", block.Header.Height))
+		}),
+	}
+}
+
+func (s *session) valsetCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "valset <height>",
+		Short: "Report the validator set of a block and how its voting power is spread",
+		Args:  cobra.ExactArgs(1),
+	}
+	topN := cmd.Flags().Int("top", 10, "number of largest validators to report the combined voting power of")
+	cmd.RunE = s.needsCore(func(src blockSource, args []string) error {
+		block, err := s.getSignedBlock(src, args[0])
+		if err != nil {
+			return err
+		}
+		return s.printResult(newValidatorSetReport(block.Header.Height, block.ValidatorSet, *topN))
+	})
+	return cmd
+}
+
+func (s *session) verifyParityCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify-parity <height>",
+		Short: "Check that the parity shares of a block's extended square re-encode from its data",
+		Args:  cobra.ExactArgs(1),
+		RunE: s.needsCore(func(src blockSource, args []string) error {
+			block, err := s.getSignedBlock(src, args[0])
+			if err != nil {
+				return err
+			}
+			eds, err := s.extendBlock(block)
+			if err != nil {
+				return err
+			}
+			if err := verifyParity(eds, s.extendCodec); err != nil {
+				return verificationFailed(err)
+			}
+			return s.printResult(newCheckResult("verify-parity", block.Header.Height))
+		}),
+	}
+}
+
+func (s *session) verifyEDSCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify-eds <height>",
+		Short: "Check that a block's extended square matches its original quadrant extended again",
+		Args:  cobra.ExactArgs(1),
+		RunE: s.needsCore(func(src blockSource, args []string) error {
+			block, err := s.getSignedBlock(src, args[0])
+			if err != nil {
+				return err
+			}
+			eds, err := s.extendBlock(block)
+			if err != nil {
+				return err
+			}
+			if err := s.verifyEDS(eds, s.appVersion(block.Header)); err != nil {
+				return verificationFailed(err)
+			}
+			return s.printResult(newCheckResult("verify-eds", block.Header.Height))
+		}),
+	}
+}
+
+func (s *session) auditRowsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "audit-rows <height>",
+		Short: "Rebuild the NMT of every row of a block's extended square and compare its root to the DAH",
+		Args:  cobra.ExactArgs(1),
+	}
+	columns := cmd.Flags().Bool("columns", false, "audit the columns instead of the rows")
+	cmd.RunE = s.needsCore(func(src blockSource, args []string) error {
+		block, err := s.getSignedBlock(src, args[0])
+		if err != nil {
+			return err
+		}
+		eds, err := s.extendBlock(block)
+		if err != nil {
+			return err
+		}
+		dah, err := da.NewDataAvailabilityHeader(eds)
+		if err != nil {
+			return err
+		}
+		axis := rsmt2d.Row
+		if *columns {
+			axis = rsmt2d.Col
+		}
+		audits, err := auditAxes(eds, &dah, axis)
+		if err != nil {
+			return err
+		}
+		if err := s.printResult(audits); err != nil {
+			return err
+		}
+		if n := audits.mismatches(); n > 0 {
+			return verificationFailed(fmt.Errorf("%d of %d %s roots of block %d don't match the DAH",
+				n, len(audits.Roots), axis, block.Header.Height))
+		}
+		return nil
+	})
+	return cmd
+}
+
+func (s *session) verifyBlockDataCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify-block-data <height>",
+		Short: "Check that a block's data round-trips through its square to the same data root",
+		Args:  cobra.ExactArgs(1),
+		RunE: s.needsCore(func(src blockSource, args []string) error {
+			block, err := s.getSignedBlock(src, args[0])
+			if err != nil {
+				return err
+			}
+			if err := s.verifyBlockData(block); err != nil {
+				return verificationFailed(err)
+			}
+			return s.printResult(newCheckResult("verify-block-data", block.Header.Height))
+		}),
+	}
+}
+
+func (s *session) verifyDataRootCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify-data-root <height>",
+		Short: "Recompute a block's data root from its extended shares and compare it to the header's DataHash",
+		Args:  cobra.ExactArgs(1),
+		RunE: s.needsCore(func(src blockSource, args []string) error {
+			block, err := s.getSignedBlock(src, args[0])
+			if err != nil {
+				return err
+			}
+			eds, err := s.extendBlock(block)
+			if err != nil {
+				return err
+			}
+			dah, err := da.NewDataAvailabilityHeader(eds)
+			if err != nil {
+				return err
+			}
+			check := newDataRootCheck(block.Header.Height, &dah, block.Header.DataHash)
+			if err := s.printResult(check); err != nil {
+				return err
+			}
+			if !check.Match {
+				return verificationFailed(fmt.Errorf("data root %s computed from the shares of block %d does not match its header's DataHash %s",
+					check.Computed, check.Height, check.Header))
+			}
+			return nil
+		}),
+	}
+}
+
+func (s *session) isEmptyCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "is-empty <height|latest>",
+		Short: "Check that a block's emptiness, its DAH and its header's data root agree",
+		Args:  cobra.ExactArgs(1),
+		RunE: s.needsCore(func(src blockSource, args []string) error {
+			block, err := s.getSignedBlock(src, args[0])
+			if err != nil {
+				return err
+			}
+			eds, err := s.extendBlock(block)
+			if err != nil {
+				return err
+			}
+			dah, err := da.NewDataAvailabilityHeader(eds)
+			if err != nil {
+				return err
+			}
+			check, err := s.newEmptyBlockCheck(block, &dah)
+			if err != nil {
+				return err
+			}
+			if err := s.printResult(check); err != nil {
+				return err
+			}
+			if !check.Agree {
+				return verificationFailed(fmt.Errorf("block %d: the checks of whether it is empty disagree", check.Height))
+			}
+			return nil
+		}),
+	}
+}
+
+func (s *session) reconstructCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "reconstruct <height>",
+		Short: "Reconstruct a block's extended square from a subset of its shares",
+		Args:  cobra.ExactArgs(1),
+	}
+	quadrant := cmd.Flags().Int("quadrant", 0, "quadrant of the extended square, numbered row-major, to reconstruct from")
+	drop := cmd.Flags().Float64("drop", 0, "reconstruct after dropping each share with this probability instead")
+	sample := cmd.Flags().Int("sample", 0,
+		"reconstruct from this many random shares instead, each verified against its row or column root first")
+	cmd.MarkFlagsMutuallyExclusive("quadrant", "drop", "sample")
+	cmd.RunE = s.needsCore(func(src blockSource, args []string) error {
+		block, err := s.getSignedBlock(src, args[0])
+		if err != nil {
+			return err
+		}
+		eds, err := s.extendBlock(block)
+		if err != nil {
+			return err
+		}
+		dah, err := da.NewDataAvailabilityHeader(eds)
+		if err != nil {
+			return err
+		}
+		// Repair with the codec and NMT options the square was extended
+		// with, which don't depend on the app version
+		extender := s.extenderFor(block.Header.Version.App)
+		if cmd.Flags().Changed("sample") {
+			samples, err := sampleProvenShares(eds, *sample)
+			if err != nil {
+				return usageError(err)
+			}
+			_, verified, err := extender.ReconstructFromProofs(samples, &dah)
+			if err != nil {
+				return verificationFailed(err)
+			}
+			result := newCheckResult("reconstruct", block.Header.Height)
+			result.Detail = fmt.Sprintf("reconstructed from %d verified shares", verified)
+			return s.printResult(result)
+		}
+		var shares [][]byte
+		if *drop > 0 {
+			shares, err = dropShares(eds, *drop)
+		} else {
+			shares, err = quadrantShares(eds, *quadrant)
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := extender.Reconstruct(shares, &dah); err != nil {
+			return verificationFailed(err)
+		}
+		return s.printResult(newCheckResult("reconstruct", block.Header.Height))
+	})
+	return cmd
+}
+
+func (s *session) verifyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify <height>",
+		Short: "Verify a block's commit against its validator set",
+		Args:  cobra.ExactArgs(1),
+	}
+	trustLevelFlag := cmd.Flags().String("trust-level", stateless.DefaultTrustLevel.String(),
+		"fraction of the voting power that must be exceeded by the power that signed, e.g. 1/3 as a skipping light client")
+	cmd.RunE = s.needsCore(func(src blockSource, args []string) error {
+		trustLevel, err := tmmath.ParseFraction(*trustLevelFlag)
+		if err != nil {
+			return usageError(fmt.Errorf("invalid --trust-level %q: %w", *trustLevelFlag, err))
+		}
+		if trustLevel.Numerator == 0 || trustLevel.Numerator > trustLevel.Denominator {
+			return usageError(fmt.Errorf("invalid --trust-level %q: must be in (0, 1]", *trustLevelFlag))
+		}
+		block, err := s.getSignedBlock(src, args[0])
+		if err != nil {
+			return err
+		}
+		power, err := stateless.VerifyCommitTrustLevel(block, trustLevel)
+		if err != nil {
+			// Still report how much of the voting power did sign
+			if power != nil {
+				if err := s.printResult(power); err != nil {
+					return err
+				}
+			}
+			return verificationFailed(err)
+		}
+		return s.printResult(&commitCheck{*newCheckResult("verify", block.Header.Height), power})
+	})
+	return cmd
+}
+
+func (s *session) verifyHeaderCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify-header <height>",
+		Short: "Check a block's header against its validator set, commit and data",
+		Args:  cobra.ExactArgs(1),
+		RunE: s.needsCore(func(src blockSource, args []string) error {
+			block, err := s.getSignedBlock(src, args[0])
+			if err != nil {
+				return err
+			}
+			eds, err := s.extendBlock(block)
+			if err != nil {
+				return err
+			}
+			dah, err := da.NewDataAvailabilityHeader(eds)
+			if err != nil {
+				return err
+			}
+			if err := stateless.VerifyHeader(block, &dah); err != nil {
+				return verificationFailed(err)
+			}
+			return s.printResult(newCheckResult("verify-header", block.Header.Height))
+		}),
+	}
+}
+
+func (s *session) verifyEHCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify-eh <file>",
+		Short: "Check an extended header saved with --json eds against its data root, validator set and commit",
+		Args:  cobra.ExactArgs(1),
+	}
+	dataRoot := cmd.Flags().String("data-root", "", "hex-encoded trusted data root the DAH must also hash to")
+	cmd.RunE = func(_ *cobra.Command, args []string) error {
+		var trustedRoot []byte
+		if *dataRoot != "" {
+			var err error
+			if trustedRoot, err = hex.DecodeString(*dataRoot); err != nil {
+				return usageError(fmt.Errorf("invalid --data-root %q: %w", *dataRoot, err))
+			}
+		}
+		eh, err := readExtendedHeader(args[0])
+		if err != nil {
+			return err
+		}
+		checks := checkExtendedHeader(eh, trustedRoot)
+		if err := s.printResult(checks); err != nil {
+			return err
+		}
+		if n := checks.failed(); n > 0 {
+			return verificationFailed(fmt.Errorf("%d of %d checks of extended header %d failed", n, len(checks), eh.Height))
+		}
+		return nil
+	}
+	return cmd
+}
+
+func (s *session) sampleCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sample <height> <n>",
+		Short: "Sample n random shares of a block, verifying each proof against the header's DAH",
+		Args:  cobra.ExactArgs(2),
+	}
+	seed := cmd.Flags().Int64("seed", 0, "seed of the random sample coordinates, random if 0; reported so runs can be repeated")
+	workers := cmd.Flags().Int("verify-workers", runtime.NumCPU(), "number of samples proven and verified in parallel")
+	cmd.RunE = s.needsCore(func(src blockSource, args []string) error {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 0 {
+			return usageError(fmt.Errorf("invalid sample count %q", args[1]))
+		}
+		if *workers < 1 {
+			return usageError(fmt.Errorf("invalid --verify-workers %d: must be at least 1", *workers))
+		}
+		if *seed == 0 {
+			*seed = time.Now().UnixNano()
+		}
+		block, err := s.getSignedBlock(src, args[0])
+		if err != nil {
+			return err
+		}
+		eds, err := s.extendBlock(block)
+		if err != nil {
+			return err
+		}
+		dah, err := da.NewDataAvailabilityHeader(eds)
+		if err != nil {
+			return err
+		}
+		// Light nodes sample against the DAH the header commits to
+		if err := stateless.VerifyDAH(block.Header, &dah); err != nil {
+			return verificationFailed(err)
+		}
+		report := sampleEDS(eds, &dah, n, *seed, *workers)
+		if err := s.printResult(report); err != nil {
+			return err
+		}
+		if failed := len(report.Failures); failed > 0 {
+			return verificationFailed(fmt.Errorf("%d of %d samples failed", failed, n))
+		}
+		return nil
+	})
+	return cmd
+}
+
+func (s *session) receiptCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "receipt <height> <file>",
+		Short: "Write an availability receipt for a block, with proofs of randomly sampled shares",
+		Args:  cobra.ExactArgs(2),
+	}
+	samples := cmd.Flags().Int("samples", 16, "number of random shares to include with proofs")
+	cmd.RunE = s.needsCore(func(src blockSource, args []string) error {
+		if *samples < 0 {
+			return fmt.Errorf("--samples must not be negative, got %d", *samples)
+		}
+		block, err := s.getSignedBlock(src, args[0])
+		if err != nil {
+			return err
+		}
+		eds, err := s.extendBlock(block)
+		if err != nil {
+			return err
+		}
+		receipt, err := newAvailabilityReceipt(block, eds, *samples)
+		if err != nil {
+			return err
+		}
+		return writeReceipt(args[1], receipt)
+	})
+	return cmd
+}
+
+func (s *session) verifyReceiptCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify-receipt <file>",
+		Short: "Verify an availability receipt offline",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			receipt, err := readReceipt(args[0])
+			if err != nil {
+				return err
+			}
+			if err := receipt.verify(); err != nil {
+				return verificationFailed(err)
+			}
+			return s.printResult(newCheckResult("verify-receipt", receipt.Header.Height))
+		},
+	}
+}
+
+func (s *session) namespaceProofCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "namespace-proof <height> <namespace>",
+		Short: "Prove the shares of a namespace in a block, or its absence, row by row",
+		Args:  cobra.ExactArgs(2),
+		RunE: s.needsCore(func(src blockSource, args []string) error {
+			ns, err := parseNamespace(args[1])
+			if err != nil {
+				return err
+			}
+			block, err := s.getSignedBlock(src, args[0])
+			if err != nil {
+				return err
+			}
+			eds, err := s.extendBlock(block)
+			if err != nil {
+				return err
+			}
+			dah, err := da.NewDataAvailabilityHeader(eds)
+			if err != nil {
+				return err
+			}
+			proofs, err := proveNamespaceRows(eds, &dah, ns)
+			if err != nil {
+				return err
+			}
+			return s.printResult(proofs)
+		}),
+	}
+}
+
+func (s *session) getNamespaceDataCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "get-namespace-data <height> <namespace>",
+		Short: "Print the shares of a namespace in a block with their row proofs, as celestia-node's NamespaceData",
+		Args:  cobra.ExactArgs(2),
+		RunE: s.needsCore(func(src blockSource, args []string) error {
+			ns, err := parseNamespace(args[1])
+			if err != nil {
+				return err
+			}
+			block, err := s.getSignedBlock(src, args[0])
+			if err != nil {
+				return err
+			}
+			eds, err := s.extendBlock(block)
+			if err != nil {
+				return err
+			}
+			dah, err := da.NewDataAvailabilityHeader(eds)
+			if err != nil {
+				return err
+			}
+			nd, err := newNamespaceData(eds, &dah, ns)
+			if err != nil {
+				return err
+			}
+			return s.printResult(nd)
+		}),
+	}
+}
+
+func (s *session) absenceProofCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "absence-proof <height> <namespace>",
+		Short: "Prove that a block has no shares of a namespace, failing if it has",
+		Args:  cobra.ExactArgs(2),
+		RunE: s.needsCore(func(src blockSource, args []string) error {
+			ns, err := parseNamespace(args[1])
+			if err != nil {
+				return err
+			}
+			block, err := s.getSignedBlock(src, args[0])
+			if err != nil {
+				return err
+			}
+			eds, err := s.extendBlock(block)
+			if err != nil {
+				return err
+			}
+			dah, err := da.NewDataAvailabilityHeader(eds)
+			if err != nil {
+				return err
+			}
+			if err := stateless.VerifyDAH(block.Header, &dah); err != nil {
+				return verificationFailed(err)
+			}
+			proof, err := newAbsenceProof(block.Header.Height, eds, &dah, ns)
+			if err != nil {
+				return verificationFailed(err)
+			}
+			return s.printResult(proof)
+		}),
+	}
+}
+
+func (s *session) verifyNamespaceProofCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify-namespace-proof <dah-file> <namespace> <proofs-file>",
+		Short: "Verify namespace-proof output against a saved DAH",
+		Args:  cobra.ExactArgs(3),
+		RunE: func(_ *cobra.Command, args []string) error {
+			dah, err := readDAH(args[0])
+			if err != nil {
+				return err
+			}
+			ns, err := parseNamespace(args[1])
+			if err != nil {
+				return err
+			}
+			proofs, err := readNamespaceProofs(args[2])
+			if err != nil {
+				return err
+			}
+			if err := proofs.verify(dah.RowRoots, ns); err != nil {
+				return verificationFailed(err)
+			}
+			return s.printResult(newCheckResult("verify-namespace-proof", 0))
+		},
+	}
+}
+
+func (s *session) exportNamespaceProofsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "export-namespace-proofs <height> <namespace> <file>",
+		Short: "Write the namespace proofs of a block, with its DAH, to a file",
+		Args:  cobra.ExactArgs(3),
+		RunE: s.needsCore(func(src blockSource, args []string) error {
+			ns, err := parseNamespace(args[1])
+			if err != nil {
+				return err
+			}
+			block, err := s.getSignedBlock(src, args[0])
+			if err != nil {
+				return err
+			}
+			eds, err := s.extendBlock(block)
+			if err != nil {
+				return err
+			}
+			bundle, err := newNamespaceProofBundle(block.Header.Height, eds, ns)
+			if err != nil {
+				return err
+			}
+			return writeNamespaceProofBundle(args[2], bundle)
+		}),
+	}
+}
+
+func (s *session) verifyNamespaceProofsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify-namespace-proofs <file> <data-hash>",
+		Short: "Verify exported namespace proofs against a block's data hash offline",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(_ *cobra.Command, args []string) error {
+			bundle, err := readNamespaceProofBundle(args[0])
+			if err != nil {
+				return err
+			}
+			dataHash, err := hex.DecodeString(args[1])
+			if err != nil {
+				return err
+			}
+			if err := bundle.verify(dataHash); err != nil {
+				return verificationFailed(err)
+			}
+			return s.printResult(newCheckResult("verify-namespace-proofs", bundle.Height))
+		},
+	}
+}
+
+func (s *session) exportCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "export <height> <file>",
+		Short: "Write a block's extended square to a CAR file",
+		Args:  cobra.ExactArgs(2),
+		RunE: s.needsCore(func(src blockSource, args []string) error {
+			block, err := s.getSignedBlock(src, args[0])
+			if err != nil {
+				return err
+			}
+			eds, err := s.extendBlock(block)
+			if err != nil {
+				return err
+			}
+			return writeCARFile(args[1], eds)
+		}),
+	}
+}
+
+func (s *session) archiveCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "archive <height> <dir>",
+		Short: "Write a block's extended square as a CAR and its extended header as JSON, checked against each other",
+		Args:  cobra.ExactArgs(2),
+		RunE: s.needsCore(func(src blockSource, args []string) error {
+			block, err := s.getSignedBlock(src, args[0])
+			if err != nil {
+				return err
+			}
+			eds, err := s.extendBlock(block)
+			if err != nil {
+				return err
+			}
+			eh, err := s.makeExtendedHeader(block, eds)
+			if err != nil {
+				return err
+			}
+			archived, err := archiveBlock(args[1], eh, eds)
+			if err != nil {
+				return err
+			}
+			return s.printResult(archived)
+		}),
+	}
+}
+
+func (s *session) importCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "import <file> [<height>]",
+		Short: "Read an extended square from a CAR file and print its DAH, or check it against a block",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: func(_ *cobra.Command, args []string) error {
+			eds, err := readCARFile(args[0])
+			if err != nil {
+				return err
+			}
+			dah, err := da.NewDataAvailabilityHeader(eds)
+			if err != nil {
+				return err
+			}
+			// Without a height the imported square's DAH is printed as is
+			if len(args) < 2 {
+				return s.printResult(&dah)
+			}
+			src, err := s.blocks()
+			if err != nil {
+				return err
+			}
+			block, err := s.getSignedBlock(src, args[1])
+			if err != nil {
+				return err
+			}
+			if err := stateless.VerifyDAH(block.Header, &dah); err != nil {
+				return verificationFailed(err)
+			}
+			return s.printResult(newCheckResult("import", block.Header.Height))
+		},
+	}
+}
+
+func (s *session) sharesCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "shares <height> <file>",
+		Short: "Write the shares of a block's original data square to a file, with its square and share size",
+		Args:  cobra.ExactArgs(2),
+		RunE: s.needsCore(func(src blockSource, args []string) error {
+			block, err := s.getSignedBlock(src, args[0])
+			if err != nil {
+				return err
+			}
+			eds, err := s.extendBlock(block)
+			if err != nil {
+				return err
+			}
+			return writeODSFile(args[1], eds)
+		}),
+	}
+}
+
+func (s *session) extendSharesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "extend-shares <file>",
+		Short: "Extend a file of original data square shares, raw or written by shares, and print its DAH",
+		Args:  cobra.ExactArgs(1),
+	}
+	pad := cmd.Flags().Bool("pad", false,
+		"pad the shares with tail padding up to the next power-of-2 square instead of failing; changes the DAH")
+	cmd.RunE = func(_ *cobra.Command, args []string) error {
+		shares, err := readODSShares(args[0])
+		if err != nil {
+			return err
+		}
+		if *pad {
+			padded := stateless.PadShares(shares)
+			slog.Debug("padded shares", "shares", len(shares), "padding", len(padded)-len(shares))
+			shares = padded
+		}
+		eds, err := s.extenderFor(s.headerlessAppVersion()).ExtendShares(shares)
+		if err != nil {
+			return decodeFailed(err)
+		}
+		dah, err := da.NewDataAvailabilityHeader(eds)
+		if err != nil {
+			return err
+		}
+		return s.printResult(&dah)
+	}
+	return cmd
+}
+
+func (s *session) watchDirCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "watch-dir <dir>",
+		Short: "Extend every block file written to a directory and print its extended header",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+			return s.watchDir(ctx, args[0], func(eh *stateless.ExtendedHeader) error {
+				return s.printResult(eh)
+			})
+		},
+	}
+}
+
+func (s *session) serveCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve extended headers, shares and DAHs of blocks as JSON over HTTP",
+		Args:  cobra.NoArgs,
+	}
+	listenAddr := cmd.Flags().String("listen-addr", "localhost:8080", "address to serve HTTP on")
+	cmd.RunE = s.needsCore(func(src blockSource, _ []string) error {
+		if dry, ok := src.(*dryRunSource); ok {
+			if _, _, err := net.SplitHostPort(*listenAddr); err != nil {
+				return usageError(fmt.Errorf("invalid --listen-addr: %w", err))
+			}
+			return dry.would("serve blocks on %s", *listenAddr)
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return (&server{session: s, src: src}).serve(ctx, *listenAddr)
+	})
+	return cmd
+}
+
+func (s *session) benchCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bench",
+		Short: "Measure how fast this machine processes blocks",
+	}
+	extend := &cobra.Command{
+		Use:   "extend [<height|latest>]",
+		Short: "Measure extension throughput on a block, fetched once, or on random squares",
+		Args:  cobra.MaximumNArgs(1),
+	}
+	iterations := extend.Flags().Int("iterations", 10, "times to extend the square")
+	size := extend.Flags().Int("size", 0,
+		"extend a random square of this power-of-2 width instead of a block, without --core")
+	seed := extend.Flags().Int64("seed", 1, "seed of the random square's data")
+	extend.RunE = func(_ *cobra.Command, args []string) error {
+		var run extendFunc
+		switch {
+		case *size != 0 && len(args) > 0:
+			return usageError(errors.New("give either a height or --size, not both"))
+		case *size != 0:
+			var err error
+			if run, err = s.extendRandomSquare(*size, *seed); err != nil {
+				return err
+			}
+		case len(args) == 0:
+			return usageError(errors.New("a height or --size is required"))
+		default:
+			src, err := s.blocks()
+			if err != nil {
+				return err
+			}
+			block, err := s.getSignedBlock(src, args[0])
+			if err != nil {
+				return err
+			}
+			run = s.extendSignedBlock(block)
+		}
+		report, err := s.benchExtend(*iterations, run)
+		if err != nil {
+			return err
+		}
+		return s.printResult(report)
+	}
+	fetch := &cobra.Command{
+		Use:   "fetch <height|latest>",
+		Short: "Compare the time to fetch a block from core with pipelined and sequential reassembly",
+		Args:  cobra.ExactArgs(1),
+	}
+	fetchIterations := fetch.Flags().Int("iterations", 10, "times to fetch the block with each reassembly")
+	fetch.RunE = s.needsCore(func(src blockSource, args []string) error {
+		var core *stateless.CoreAccessor
+		switch src := src.(type) {
+		case *stateless.CoreAccessor:
+			core = src
+		case *blockCache:
+			core = src.core
+		case *dryRunSource:
+			_, err := src.GetSignedBlock(context.Background(), args[0])
+			return err
+		default:
+			return usageError(errors.New("bench fetch needs a core endpoint, not a block file"))
+		}
+		report, err := s.benchFetch(core, args[0], *fetchIterations, s.sequential)
+		if err != nil {
+			return err
+		}
+		return s.printResult(report)
+	})
+	cmd.AddCommand(extend, fetch)
+	return cmd
+}
+
+func (s *session) replCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "repl",
+		Short: "Read commands interactively and run them against the same core connection",
+		Args:  cobra.NoArgs,
+		RunE: func(*cobra.Command, []string) error {
+			return runREPL(s)
+		},
+	}
+}
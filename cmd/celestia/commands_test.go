@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	libshare "github.com/celestiaorg/go-square/v2/share"
+	"github.com/tendermint/tendermint/crypto/merkle"
+)
+
+func TestParseNamespace(t *testing.T) {
+	blobNS := libshare.MustNewV0Namespace([]byte("parse"))
+	for _, tc := range []struct {
+		name string
+		arg  string
+		// want is a substring of the error, empty if there is none. A
+		// blob namespace error applies to parseBlobNamespace only.
+		want, wantBlob string
+	}{
+		{name: "v0", arg: hex.EncodeToString(blobNS.Bytes())},
+		{name: "tx namespace", arg: hex.EncodeToString(libshare.TxNamespace.Bytes()), wantBlob: "reserved"},
+		{name: "pay for blob namespace", arg: hex.EncodeToString(libshare.PayForBlobNamespace.Bytes()), wantBlob: "reserved"},
+		{name: "parity namespace", arg: hex.EncodeToString(libshare.ParitySharesNamespace.Bytes()), wantBlob: "reserved"},
+		{name: "tail padding namespace", arg: hex.EncodeToString(libshare.TailPaddingNamespace.Bytes()), wantBlob: "reserved"},
+		{name: "not hex", arg: "zz", want: "not hex"},
+		{name: "empty", arg: "", want: "wrong length, 0 bytes instead of 29"},
+		{name: "too short", arg: hex.EncodeToString(blobNS.Bytes()[1:]), want: "wrong length, 28 bytes instead of 29"},
+		{name: "too long", arg: hex.EncodeToString(append(blobNS.Bytes(), 0)), want: "wrong length, 30 bytes instead of 29"},
+		{name: "unsupported version", arg: "01" + hex.EncodeToString(blobNS.Bytes()[1:]), want: "unsupported version 1"},
+		{name: "v0 with non-zero prefix", arg: "00" + strings.Repeat("01", 28), want: "version 0 IDs must start with 18 zero bytes"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parseNamespace(tc.arg)
+			checkUsageError(t, err, tc.want)
+			_, err = parseBlobNamespace(tc.arg)
+			if tc.want != "" {
+				checkUsageError(t, err, tc.want)
+			} else {
+				checkUsageError(t, err, tc.wantBlob)
+			}
+		})
+	}
+}
+
+// checkUsageError fails t unless err is nil when want is empty, or else a
+// usage error containing want.
+func checkUsageError(t *testing.T, err error, want string) {
+	t.Helper()
+	switch {
+	case want == "" && err != nil:
+		t.Errorf("unexpected error: %v", err)
+	case want == "":
+	case err == nil:
+		t.Errorf("no error, want one containing %q", want)
+	case !strings.Contains(err.Error(), want):
+		t.Errorf("error %q does not contain %q", err, want)
+	case exitCode(err) != exitUsage:
+		t.Errorf("error %q exits with code %d, want %d", err, exitCode(err), exitUsage)
+	}
+}
+
+func TestVerifyDataCommitmentArgs(t *testing.T) {
+	addr := startFakeCore(t)
+	commitment := strings.Repeat("ab", 32)
+	for _, tc := range []struct {
+		name                   string
+		start, end, commitment string
+		want                   string
+	}{
+		{"start not a number", "first", "5", commitment, `invalid height "first"`},
+		{"malformed tip offset", "1", "latest-x", commitment, `invalid height "latest-x"`},
+		// The commitment is checked before the heights are resolved
+		{"commitment not hex", "latest", "5", "xyz", `invalid commitment "xyz": not hex`},
+		{"short commitment", "latest", "5", "abcd", "wrong length, 2 bytes instead of 32"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := runCLI(t, addr, "verify-data-commitment", tc.start, tc.end,
+				"--commitment", tc.commitment, "--proof", "proof.json")
+			checkUsageError(t, err, tc.want)
+		})
+	}
+}
+
+func TestDataCommitmentHeight(t *testing.T) {
+	proof := &merkle.Proof{Total: 4, Index: 2}
+	for _, tc := range []struct {
+		name       string
+		start, end uint64
+		want       string
+	}{
+		{"range", 10, 14, ""},
+		{"start at genesis", 0, 4, "invalid data commitment range [0, 4)"},
+		{"empty range", 10, 10, "invalid data commitment range [10, 10)"},
+		{"start above end", 14, 10, "invalid data commitment range [14, 10)"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			height, err := dataCommitmentHeight(tc.start, tc.end, proof)
+			checkUsageError(t, err, tc.want)
+			if tc.want == "" && height != tc.start+2 {
+				t.Errorf("height %d, want %d", height, tc.start+2)
+			}
+		})
+	}
+}
+
+// TestDryRunCellArgs checks that --dry-run rejects the cell indices of the
+// share and proof commands, which are checked before the block is fetched.
+func TestDryRunCellArgs(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		args []string
+		// want is a substring of the usage error, empty if the dry run
+		// passes.
+		want string
+	}{
+		{"share", []string{"share", "5", "1", "2"}, ""},
+		{"share row not a number", []string{"share", "5", "abc", "xyz"}, `invalid row "abc": not an integer`},
+		{"share negative column", []string{"share", "--", "5", "1", "-2"}, "invalid column -2: negative"},
+		{"share --row", []string{"share", "5", "--row", "3"}, ""},
+		{"share --row not a number", []string{"share", "5", "--row", "x"}, `invalid row "x"`},
+		{"share --col negative", []string{"share", "5", "--col=-1"}, "invalid column -1: negative"},
+		{"proof", []string{"proof", "5", "3", "7"}, ""},
+		{"proof negative row", []string{"proof", "--", "5", "-3", "zz"}, "invalid row -3: negative"},
+		{"proof column not a number", []string{"proof", "5", "3", "zz"}, `invalid column "zz"`},
+		{"col-proof", []string{"col-proof", "5", "7", "3"}, ""},
+		{"col-proof column not a number", []string{"col-proof", "5", "c", "3"}, `invalid column "c"`},
+		{"col-proof negative row", []string{"col-proof", "--", "5", "7", "-3"}, "invalid row -3: negative"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := runCLI(t, "localhost:9090", append([]string{"--dry-run"}, tc.args...)...)
+			checkUsageError(t, err, tc.want)
+		})
+	}
+}
+
+func TestCodecMemory(t *testing.T) {
+	for _, tc := range []struct {
+		name, value, want string
+	}{
+		{"valid", "1048576", "--codec-memory is not supported by the Leopard codec"},
+		{"empty", "", `invalid --codec-memory ""`},
+		{"zero", "0", `invalid --codec-memory "0"`},
+		{"not a number", "1GiB", `invalid --codec-memory "1GiB"`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := runCLI(t, "localhost:9090", "--dry-run", "--codec-memory="+tc.value, "eds", "5")
+			checkUsageError(t, err, tc.want)
+		})
+	}
+}
+
+func TestChainIDMismatch(t *testing.T) {
+	addr := startFakeCore(t, testBlock(t, 1))
+	if _, err := runCLI(t, addr, "--chain-id", "test", "eds", "1"); err != nil {
+		t.Fatalf("block of the expected chain: %v", err)
+	}
+	_, err := runCLI(t, addr, "--chain-id", "mocha-4", "eds", "1")
+	if err == nil || !strings.Contains(err.Error(), `block 1 is from chain "test", expected "mocha-4"`) {
+		t.Fatalf("got error %v, want a chain ID mismatch", err)
+	}
+	if code := exitCode(err); code != exitVerification {
+		t.Errorf("exit code %d, want %d", code, exitVerification)
+	}
+}
+
+func TestUtilizationHeights(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"one height", []string{"utilization", "5"}, ""},
+		{"range", []string{"utilization", "3", "9"}, ""},
+		{"start above end", []string{"utilization", "9", "3"}, "start height 9 is above end height 3"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := runCLI(t, "localhost:9090", append([]string{"--dry-run"}, tc.args...)...)
+			checkUsageError(t, err, tc.want)
+		})
+	}
+}
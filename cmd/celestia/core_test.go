@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/adlerjohn/celestia-node-stateless/pkg/stateless"
+	p2p "github.com/tendermint/tendermint/proto/tendermint/p2p"
+	coregrpc "github.com/tendermint/tendermint/rpc/grpc"
+	"github.com/tendermint/tendermint/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeCore is a core BlockAPI serving blocks, by height, as core does.
+type fakeCore struct {
+	coregrpc.UnimplementedBlockAPIServer
+	blocks map[int64]*stateless.SignedBlock
+}
+
+func (c *fakeCore) BlockByHeight(req *coregrpc.BlockByHeightRequest, srv coregrpc.BlockAPI_BlockByHeightServer) error {
+	block, ok := c.blocks[req.Height]
+	if !ok {
+		return status.Errorf(codes.Unknown, "nil block meta for height %d", req.Height)
+	}
+	full := &types.Block{Header: *block.Header, Data: *block.Data, LastCommit: &types.Commit{}}
+	parts := full.MakePartSet(types.BlockPartSizeBytes)
+	validators, err := block.ValidatorSet.ToProto()
+	if err != nil {
+		return err
+	}
+	for i := range int(parts.Total()) {
+		part, err := parts.GetPart(i).ToProto()
+		if err != nil {
+			return err
+		}
+		resp := &coregrpc.StreamedBlockByHeightResponse{BlockPart: part, IsLast: i == int(parts.Total())-1}
+		if i == 0 {
+			resp.Commit, resp.ValidatorSet = block.Commit.ToProto(), validators
+		}
+		if err := srv.Send(resp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *fakeCore) Commit(_ context.Context, req *coregrpc.CommitRequest) (*coregrpc.CommitResponse, error) {
+	block, ok := c.blocks[req.Height]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "no commit for height %d", req.Height)
+	}
+	return &coregrpc.CommitResponse{Commit: block.Commit.ToProto()}, nil
+}
+
+func (c *fakeCore) Status(context.Context, *coregrpc.StatusRequest) (*coregrpc.StatusResponse, error) {
+	var latest, earliest int64
+	for height := range c.blocks {
+		latest = max(latest, height)
+		if earliest == 0 || height < earliest {
+			earliest = height
+		}
+	}
+	return &coregrpc.StatusResponse{
+		NodeInfo: &p2p.DefaultNodeInfo{Network: "test"},
+		SyncInfo: &coregrpc.SyncInfo{LatestBlockHeight: latest, EarliestBlockHeight: earliest},
+	}, nil
+}
+
+// startFakeCore serves blocks on a local port and returns its address.
+func startFakeCore(t *testing.T, blocks ...*stateless.SignedBlock) string {
+	t.Helper()
+	core := &fakeCore{blocks: make(map[int64]*stateless.SignedBlock)}
+	for _, block := range blocks {
+		core.blocks[block.Header.Height] = block
+	}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	coregrpc.RegisterBlockAPIServer(srv, core)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	return lis.Addr().String()
+}
+
+// runCLI runs the command line args against the core at addr and returns
+// the results it wrote, through --output-file, along with its error.
+func runCLI(t *testing.T, addr string, args ...string) (string, error) {
+	t.Helper()
+	out := filepath.Join(t.TempDir(), "out")
+	s := new(session)
+	root := newRootCmd(s)
+	root.SetArgs(append([]string{"--core", addr, "--output-file", out}, args...))
+	err := s.finish(root.Execute())
+	bz, readErr := os.ReadFile(out)
+	if readErr != nil && !os.IsNotExist(readErr) {
+		t.Fatal(readErr)
+	}
+	return string(bz), err
+}
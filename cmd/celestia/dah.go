@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/adlerjohn/celestia-node-stateless/pkg/stateless"
+	"github.com/celestiaorg/celestia-app/v3/app"
+	"github.com/celestiaorg/celestia-app/v3/pkg/da"
+	libsquare "github.com/celestiaorg/go-square/v2"
+	libshare "github.com/celestiaorg/go-square/v2/share"
+	tmbytes "github.com/tendermint/tendermint/libs/bytes"
+)
+
+// dahSummary is the data root of a block and the shape of its DAH.
+type dahSummary struct {
+	DataRoot    tmbytes.HexBytes `json:"data_root"`
+	SquareSize  int              `json:"square_size"`
+	RowRoots    int              `json:"row_roots"`
+	ColumnRoots int              `json:"column_roots"`
+	// Empty is set when the DAH is that of a block without user data.
+	Empty bool `json:"empty"`
+}
+
+func newDAHSummary(dah *da.DataAvailabilityHeader) *dahSummary {
+	minDAH := da.MinDataAvailabilityHeader()
+	return &dahSummary{
+		DataRoot:    dah.Hash(),
+		SquareSize:  dah.SquareSize(),
+		RowRoots:    len(dah.RowRoots),
+		ColumnRoots: len(dah.ColumnRoots),
+		Empty:       dah.Equals(&minDAH),
+	}
+}
+
+func (s *dahSummary) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "data root: %s", s.DataRoot)
+	if s.Empty {
+		b.WriteString(" (empty block)")
+	}
+	fmt.Fprintf(&b, "\nsquare size: %d\n", s.SquareSize)
+	fmt.Fprintf(&b, "row roots: %d\n", s.RowRoots)
+	fmt.Fprintf(&b, "column roots: %d", s.ColumnRoots)
+	return b.String()
+}
+
+// dataRootCheck compares the data root computed from a block's extended
+// shares with the DataHash its header commits to.
+type dataRootCheck struct {
+	Height   int64            `json:"height"`
+	Computed tmbytes.HexBytes `json:"computed"`
+	Header   tmbytes.HexBytes `json:"header"`
+	Match    bool             `json:"match"`
+}
+
+func newDataRootCheck(height int64, dah *da.DataAvailabilityHeader, dataHash []byte) *dataRootCheck {
+	computed := dah.Hash()
+	return &dataRootCheck{
+		Height:   height,
+		Computed: computed,
+		Header:   dataHash,
+		Match:    bytes.Equal(computed, dataHash),
+	}
+}
+
+func (c *dataRootCheck) String() string {
+	result := "PASS"
+	if !c.Match {
+		result = "MISMATCH"
+	}
+	return fmt.Sprintf("computed data root: %s\nheader data hash:   %s\n%s", c.Computed, c.Header, result)
+}
+
+// emptyBlockCheck reports whether a block is empty by each of the
+// definitions that should agree on it: celestia-app's IsEmptyBlockRef,
+// which picks the shortcut extendBlock takes, the DAH of its extended
+// square, and the data root its header commits to.
+type emptyBlockCheck struct {
+	Height     int64  `json:"height"`
+	AppVersion uint64 `json:"app_version"`
+	// Empty is what IsEmptyBlockRef says, and Path the way the square was
+	// extended as a result.
+	Empty bool   `json:"empty"`
+	Path  string `json:"path"`
+	// MinDAH is set when the DAH is da.MinDataAvailabilityHeader.
+	MinDAH bool `json:"min_dah"`
+	// EmptyDataRoot is set when the header's DataHash is the data root of
+	// an empty block.
+	EmptyDataRoot bool `json:"empty_data_root"`
+	// Constructed is set for an empty block when square construction,
+	// skipping the shortcut, yields the same DAH.
+	Constructed *bool `json:"constructed,omitempty"`
+	Agree       bool  `json:"agree"`
+}
+
+// Paths extendBlock takes through an empty block and any other.
+const (
+	emptyEDSPath  = "share.EmptyEDS"
+	constructPath = "square construction"
+)
+
+func (s *session) newEmptyBlockCheck(block *stateless.SignedBlock, dah *da.DataAvailabilityHeader) (*emptyBlockCheck, error) {
+	version := s.appVersion(block.Header)
+	minDAH := da.MinDataAvailabilityHeader()
+	c := &emptyBlockCheck{
+		Height:     block.Header.Height,
+		AppVersion: version,
+		Empty:      app.IsEmptyBlockRef(block.Data, version),
+		Path:       constructPath,
+		MinDAH:     dah.Equals(&minDAH),
+		// Every app version commits to the minimal DAH for an empty block
+		EmptyDataRoot: bytes.Equal(block.Header.DataHash, minDAH.Hash()),
+	}
+	c.Agree = c.Empty == c.MinDAH && c.Empty == c.EmptyDataRoot
+	if !c.Empty {
+		return c, nil
+	}
+	c.Path = emptyEDSPath
+	square, err := libsquare.Construct(block.Data.Txs.ToSliceOfBytes(),
+		stateless.SquareSizeUpperBound(version), stateless.SubtreeRootThreshold(version))
+	if err != nil {
+		return nil, err
+	}
+	eds, err := s.extenderFor(version).ExtendShares(libshare.ToBytes(square))
+	if err != nil {
+		return nil, err
+	}
+	constructed, err := da.NewDataAvailabilityHeader(eds)
+	if err != nil {
+		return nil, err
+	}
+	match := constructed.Equals(dah)
+	c.Constructed = &match
+	c.Agree = c.Agree && match
+	return c, nil
+}
+
+func (c *emptyBlockCheck) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "height %d, app version %d\n", c.Height, c.AppVersion)
+	fmt.Fprintf(&b, "empty by IsEmptyBlockRef: %t, extended by %s\n", c.Empty, c.Path)
+	fmt.Fprintf(&b, "DAH is the minimal DAH: %t\n", c.MinDAH)
+	fmt.Fprintf(&b, "header data hash is the empty data root: %t\n", c.EmptyDataRoot)
+	if c.Constructed != nil {
+		fmt.Fprintf(&b, "square construction yields the same DAH: %t\n", *c.Constructed)
+	}
+	if c.Agree {
+		b.WriteString("PASS")
+	} else {
+		b.WriteString("DISAGREE")
+	}
+	return b.String()
+}
+
+// dahRoots lists every root of a block's DAH, for diffing against another
+// node's view of the same height.
+type dahRoots struct {
+	RowRoots    []tmbytes.HexBytes `json:"row_roots"`
+	ColumnRoots []tmbytes.HexBytes `json:"column_roots"`
+	Hash        tmbytes.HexBytes   `json:"hash"`
+}
+
+func newDAHRoots(dah *da.DataAvailabilityHeader) *dahRoots {
+	r := &dahRoots{Hash: dah.Hash()}
+	for _, root := range dah.RowRoots {
+		r.RowRoots = append(r.RowRoots, root)
+	}
+	for _, root := range dah.ColumnRoots {
+		r.ColumnRoots = append(r.ColumnRoots, root)
+	}
+	return r
+}
+
+func (r *dahRoots) String() string {
+	var b strings.Builder
+	for i, root := range r.RowRoots {
+		fmt.Fprintf(&b, "row %d: %s\n", i, root)
+	}
+	for i, root := range r.ColumnRoots {
+		fmt.Fprintf(&b, "col %d: %s\n", i, root)
+	}
+	fmt.Fprintf(&b, "hash: %s", r.Hash)
+	return b.String()
+}
+
+// dahDiff compares the DAHs of two blocks root by root.
+type dahDiff struct {
+	DataRoots   [2]tmbytes.HexBytes `json:"data_roots"`
+	SquareSizes [2]int              `json:"square_sizes"`
+	// RowRoots and ColumnRoots are the indices of the roots that differ,
+	// including those only one of the DAHs has.
+	RowRoots    []int `json:"row_roots"`
+	ColumnRoots []int `json:"column_roots"`
+}
+
+func newDAHDiff(a, b *da.DataAvailabilityHeader) *dahDiff {
+	return &dahDiff{
+		DataRoots:   [2]tmbytes.HexBytes{a.Hash(), b.Hash()},
+		SquareSizes: [2]int{a.SquareSize(), b.SquareSize()},
+		RowRoots:    differingRoots(a.RowRoots, b.RowRoots),
+		ColumnRoots: differingRoots(a.ColumnRoots, b.ColumnRoots),
+	}
+}
+
+// differingRoots returns the indices at which the roots of a and b differ.
+func differingRoots(a, b [][]byte) []int {
+	diff := []int{}
+	for i := 0; i < max(len(a), len(b)); i++ {
+		if i >= len(a) || i >= len(b) || !bytes.Equal(a[i], b[i]) {
+			diff = append(diff, i)
+		}
+	}
+	return diff
+}
+
+// match reports whether the two DAHs are the same.
+func (d *dahDiff) match() bool {
+	return bytes.Equal(d.DataRoots[0], d.DataRoots[1])
+}
+
+func (d *dahDiff) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "data roots: %s %s", d.DataRoots[0], d.DataRoots[1])
+	if d.match() {
+		b.WriteString(" (match)")
+	}
+	fmt.Fprintf(&b, "\nsquare sizes: %d %d\n", d.SquareSizes[0], d.SquareSizes[1])
+	fmt.Fprintf(&b, "differing row roots: %v\n", d.RowRoots)
+	fmt.Fprintf(&b, "differing column roots: %v", d.ColumnRoots)
+	return b.String()
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/tendermint/tendermint/crypto/merkle"
+	tmjson "github.com/tendermint/tendermint/libs/json"
+)
+
+// encodeDataRootTuple returns the ABI encoding of a Blobstream
+// DataRootTuple: the height left-padded to 32 bytes followed by the data
+// root. It matches EncodeDataRootTuple in celestia-core.
+func encodeDataRootTuple(height uint64, dataRoot []byte) ([]byte, error) {
+	if len(dataRoot) != 32 {
+		return nil, fmt.Errorf("data root must be 32 bytes: got %d", len(dataRoot))
+	}
+	tuple := make([]byte, 64)
+	binary.BigEndian.PutUint64(tuple[24:32], height)
+	copy(tuple[32:], dataRoot)
+	return tuple, nil
+}
+
+// readDataRootInclusionProof reads a Merkle proof of a data root tuple as
+// core's DataRootInclusionProof endpoint returns it: tmjson-encoded, with
+// int64 fields quoted, and wrapped in a ResultDataRootInclusionProof's
+// "proof" field, itself optionally in the "result" of a JSON-RPC response.
+// A bare proof is accepted too.
+func readDataRootInclusionProof(path string) (*merkle.Proof, error) {
+	bz, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var envelope struct {
+		Result json.RawMessage `json:"result"`
+		Proof  json.RawMessage `json:"proof"`
+	}
+	if err := json.Unmarshal(bz, &envelope); err != nil {
+		return nil, decodeFailed(fmt.Errorf("decoding proof %s: %w", path, err))
+	}
+	if len(envelope.Result) > 0 {
+		bz, envelope.Proof = envelope.Result, nil
+		if err := json.Unmarshal(bz, &envelope); err != nil {
+			return nil, decodeFailed(fmt.Errorf("decoding proof %s: result: %w", path, err))
+		}
+	}
+	if len(envelope.Proof) > 0 {
+		bz = envelope.Proof
+	}
+	proof := new(merkle.Proof)
+	if err := tmjson.Unmarshal(bz, proof); err != nil {
+		return nil, decodeFailed(fmt.Errorf("decoding proof %s: %w", path, err))
+	}
+	if err := proof.ValidateBasic(); err != nil {
+		return nil, decodeFailed(fmt.Errorf("proof %s: %w", path, err))
+	}
+	return proof, nil
+}
+
+// dataCommitmentHeight returns the height whose data root tuple the given
+// proof commits to, within the end-exclusive range [start, end).
+func dataCommitmentHeight(start, end uint64, proof *merkle.Proof) (uint64, error) {
+	if start == 0 || start >= end {
+		return 0, usageError(fmt.Errorf("invalid data commitment range [%d, %d)", start, end))
+	}
+	if uint64(proof.Total) != end-start {
+		return 0, fmt.Errorf("proof covers %d tuples, range [%d, %d) has %d", proof.Total, start, end, end-start)
+	}
+	return start + uint64(proof.Index), nil
+}
+
+// verifyDataRootInclusion verifies that the data root of the block at the
+// given height is included in the Blobstream data commitment using proof.
+func verifyDataRootInclusion(height uint64, dataRoot, commitment []byte, proof *merkle.Proof) error {
+	tuple, err := encodeDataRootTuple(height, dataRoot)
+	if err != nil {
+		return err
+	}
+	return proof.Verify(commitment, tuple)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/adlerjohn/celestia-node-stateless/pkg/stateless"
+	"github.com/tendermint/tendermint/crypto/merkle"
+	tmjson "github.com/tendermint/tendermint/libs/json"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	rpctypes "github.com/tendermint/tendermint/rpc/jsonrpc/types"
+)
+
+// TestReadDataRootInclusionProof commits to the data roots of blocks 1 to 3
+// and checks block 2's proof, encoded as core serves it, against them.
+func TestReadDataRootInclusionProof(t *testing.T) {
+	blocks := []*stateless.SignedBlock{testBlock(t, 1), testBlock(t, 2, []byte("transfer")), testBlock(t, 3)}
+	tuples := make([][]byte, len(blocks))
+	for i, block := range blocks {
+		tuple, err := encodeDataRootTuple(uint64(block.Header.Height), block.Header.DataHash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tuples[i] = tuple
+	}
+	commitment, proofs := merkle.ProofsFromByteSlices(tuples)
+	result := &ctypes.ResultDataRootInclusionProof{Proof: *proofs[1]}
+
+	proofJSON, err := tmjson.Marshal(proofs[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	resultJSON, err := tmjson.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	responseJSON, err := json.Marshal(rpctypes.NewRPCSuccessResponse(rpctypes.JSONRPCIntID(1), result))
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := startFakeCore(t, blocks...)
+	for _, tc := range []struct {
+		name string
+		file []byte
+	}{
+		{"bare proof", proofJSON},
+		{"result", resultJSON},
+		{"JSON-RPC response", responseJSON},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "proof.json")
+			if err := os.WriteFile(path, tc.file, 0o600); err != nil {
+				t.Fatal(err)
+			}
+			proof, err := readDataRootInclusionProof(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if proof.Total != 3 || proof.Index != 1 {
+				t.Errorf("proof of tuple %d of %d, want 1 of 3", proof.Index, proof.Total)
+			}
+			out, err := runCLI(t, addr, "verify-data-commitment", "1", "4",
+				"--commitment", hex.EncodeToString(commitment), "--proof", path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out != "PASS 2\n" {
+				t.Errorf("printed %q", out)
+			}
+		})
+	}
+
+	path := filepath.Join(t.TempDir(), "proof.json")
+	if err := os.WriteFile(path, []byte(`{"proof":{"total":3,"index":1}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readDataRootInclusionProof(path); exitCode(err) != exitDecode {
+		t.Errorf("unquoted int64s: error %v exits with code %d, want %d", err, exitCode(err), exitDecode)
+	}
+}
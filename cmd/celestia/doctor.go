@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/adlerjohn/celestia-node-stateless/pkg/stateless"
+	"github.com/celestiaorg/celestia-app/v3/pkg/da"
+)
+
+// Outcomes of a doctor check.
+const (
+	checkPass = "PASS"
+	checkFail = "FAIL"
+	// checkWarn is a failed check that doesn't stop core being usable.
+	checkWarn = "WARN"
+	// checkSkip is a check that couldn't run because one before it failed.
+	checkSkip = "SKIP"
+)
+
+// doctorCheck is the outcome of one check the doctor command runs, with
+// what it found if it passed, or its error and a hint at the likely cause
+// if it failed.
+type doctorCheck struct {
+	Check  string `json:"check"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Error  string `json:"error,omitempty"`
+	Hint   string `json:"hint,omitempty"`
+	// err is the error of a failed check, kept for its exit code.
+	err error
+}
+
+type doctorReport []doctorCheck
+
+func (r doctorReport) String() string {
+	var b strings.Builder
+	for i, c := range r {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		switch {
+		case c.Error != "":
+			fmt.Fprintf(&b, "%s %s: %s", c.Status, c.Check, c.Error)
+		case c.Detail != "":
+			fmt.Fprintf(&b, "%s %s: %s", c.Status, c.Check, c.Detail)
+		default:
+			fmt.Fprintf(&b, "%s %s", c.Status, c.Check)
+		}
+		if c.Hint != "" {
+			fmt.Fprintf(&b, "\n     hint: %s", c.Hint)
+		}
+	}
+	return b.String()
+}
+
+// failure returns the error of the first check that failed, nil if none
+// did. Warnings aren't failures.
+func (r doctorReport) failure() error {
+	for _, c := range r {
+		if c.Status == checkFail {
+			return fmt.Errorf("%s: %w", c.Check, c.err)
+		}
+	}
+	return nil
+}
+
+// doctor diagnoses core and the blocks it serves, for setting up against
+// a new endpoint: it queries core's status, then fetches its tip block,
+// extends it and runs every check there is on it, going on past failures
+// where the checks after don't depend on them. The --chain-id, --codec,
+// --nmt-* and --app-version flags apply as in any other command.
+func (s *session) doctor(core *stateless.CoreAccessor) doctorReport {
+	var report doctorReport
+	pass := func(check, detail string) {
+		report = append(report, doctorCheck{Check: check, Status: checkPass, Detail: detail})
+	}
+	fail := func(check string, err error, hint string) {
+		report = append(report, doctorCheck{Check: check, Status: checkFail, Error: err.Error(), Hint: hint, err: err})
+	}
+	skip := func(checks ...string) doctorReport {
+		for _, check := range checks {
+			report = append(report, doctorCheck{Check: check, Status: checkSkip})
+		}
+		return report
+	}
+	// check records err under check, failed with hint, or passed with
+	// detail.
+	check := func(check string, err error, detail, hint string) {
+		if err != nil {
+			fail(check, err, hint)
+			return
+		}
+		pass(check, detail)
+	}
+
+	ctx := context.Background()
+	if s.fetchTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.fetchTimeout)
+		defer cancel()
+	}
+	status, err := core.Status(ctx)
+	if err != nil {
+		fail("core reachable", fmt.Errorf("querying core status: %w", err),
+			"check the --core address and that core's gRPC port is open; endpoints behind TLS or a gateway need --tls or --auth-token")
+		return skip("chain id", "synced", "fetch tip block", "extend", "validator set", "commit", "data root", "parity")
+	}
+	pass("core reachable", fmt.Sprintf("chain %s, tip %d", status.ChainID, status.LatestHeight))
+
+	if s.expectedChainID != "" && status.ChainID != s.expectedChainID {
+		fail("chain id", fmt.Errorf("core node is on chain %q, expected %q", status.ChainID, s.expectedChainID),
+			"the endpoint serves another network; point --core at a node of the chain, or fix --chain-id")
+		return skip("synced", "fetch tip block", "extend", "validator set", "commit", "data root", "parity")
+	}
+	pass("chain id", status.ChainID)
+	if status.CatchingUp {
+		report = append(report, doctorCheck{
+			Check:  "synced",
+			Status: checkWarn,
+			Error:  fmt.Sprintf("core node is catching up, at height %d", status.LatestHeight),
+			Hint:   "recent blocks aren't available yet; wait for the node to sync or use another",
+		})
+	} else {
+		pass("synced", "")
+	}
+
+	block, err := s.getSignedBlock(core, strconv.FormatInt(status.LatestHeight, 10))
+	if err != nil {
+		fail("fetch tip block", err, fetchHint(err))
+		return skip("extend", "validator set", "commit", "data root", "parity")
+	}
+	pass("fetch tip block", fmt.Sprintf("height %d, %d transactions", block.Header.Height, len(block.Data.Txs)))
+
+	eds, extendErr := s.extendBlock(block)
+	var size string
+	if extendErr == nil {
+		size = stateless.EDSSize(eds).String()
+	}
+	check("extend", extendErr, size,
+		"the block's data may be corrupt, or its app version unknown to this build; see --app-version")
+	// The block's own parts are checked against each other even if its
+	// data failed to extend
+	check("validator set", stateless.VerifyValidatorSet(block.ValidatorSet, block.Header), "",
+		"core may be serving the validator set of another height")
+	check("commit", stateless.VerifyCommit(block), "",
+		"core may be on a fork, or serving a commit not signed by the block's validators")
+	if extendErr != nil {
+		return skip("data root", "parity")
+	}
+	var dataRoot string
+	dah, err := da.NewDataAvailabilityHeader(eds)
+	if err == nil {
+		err = stateless.VerifyDAH(block.Header, &dah)
+		dataRoot = fmt.Sprintf("%X", dah.Hash())
+	}
+	check("data root", err, dataRoot,
+		"DAH mismatch: core may be serving corrupt data, or --nmt-* or --app-version change the square")
+	if err := verifyParity(eds, s.extendCodec); err != nil {
+		fail("parity", &classifiedError{exitVerification, err},
+			fmt.Sprintf("the %s codec disagrees with itself; try another --codec", s.extendCodec.Name()))
+	} else {
+		pass("parity", "")
+	}
+	return report
+}
+
+// fetchHint returns the likely cause of err, the failure to fetch a block
+// from core, by its class.
+func fetchHint(err error) string {
+	switch exitCode(err) {
+	case exitNetwork:
+		return "core may be overloaded or dropping the stream; try a larger --part-timeout or --max-recv-size"
+	case exitNotFound:
+		return "core may have pruned the block or not stored it yet; try again once it has synced"
+	default:
+		return "core may be serving corrupt data: the block it sent doesn't match its own commit"
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/adlerjohn/celestia-node-stateless/pkg/stateless"
+	"github.com/tendermint/tendermint/crypto/tmhash"
+)
+
+// errDryRun ends a command under --dry-run where it would first have
+// contacted core. The session treats it as success.
+var errDryRun = errors.New("dry run")
+
+// dryRunSource is the block source of --dry-run. It checks the height or
+// hash of each block a command asks for like core would, prints what would
+// be fetched from where, and stops the command there with errDryRun.
+type dryRunSource struct {
+	addrs []string
+}
+
+func (d *dryRunSource) GetSignedBlock(_ context.Context, h string) (*stateless.SignedBlock, error) {
+	if err := checkDryRunHeight(h); err != nil {
+		return nil, err
+	}
+	return nil, d.would("fetch block %s", h)
+}
+
+func (d *dryRunSource) GetSignedHeader(_ context.Context, h string) (*stateless.SignedBlock, error) {
+	if err := checkDryRunHeight(h); err != nil {
+		return nil, err
+	}
+	return nil, d.would("fetch the header of block %s", h)
+}
+
+func (d *dryRunSource) GetSignedBlockByHash(_ context.Context, h string) (*stateless.SignedBlock, error) {
+	hash, err := hex.DecodeString(h)
+	if err != nil {
+		return nil, usageError(fmt.Errorf("invalid block hash: %w", err))
+	}
+	if len(hash) != tmhash.Size {
+		return nil, usageError(fmt.Errorf("invalid block hash: got %d bytes, expected %d", len(hash), tmhash.Size))
+	}
+	return nil, d.would("fetch block %X", hash)
+}
+
+func (d *dryRunSource) LatestHeight(context.Context) (int64, error) {
+	return 0, d.would("query the chain tip")
+}
+
+func (d *dryRunSource) Close() error {
+	return nil
+}
+
+// checkDryRunHeight checks h is a height core would accept.
+func checkDryRunHeight(h string) error {
+	if h != "latest" {
+		if height, err := strconv.ParseInt(h, 10, 64); err != nil || height < 0 {
+			return usageError(fmt.Errorf("invalid height %q", h))
+		}
+	}
+	return nil
+}
+
+// would prints the request that would have been sent and returns
+// errDryRun.
+func (d *dryRunSource) would(format string, a ...any) error {
+	fmt.Printf("dry run: would %s from %s\n", fmt.Sprintf(format, a...), strings.Join(d.addrs, ","))
+	return errDryRun
+}
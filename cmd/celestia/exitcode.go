@@ -0,0 +1,165 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/adlerjohn/celestia-node-stateless/pkg/stateless"
+)
+
+// Exit codes, by class of failure.
+const (
+	exitFailure      = 1 // a failure of no class below
+	exitUsage        = 2 // invalid arguments or flags
+	exitNetwork      = 3 // core was unreachable or timed out
+	exitNotFound     = 4 // core doesn't have the requested block
+	exitDecode       = 5 // a file or block failed to decode, or a block failed to extend
+	exitVerification = 6 // a verification ran and failed
+)
+
+// exitTypes names the class of each exit code in JSON errors.
+var exitTypes = map[int]string{
+	exitFailure:      "failure",
+	exitUsage:        "usage",
+	exitNetwork:      "network",
+	exitNotFound:     "not_found",
+	exitDecode:       "decode",
+	exitVerification: "verification",
+}
+
+// exitCodesHelp documents the exit codes in the usage output.
+const exitCodesHelp = `Exit codes:
+  0  success
+  1  failure of no other class
+  2  invalid arguments or flags
+  3  core unreachable or timed out
+  4  block not found
+  5  file or block failed to decode, or block failed to extend
+  6  verification failed`
+
+// classifiedError is an error tagged with the exit code of its class.
+type classifiedError struct {
+	code int
+	err  error
+}
+
+func (e *classifiedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *classifiedError) Unwrap() error {
+	return e.err
+}
+
+// verificationFailed reports that the check err describes failed.
+func verificationFailed(err error) error {
+	return &classifiedError{exitVerification, fmt.Errorf("FAIL: %w", err)}
+}
+
+// usageError reports that the command was invoked wrongly.
+func usageError(err error) error {
+	return &classifiedError{exitUsage, err}
+}
+
+// heightError is an error tied to the block at height. Its message is
+// err's, the height is for JSON errors.
+type heightError struct {
+	height int64
+	err    error
+}
+
+func (e *heightError) Error() string {
+	return e.err.Error()
+}
+
+func (e *heightError) Unwrap() error {
+	return e.err
+}
+
+// atHeight ties err to the block at height.
+func atHeight(height int64, err error) error {
+	return &heightError{height, err}
+}
+
+// blockNotFound reports that the requested block doesn't exist.
+func blockNotFound(err error) error {
+	return &classifiedError{exitNotFound, err}
+}
+
+// decodeFailed reports that decoding or extending data failed with err.
+func decodeFailed(err error) error {
+	return &classifiedError{exitDecode, err}
+}
+
+// exitCode returns the exit code for the class of err. Errors that weren't
+// classified where they originated are classified by the stateless error
+// they wrap, or else by their gRPC status.
+func exitCode(err error) int {
+	var classified *classifiedError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &classified):
+		return classified.code
+	case errors.Is(err, stateless.ErrBlockNotFound):
+		return exitNotFound
+	case errors.Is(err, stateless.ErrDAHMismatch),
+		errors.Is(err, stateless.ErrCommitVerification),
+		errors.Is(err, stateless.ErrInvalidValidatorSet):
+		return exitVerification
+	case errors.Is(err, stateless.ErrNotPowerOfTwo),
+		errors.Is(err, stateless.ErrSquareTooLarge),
+		errors.Is(err, stateless.ErrInvalidBlockParts):
+		return exitDecode
+	case isTransient(err):
+		return exitNetwork
+	default:
+		return exitFailure
+	}
+}
+
+// jsonError is how printError renders an error under --json.
+type jsonError struct {
+	Error struct {
+		Type    string `json:"type"`
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Height  int64  `json:"height,omitempty"`
+	} `json:"error"`
+}
+
+// errorWriter returns where a failed command's error goes: stdout under
+// --json, where it is a JSON object consumed like a result, and stderr
+// otherwise, so that it doesn't mix with results piped elsewhere.
+func (s *session) errorWriter() io.Writer {
+	if s.jsonOutput {
+		return os.Stdout
+	}
+	return os.Stderr
+}
+
+// printError writes err, which exits with code, to w: as its message, or
+// under --json as a JSON object naming its class and exit code, and the
+// height of the block it concerns if known.
+func (s *session) printError(w io.Writer, err error, code int) {
+	if !s.jsonOutput {
+		fmt.Fprintln(w, err)
+		return
+	}
+	var out jsonError
+	out.Error.Type = exitTypes[code]
+	out.Error.Code = code
+	out.Error.Message = err.Error()
+	var tied *heightError
+	if errors.As(err, &tied) {
+		out.Error.Height = tied.height
+	}
+	bz, marshalErr := s.marshalOutput(&out)
+	if marshalErr != nil {
+		fmt.Fprintln(w, err)
+		return
+	}
+	fmt.Fprintln(w, string(bz))
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/adlerjohn/celestia-node-stateless/pkg/stateless"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// isTransient reports whether a failed fetch may succeed when retried:
+// core was unreachable, or the attempt ran out of time. The core accessor
+// says so with a stateless.RetriableError, sources without one by the
+// gRPC code or context error they fail with.
+func isTransient(err error) bool {
+	if stateless.IsRetriable(err) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	default:
+		return false
+	}
+}
+
+// getSignedBlock fetches the block at height h, giving each attempt
+// fetchTimeout and retrying transient failures up to fetchRetries times
+// with exponential backoff. h may count blocks below the chain tip, as
+// resolveTipOffset takes. If core has no block at h, the error says what
+// its chain tip is.
+func (s *session) getSignedBlock(coreAccessor blockSource, h string) (*stateless.SignedBlock, error) {
+	h, err := s.resolveTipOffset(coreAccessor, h)
+	if err != nil {
+		return nil, err
+	}
+	block, err := s.fetchWithRetries(h, func(ctx context.Context) (*stateless.SignedBlock, error) {
+		return coreAccessor.GetSignedBlock(ctx, h)
+	})
+	if errors.Is(err, stateless.ErrBlockNotFound) {
+		err = s.heightNotAvailable(coreAccessor, h, err)
+	}
+	if err != nil {
+		return nil, fetchedAtHeight(h, err)
+	}
+	return block, nil
+}
+
+// fetchedAtHeight ties err, the failure to fetch the block at height h, to
+// that height if h is a number.
+func fetchedAtHeight(h string, err error) error {
+	if height, parseErr := strconv.ParseInt(h, 10, 64); parseErr == nil {
+		return atHeight(height, err)
+	}
+	return err
+}
+
+// headerSource is a blockSource that can fetch the header, commit and
+// validator set of a block without its data.
+type headerSource interface {
+	GetSignedHeader(ctx context.Context, h string) (*stateless.SignedBlock, error)
+}
+
+// getSignedHeader fetches the block at height h like getSignedBlock, but
+// without its data: only its header, commit and validator set are
+// fetched if src can, and the data is dropped otherwise.
+func (s *session) getSignedHeader(src blockSource, h string) (*stateless.SignedBlock, error) {
+	h, err := s.resolveTipOffset(src, h)
+	if err != nil {
+		return nil, err
+	}
+	headers, ok := src.(headerSource)
+	if !ok {
+		block, err := s.getSignedBlock(src, h)
+		if err != nil {
+			return nil, err
+		}
+		headerOnly := *block
+		headerOnly.Data = nil
+		return &headerOnly, nil
+	}
+	block, err := s.fetchWithRetries(h, func(ctx context.Context) (*stateless.SignedBlock, error) {
+		return headers.GetSignedHeader(ctx, h)
+	})
+	if errors.Is(err, stateless.ErrBlockNotFound) {
+		err = s.heightNotAvailable(src, h, err)
+	}
+	if err != nil {
+		return nil, fetchedAtHeight(h, err)
+	}
+	return block, nil
+}
+
+// tipSource is a blockSource that can tell the height of the chain tip.
+type tipSource interface {
+	LatestHeight(ctx context.Context) (int64, error)
+}
+
+// upgradeSource is a blockSource that can tell the app version upgrade
+// the chain has scheduled.
+type upgradeSource interface {
+	ScheduledUpgrade(ctx context.Context) (*stateless.Upgrade, error)
+}
+
+// scheduledUpgrade returns the upgrade src reports scheduled, and whether
+// it could tell: sources other than core can't, and neither can core
+// endpoints that don't serve the app's queries.
+func (s *session) scheduledUpgrade(src blockSource) (*stateless.Upgrade, bool) {
+	upgrades, ok := src.(upgradeSource)
+	if !ok {
+		return nil, false
+	}
+	ctx, cancel := s.fetchContext()
+	defer cancel()
+	upgrade, err := upgrades.ScheduledUpgrade(ctx)
+	if err != nil {
+		slog.Debug("querying scheduled upgrade failed", "err", err)
+		return nil, false
+	}
+	return upgrade, true
+}
+
+// heightNotAvailable turns err, core's raw answer to a fetch of a height h
+// it doesn't have, into an error naming core's chain tip. err is returned
+// as is if the tip can't be queried.
+func (s *session) heightNotAvailable(src blockSource, h string, err error) error {
+	tips, ok := src.(tipSource)
+	if !ok {
+		return err
+	}
+	ctx, cancel := s.fetchContext()
+	defer cancel()
+	tip, tipErr := tips.LatestHeight(ctx)
+	if tipErr != nil {
+		slog.Debug("querying chain tip failed", "err", tipErr)
+		return err
+	}
+	slog.Debug("block not found", "height", h, "err", err)
+	if height, parseErr := strconv.ParseInt(h, 10, 64); parseErr == nil && height <= tip {
+		return blockNotFound(fmt.Errorf("height %d not available; chain tip is %d, so core may have pruned it", height, tip))
+	}
+	return blockNotFound(fmt.Errorf("height %s not available; chain tip is %d", h, tip))
+}
+
+// getSignedBlockByHash fetches the block with hex-encoded hash h, like
+// getSignedBlock.
+func (s *session) getSignedBlockByHash(coreAccessor blockSource, h string) (*stateless.SignedBlock, error) {
+	return s.fetchWithRetries(h, func(ctx context.Context) (*stateless.SignedBlock, error) {
+		return coreAccessor.GetSignedBlockByHash(ctx, h)
+	})
+}
+
+// fetchWithRetries calls fetch until it succeeds, fails with an error that
+// isn't transient, or has been retried fetchRetries times. A block from
+// another chain than expectedChainID fails verification.
+func (s *session) fetchWithRetries(
+	what string,
+	fetch func(ctx context.Context) (*stateless.SignedBlock, error),
+) (*stateless.SignedBlock, error) {
+	backoff := s.fetchRetryBackoff
+	for attempt := 0; ; attempt++ {
+		block, err := s.fetchOnce(fetch)
+		if err == nil {
+			if s.expectedChainID != "" && block.Header.ChainID != s.expectedChainID {
+				return nil, verificationFailed(fmt.Errorf("block %d is from chain %q, expected %q",
+					block.Header.Height, block.Header.ChainID, s.expectedChainID))
+			}
+			return block, nil
+		}
+		if attempt >= s.fetchRetries || !isTransient(err) {
+			return nil, err
+		}
+		slog.Info("fetch failed, retrying", "block", what, "attempt", attempt+1, "attempts", s.fetchRetries+1,
+			"backoff", backoff, "err", err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// fetchOnce calls fetch with a context that expires after fetchTimeout.
+func (s *session) fetchOnce(fetch func(ctx context.Context) (*stateless.SignedBlock, error)) (*stateless.SignedBlock, error) {
+	ctx, cancel := s.fetchContext()
+	defer cancel()
+	return fetch(ctx)
+}
+
+// fetchContext returns a context for a request to core that expires after
+// fetchTimeout, if set.
+func (s *session) fetchContext() (context.Context, context.CancelFunc) {
+	if s.fetchTimeout > 0 {
+		return context.WithTimeout(context.Background(), s.fetchTimeout)
+	}
+	return context.WithCancel(context.Background())
+}
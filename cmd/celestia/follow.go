@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/adlerjohn/celestia-node-stateless/pkg/stateless"
+)
+
+// follow passes the ExtendedHeader of every block from start on to emit as
+// core produces them, polling core's chain tip every pollInterval, until
+// ctx is done. A start of 0 begins at the current tip. Failing to query
+// the tip, and core answering that it doesn't have a height its tip
+// already covers or being briefly unreachable, are retried at the next
+// poll rather than ending the follow.
+func (s *session) follow(
+	ctx context.Context,
+	src blockSource,
+	start int64,
+	pollInterval time.Duration,
+	emit func(*stateless.ExtendedHeader) error,
+) error {
+	if pollInterval <= 0 {
+		return usageError(fmt.Errorf("--poll-interval must be positive, got %s", pollInterval))
+	}
+	tips, ok := src.(tipSource)
+	if !ok {
+		return usageError(errors.New("follow needs a core endpoint, not a block file"))
+	}
+
+	next := start
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		tip, tipErr := s.latestHeight(ctx, tips)
+		switch {
+		case ctx.Err() != nil:
+			return nil
+		case errors.Is(tipErr, errDryRun):
+			return tipErr
+		case tipErr != nil:
+			// Including core still syncing after a restart
+			slog.Warn("querying chain tip failed, retrying", "err", tipErr)
+		case next == 0:
+			next = tip
+		}
+		for tipErr == nil && next <= tip && ctx.Err() == nil {
+			eh, err := s.extendHeight(src, next)
+			if err != nil && retryable(err) {
+				slog.Warn("fetching block failed, retrying", "height", next, "tip", tip, "err", err)
+				break
+			}
+			if err != nil {
+				return atHeight(next, fmt.Errorf("height %d: %w", next, err))
+			}
+			if err := emit(eh); err != nil {
+				return atHeight(next, fmt.Errorf("height %d: %w", next, err))
+			}
+			next++
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// latestHeight queries the chain tip of tips with a fetch context that is
+// also cancelled with ctx.
+func (s *session) latestHeight(ctx context.Context, tips tipSource) (int64, error) {
+	fetchCtx, cancel := s.fetchContext()
+	defer cancel()
+	stop := context.AfterFunc(ctx, cancel)
+	defer stop()
+	return tips.LatestHeight(fetchCtx)
+}
+
+// retryable reports whether a follow should retry fetching a block that
+// failed with err at its next poll: core was unreachable, or doesn't have
+// the block yet.
+func retryable(err error) bool {
+	switch exitCode(err) {
+	case exitNetwork, exitNotFound:
+		return true
+	default:
+		return false
+	}
+}
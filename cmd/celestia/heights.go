@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// A height can be given relative to the chain tip: "latest" is the tip,
+// and -N or latest-N the block N below it.
+const latestArg = "latest"
+
+// tipOffsetArg matches a -N height, which cobra would take for shorthand
+// flags.
+var tipOffsetArg = regexp.MustCompile(`^-[0-9]+$`)
+
+// earliestSource is a blockSource that can tell the oldest height it
+// still has.
+type earliestSource interface {
+	EarliestHeight(ctx context.Context) (int64, error)
+}
+
+// tipOffset returns how many blocks below the chain tip h is, and whether
+// h is relative to the tip at all.
+func tipOffset(h string) (int64, bool) {
+	if h == latestArg {
+		return 0, true
+	}
+	n := strings.TrimPrefix(h, latestArg)
+	if !tipOffsetArg.MatchString(n) {
+		return 0, false
+	}
+	offset, err := strconv.ParseInt(n[1:], 10, 64)
+	return offset, err == nil
+}
+
+// resolveHeights returns the absolute heights of hs, which are given in
+// decimal or relative to the chain tip of src. The tip is queried at most
+// once, so all relative heights count from the same tip, and a relative
+// height below the oldest block src has fails with blockNotFound.
+func (s *session) resolveHeights(src blockSource, hs ...string) ([]int64, error) {
+	heights := make([]int64, len(hs))
+	var (
+		tip, earliest int64
+		queried       bool
+	)
+	for i, h := range hs {
+		offset, relative := tipOffset(h)
+		if !relative {
+			height, err := strconv.ParseInt(h, 10, 64)
+			if err != nil || height < 0 {
+				return nil, usageError(fmt.Errorf("invalid height %q", h))
+			}
+			heights[i] = height
+			continue
+		}
+		if !queried {
+			var err error
+			if tip, earliest, err = s.tipAndEarliest(src); err != nil {
+				return nil, err
+			}
+			queried = true
+		}
+		heights[i] = tip - offset
+		if heights[i] < earliest {
+			return nil, blockNotFound(fmt.Errorf("height %s would be %d, below the earliest available height %d; chain tip is %d",
+				h, heights[i], earliest, tip))
+		}
+	}
+	return heights, nil
+}
+
+// tipAndEarliest queries the chain tip of src and the oldest height it
+// has, which is 1 for sources that can't tell.
+func (s *session) tipAndEarliest(src blockSource) (tip, earliest int64, err error) {
+	tips, ok := src.(tipSource)
+	if !ok {
+		return 0, 0, usageError(errors.New("heights relative to the chain tip need --core to be a core node, not a block file"))
+	}
+	ctx, cancel := s.fetchContext()
+	defer cancel()
+	if tip, err = s.latestHeight(ctx, tips); err != nil {
+		return 0, 0, err
+	}
+	earliest = 1
+	if earliests, ok := src.(earliestSource); ok {
+		if earliest, err = earliests.EarliestHeight(ctx); err != nil {
+			return 0, 0, err
+		}
+	}
+	return tip, max(earliest, 1), nil
+}
+
+// resolveTipOffset resolves h to an absolute height if it is given as a
+// number of blocks below the chain tip, leaving "latest" and absolute
+// heights to the block source.
+func (s *session) resolveTipOffset(src blockSource, h string) (string, error) {
+	if _, relative := tipOffset(h); !relative || h == latestArg {
+		return h, nil
+	}
+	heights, err := s.resolveHeights(src, h)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(heights[0], 10), nil
+}
+
+// heightPlaceholders are the placeholders that stand for a height in the
+// usage lines of commands.
+var heightPlaceholders = []string{"height", "height|latest", "start", "end", "end-height", "height-a", "height-b"}
+
+// heightArgPositions returns the positions among cmd's arguments of those
+// that are heights, as declared by its usage line: the leading <name> or
+// [<name>] placeholders that are heightPlaceholders.
+func heightArgPositions(cmd *cobra.Command) []int {
+	var positions []int
+	for i, field := range strings.Fields(cmd.Use)[1:] {
+		name, ok := strings.CutPrefix(strings.Trim(field, "[]"), "<")
+		if name, ok = strings.CutSuffix(name, ">"); !ok {
+			break
+		}
+		if slices.Contains(heightPlaceholders, name) {
+			positions = append(positions, i)
+		}
+	}
+	return positions
+}
+
+// tipOffsetArgs rewrites the -N heights in the command line args of root
+// as latest-N, before cobra parses them as shorthand flags. Only arguments
+// in the height positions of the command are rewritten; a -N elsewhere,
+// the value of a flag, or after "--" is left as is.
+func tipOffsetArgs(root *cobra.Command, args []string) []string {
+	cmd, _, err := root.Find(args)
+	if err != nil {
+		cmd = root
+	}
+	heights := heightArgPositions(cmd)
+	// The first arguments name the command, not counting root
+	names := len(strings.Fields(cmd.CommandPath())) - 1
+	out := slices.Clone(args)
+	position := -names
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		if strings.HasPrefix(arg, "-") && !tipOffsetArg.MatchString(arg) {
+			if takesValue(cmd, arg) {
+				i++
+			}
+			continue
+		}
+		if tipOffsetArg.MatchString(arg) && slices.Contains(heights, position) {
+			out[i] = latestArg + arg
+		}
+		position++
+	}
+	return out
+}
+
+// negativeArgError is the flag error function of the root commands. It
+// explains a -N that cobra took for a shorthand flag because it is not in
+// a height position, and leaves other flag errors as they are.
+func negativeArgError(_ *cobra.Command, err error) error {
+	if m := unknownNegativeFlag.FindStringSubmatch(err.Error()); m != nil {
+		return usageError(fmt.Errorf("invalid argument %s: negative, and only heights can count back from the chain tip", m[1]))
+	}
+	return err
+}
+
+// unknownNegativeFlag matches the error pflag returns for a -N argument.
+var unknownNegativeFlag = regexp.MustCompile(`^unknown shorthand flag: '[0-9]' in (-[0-9]+)$`)
+
+// takesValue reports whether arg is a flag of cmd whose value is the next
+// argument.
+func takesValue(cmd *cobra.Command, arg string) bool {
+	if !strings.HasPrefix(arg, "-") || strings.Contains(arg, "=") || tipOffsetArg.MatchString(arg) {
+		return false
+	}
+	name := strings.TrimLeft(arg, "-")
+	flag := cmd.Flag(name)
+	if !strings.HasPrefix(arg, "--") && len(name) == 1 {
+		if flag = cmd.Flags().ShorthandLookup(name); flag == nil {
+			flag = cmd.InheritedFlags().ShorthandLookup(name)
+		}
+	}
+	return flag != nil && flag.NoOptDefVal == ""
+}
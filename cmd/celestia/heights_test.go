@@ -0,0 +1,43 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestTipOffsetArgs(t *testing.T) {
+	root := newRootCmd(new(session))
+	for _, tc := range []struct {
+		name string
+		args string
+		want string
+	}{
+		{"height", "eds -5", "eds latest-5"},
+		{"global flag first", "--core x:9090 eds -5", "--core x:9090 eds latest-5"},
+		{"both ends of a range", "range -9 -1 --concurrency 2", "range latest-9 latest-1 --concurrency 2"},
+		{"optional height", "follow -3", "follow latest-3"},
+		{"subcommand height", "bench extend -2", "bench extend latest-2"},
+		{"second height", "import block.json -4", "import block.json latest-4"},
+		{"blob index", "blob-proof -1 ns -1", "blob-proof latest-1 ns -1"},
+		{"cell indices", "proof 5 -3 -4", "proof 5 -3 -4"},
+		{"tx index", "tx-proof -2 -7", "tx-proof latest-2 -7"},
+		{"flag value", "share -2 --row -1", "share latest-2 --row -1"},
+		{"after --", "eds -- -5", "eds -- -5"},
+		{"no heights", "coords -1", "coords -1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tipOffsetArgs(root, strings.Fields(tc.args))
+			if want := strings.Fields(tc.want); !slices.Equal(got, want) {
+				t.Errorf("rewrote %q as %q, want %q", tc.args, got, want)
+			}
+		})
+	}
+}
+
+// TestNegativeArg checks that a -N in no height position, which cobra
+// takes for a flag, fails with a usage error that says so.
+func TestNegativeArg(t *testing.T) {
+	_, err := runCLI(t, "localhost:9090", "--dry-run", "blob-proof", "100", "ns", "-1")
+	checkUsageError(t, err, "invalid argument -1: negative, and only heights can count back from the chain tip")
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	libshare "github.com/celestiaorg/go-square/v2/share"
+	"github.com/celestiaorg/rsmt2d"
+	tmbytes "github.com/tendermint/tendermint/libs/bytes"
+)
+
+// shareKind classifies a share of the original data square by the role it
+// plays in the square layout.
+type shareKind int
+
+const (
+	txShare shareKind = iota
+	pfbShare
+	blobShare
+	primaryReservedPaddingShare
+	namespacePaddingShare
+	tailPaddingShare
+	reservedShare
+	numShareKinds
+)
+
+var shareKindNames = [numShareKinds]string{
+	"tx",
+	"pfb",
+	"blob",
+	"primary reserved padding",
+	"namespace padding",
+	"tail padding",
+	"other reserved",
+}
+
+func (k shareKind) String() string {
+	return shareKindNames[k]
+}
+
+// isPadding reports whether the kind is one of the padding share types.
+func (k shareKind) isPadding() bool {
+	return k == primaryReservedPaddingShare || k == namespacePaddingShare || k == tailPaddingShare
+}
+
+// classifyShare returns the kind of the given share.
+func classifyShare(s *libshare.Share) shareKind {
+	ns := s.Namespace()
+	switch {
+	case ns.IsTailPadding():
+		return tailPaddingShare
+	case ns.IsPrimaryReservedPadding():
+		return primaryReservedPaddingShare
+	case s.IsPadding():
+		return namespacePaddingShare
+	case ns.IsTx():
+		return txShare
+	case ns.IsPayForBlob():
+		return pfbShare
+	case ns.IsReserved():
+		return reservedShare
+	default:
+		return blobShare
+	}
+}
+
+// originalShares returns the shares of the original data square of the
+// given EDS in row-major order.
+func originalShares(eds *rsmt2d.ExtendedDataSquare) ([]libshare.Share, error) {
+	return libshare.FromBytes(eds.FlattenedODS())
+}
+
+// utilization counts the shares of one or more original data squares by
+// kind.
+type utilization struct {
+	blocks int
+	total  int
+	counts [numShareKinds]int
+}
+
+// add accounts for the shares of one original data square.
+func (u *utilization) add(shares []libshare.Share) {
+	u.blocks++
+	u.total += len(shares)
+	for i := range shares {
+		u.counts[classifyShare(&shares[i])]++
+	}
+}
+
+// countsByKind keys the non-zero counts by share kind name.
+func (u *utilization) countsByKind() map[string]int {
+	counts := make(map[string]int)
+	for k, n := range u.counts {
+		if n > 0 {
+			counts[shareKind(k).String()] = n
+		}
+	}
+	return counts
+}
+
+func (u *utilization) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Blocks int            `json:"blocks"`
+		Shares int            `json:"shares"`
+		Counts map[string]int `json:"counts"`
+	}{u.blocks, u.total, u.countsByKind()})
+}
+
+func (u *utilization) String() string {
+	var padding int
+	for k, n := range u.counts {
+		if shareKind(k).isPadding() {
+			padding += n
+		}
+	}
+	percent := func(n int) float64 {
+		if u.total == 0 {
+			return 0
+		}
+		return 100 * float64(n) / float64(u.total)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "blocks: %d\n", u.blocks)
+	fmt.Fprintf(&b, "shares: %d\n", u.total)
+	for _, k := range []shareKind{txShare, pfbShare, blobShare} {
+		fmt.Fprintf(&b, "%s: %d (%.2f%%)\n", k, u.counts[k], percent(u.counts[k]))
+	}
+	fmt.Fprintf(&b, "padding: %d (%.2f%%)", padding, percent(padding))
+	if n := u.counts[reservedShare]; n > 0 {
+		fmt.Fprintf(&b, "\n%s: %d (%.2f%%)", reservedShare, n, percent(n))
+	}
+	return b.String()
+}
+
+// paddingReport locates the padding shares of an original data square.
+type paddingReport struct {
+	total int
+	// ranges holds, per padding kind, the end-exclusive share index ranges
+	// of consecutive padding shares of that kind.
+	ranges map[shareKind][]libshare.Range
+}
+
+// newPaddingReport scans shares, in row-major order, for padding.
+func newPaddingReport(shares []libshare.Share) *paddingReport {
+	r := &paddingReport{total: len(shares), ranges: make(map[shareKind][]libshare.Range)}
+	for i := range shares {
+		k := classifyShare(&shares[i])
+		if !k.isPadding() {
+			continue
+		}
+		ranges := r.ranges[k]
+		if n := len(ranges); n > 0 && ranges[n-1].End == i {
+			ranges[n-1].End++
+		} else {
+			r.ranges[k] = append(ranges, libshare.NewRange(i, i+1))
+		}
+	}
+	return r
+}
+
+func (r *paddingReport) MarshalJSON() ([]byte, error) {
+	ranges := make(map[string][]libshare.Range, len(r.ranges))
+	for k, rs := range r.ranges {
+		ranges[k.String()] = rs
+	}
+	return json.Marshal(struct {
+		Shares int                         `json:"shares"`
+		Ranges map[string][]libshare.Range `json:"ranges"`
+	}{r.total, ranges})
+}
+
+func (r *paddingReport) String() string {
+	var b strings.Builder
+	var padding int
+	fmt.Fprintf(&b, "shares: %d", r.total)
+	for _, k := range []shareKind{primaryReservedPaddingShare, namespacePaddingShare, tailPaddingShare} {
+		var count int
+		spans := make([]string, len(r.ranges[k]))
+		for i, rng := range r.ranges[k] {
+			count += rng.End - rng.Start
+			spans[i] = fmt.Sprintf("[%d, %d)", rng.Start, rng.End)
+		}
+		padding += count
+		fmt.Fprintf(&b, "\n%s: %d shares, %d bytes", k, count, count*libshare.ShareSize)
+		if len(spans) > 0 {
+			fmt.Fprintf(&b, " at %s", strings.Join(spans, " "))
+		}
+	}
+	fmt.Fprintf(&b, "\ntotal padding: %d shares, %d bytes", padding, padding*libshare.ShareSize)
+	return b.String()
+}
+
+// namespaceUsage is the number of shares a namespace occupies in an
+// original data square. Label names the role of a reserved namespace and
+// is empty for blob namespaces.
+type namespaceUsage struct {
+	Namespace tmbytes.HexBytes `json:"namespace"`
+	Label     string           `json:"label,omitempty"`
+	Shares    int              `json:"shares"`
+	// Padding counts the namespace padding shares among Shares.
+	Padding int `json:"padding"`
+}
+
+type namespaceUsages []namespaceUsage
+
+// newNamespaceUsages lists the namespaces of shares, sorted, with their
+// share counts. The primary reserved and tail padding namespaces, which
+// only fill out the square, are left out, so a square without data yields
+// an empty list.
+func newNamespaceUsages(shares []libshare.Share) namespaceUsages {
+	usages := namespaceUsages{}
+	index := make(map[string]int)
+	for i := range shares {
+		k := classifyShare(&shares[i])
+		if k == primaryReservedPaddingShare || k == tailPaddingShare {
+			continue
+		}
+		ns := shares[i].Namespace()
+		j, ok := index[string(ns.Bytes())]
+		if !ok {
+			j = len(usages)
+			index[string(ns.Bytes())] = j
+			usages = append(usages, namespaceUsage{Namespace: ns.Bytes()})
+		}
+		switch k {
+		case txShare, pfbShare, reservedShare:
+			usages[j].Label = k.String()
+		case namespacePaddingShare:
+			usages[j].Padding++
+		}
+		usages[j].Shares++
+	}
+	sort.Slice(usages, func(a, b int) bool {
+		return bytes.Compare(usages[a].Namespace, usages[b].Namespace) < 0
+	})
+	return usages
+}
+
+func (u namespaceUsages) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "namespaces: %d\n", len(u))
+	fmt.Fprintf(&b, "content: %s", newBlockContent(u))
+	for _, usage := range u {
+		fmt.Fprintf(&b, "\n%x: %d shares", []byte(usage.Namespace), usage.Shares)
+		if usage.Padding > 0 {
+			fmt.Fprintf(&b, " (%d padding)", usage.Padding)
+		}
+		if usage.Label != "" {
+			fmt.Fprintf(&b, " [%s]", usage.Label)
+		}
+	}
+	return b.String()
+}
+
+// Kinds of block content, telling the blocks with data users submitted
+// apart from the others.
+const (
+	// emptyContent is a block without transactions, whose square is the
+	// minimal one.
+	emptyContent = "empty"
+	// reservedOnlyContent is a block whose square isn't empty but holds
+	// only reserved namespaces, such as transactions without blobs:
+	// nothing a user submitted as a blob.
+	reservedOnlyContent = "reserved_only"
+	// userDataContent is a block holding blobs.
+	userDataContent = "user_data"
+)
+
+// blockContent classifies what the original data square of a block holds.
+type blockContent struct {
+	Kind string `json:"kind"`
+	// Reserved are the reserved namespaces in the square.
+	Reserved       namespaceUsages `json:"reserved_namespaces"`
+	BlobNamespaces int             `json:"blob_namespaces"`
+}
+
+// newBlockContent classifies a square by the namespaces it holds, as
+// newNamespaceUsages lists them.
+func newBlockContent(usages namespaceUsages) *blockContent {
+	c := &blockContent{Reserved: namespaceUsages{}}
+	for _, usage := range usages {
+		if usage.Label != "" {
+			c.Reserved = append(c.Reserved, usage)
+		} else {
+			c.BlobNamespaces++
+		}
+	}
+	switch {
+	case len(usages) == 0:
+		c.Kind = emptyContent
+	case c.BlobNamespaces == 0:
+		c.Kind = reservedOnlyContent
+	default:
+		c.Kind = userDataContent
+	}
+	return c
+}
+
+func (c *blockContent) String() string {
+	labels := make([]string, len(c.Reserved))
+	for i, usage := range c.Reserved {
+		labels[i] = usage.Label
+	}
+	switch c.Kind {
+	case emptyContent:
+		return "empty block"
+	case reservedOnlyContent:
+		return fmt.Sprintf("no user data, only reserved namespaces (%s)", strings.Join(labels, ", "))
+	default:
+		return fmt.Sprintf("user data in %d blob namespaces", c.BlobNamespaces)
+	}
+}
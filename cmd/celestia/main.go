@@ -1,297 +1,413 @@
 package main
 
 import (
-	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
-	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
 
-	"github.com/celestiaorg/celestia-app/v3/app"
-	"github.com/celestiaorg/celestia-app/v3/pkg/appconsts"
-	"github.com/celestiaorg/celestia-app/v3/pkg/da"
-	"github.com/celestiaorg/celestia-app/v3/pkg/wrapper"
-	"github.com/celestiaorg/celestia-node/share"
-	libsquare "github.com/celestiaorg/go-square/v2"
+	"github.com/adlerjohn/celestia-node-stateless/pkg/stateless"
 	libshare "github.com/celestiaorg/go-square/v2/share"
 	"github.com/celestiaorg/nmt"
 	"github.com/celestiaorg/rsmt2d"
-	"github.com/gogo/protobuf/proto"
-	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
-	coregrpc "github.com/tendermint/tendermint/rpc/grpc"
-	"github.com/tendermint/tendermint/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/spf13/cobra"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
-type SignedBlock struct {
-	Header       *types.Header       `json:"header"`
-	Commit       *types.Commit       `json:"commit"`
-	Data         *types.Data         `json:"data"`
-	ValidatorSet *types.ValidatorSet `json:"validator_set"`
-}
+// session is the state of one invocation of the CLI: the global flags, the
+// block source named by --core, and where results and timings go. The REPL
+// runs all of its commands in the same session.
+type session struct {
+	cores         []string
+	tmplText      string
+	outputFile    string
+	cacheDir      string
+	noCache       bool
+	codecName     string
+	codecMemory   string
+	useTLS        bool
+	caCert        string
+	tlsSkipVerify bool
+	authToken     string
+	partTimeout   time.Duration
+	sequential    bool
+	maxRecvSize   int
+	keepalive     time.Duration
+	keepaliveWait time.Duration
+	keepaliveIdle bool
+	nmtIgnoreMax  bool
+	nmtNSSize     int
+	logLevel      string
+	verbose       bool
+	timings       bool
+	metricsAddr   string
+	pprofAddr     string
+	dryRun        bool
 
-type CoreAccessor struct {
-	ctx    context.Context
-	client coregrpc.BlockAPIClient
-}
+	source   blockSource
+	out      *atomicFile
+	recorder *timingRecorder
+	// ran is set once the command line parsed and the command started, so
+	// errors before then are usage errors.
+	ran bool
+
+	// The settings below apply the global flags to every command of the
+	// session, once setup ran.
 
-// ExtendedHeader represents a wrapped "raw" header that includes
-// information necessary for Celestia Nodes to be notified of new
-// block headers and perform Data Availability Sampling.
-type ExtendedHeader struct {
-	types.Header `json:"header"`
-	Commit       *types.Commit              `json:"commit"`
-	ValidatorSet *types.ValidatorSet        `json:"validator_set"`
-	DAH          *da.DataAvailabilityHeader `json:"dah"`
+	// jsonOutput makes printResult emit results as indented JSON.
+	jsonOutput bool
+	// ndjsonOutput, set with jsonOutput, makes printResult and printError
+	// emit each result or error as JSON on a single line instead, so that
+	// the results of range and follow can be consumed as they are printed.
+	ndjsonOutput bool
+	// outputTemplate, when set, formats command results in place of the
+	// default rendering. It is evaluated against the command's result:
+	//
+	//	eds:   *stateless.ExtendedHeader, e.g. {{.Height}} {{.ChainID}} {{hex .DAH.Hash}}
+	//	block: *stateless.SignedBlock, e.g. {{.Header.Height}} {{len .Data.Txs}}
+	//	share: the cell's bytes, e.g. {{hex .}}, or with --row or --col the
+	//	       shareAxis, e.g. {{.Axis}} {{.Index}} {{.Half}} {{len .Shares}}
+	//	blob:  blobSummaries, e.g. {{range .}}{{.DataLen}} {{end}}
+	//	rows:  each row in turn, e.g. {{.Row}} {{len .Shares}}
+	//	verify-*: a checkResult, e.g. {{.Check}} {{.Height}} {{.OK}}
+	//
+	// Byte slices can be rendered with the `hex` function.
+	outputTemplate *template.Template
+	// resultWriter receives command results. It is stdout unless an
+	// --output-file is given.
+	resultWriter io.Writer
+
+	// fetchTimeout bounds each block fetch attempt from core when positive.
+	fetchTimeout time.Duration
+	// fetchRetries is how many times a fetch failing with a transient
+	// error is retried before giving up.
+	fetchRetries int
+	// fetchRetryBackoff is the wait before the first retry, doubled for
+	// every retry after it.
+	fetchRetryBackoff time.Duration
+	// expectedChainID, when set, is the chain every fetched block must
+	// belong to.
+	expectedChainID string
+
+	// appVersionOverride, when non-zero, is the app version blocks are
+	// extended and parsed under in place of the one in their header.
+	appVersionOverride uint64
+	// validateAll, set by --validate-all, makes commands run validateBlock
+	// on every block before building and printing its ExtendedHeader.
+	validateAll bool
+	// extendCodec is the codec blocks are extended and their parity
+	// checked with, chosen with --codec. Every codec yields the same
+	// square, so the choice shows only in speed, and a codec that
+	// disagreed would fail the data root check of any command that
+	// verifies one.
+	extendCodec rsmt2d.Codec
+	// nmtOptions, when set by the --nmt-* flags, override the NMT
+	// configuration blocks are extended with.
+	nmtOptions []nmt.Option
+	// extendConcurrency, when positive, bounds how many row and column
+	// trees of a square are hashed at once, set by --extend-concurrency.
+	extendConcurrency int
+	// extenders holds an Extender per app version, built from the
+	// settings above the first time a block of that version is extended
+	// and reused for every later one, as across a range.
+	extenders sync.Map
 }
 
-func NewCoreAccessor(ip string) (*CoreAccessor, error) {
-	opts := []grpc.DialOption{
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+func main() {
+	s := new(session)
+	root := newRootCmd(s)
+	root.SetArgs(tipOffsetArgs(root, os.Args[1:]))
+	err := root.Execute()
+	if err = s.finish(err); err != nil {
+		code := exitUsage
+		if s.ran {
+			code = exitCode(err)
+		}
+		s.printError(s.errorWriter(), err, code)
+		os.Exit(code)
 	}
-	conn, err := grpc.NewClient(ip, opts...)
-	if err != nil {
-		return nil, err
+	os.Exit(0)
+}
+
+// newRootCmd returns the celestia command, with the global flags bound to
+// s and every command as a subcommand.
+func newRootCmd(s *session) *cobra.Command {
+	root := &cobra.Command{
+		Use:   "celestia",
+		Short: "Fetch, extend and verify Celestia blocks from a core node without running a DA node",
+		Long: "Fetch, extend and verify Celestia blocks from a core node without running a DA node.\n\n" +
+			exitCodesHelp,
+		// Arguments and flags were valid by the time this runs, so errors
+		// from here on are not usage errors
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			cmd.SilenceUsage = true
+			s.ran = true
+			if err := s.setup(); err != nil {
+				return err
+			}
+			if cmd.Flags().Changed("codec-memory") {
+				return s.checkCodecMemory(s.codecMemory)
+			}
+			return nil
+		},
+		SilenceErrors: true,
 	}
-	ctx := context.WithoutCancel(context.Background())
 
-	client := coregrpc.NewBlockAPIClient(conn)
+	root.SetFlagErrorFunc(negativeArgError)
 
-	return &CoreAccessor{ctx, client}, nil
+	flags := root.PersistentFlags()
+	flags.StringSliceVar(&s.cores, "core", nil,
+		"core gRPC address to fetch blocks from, or a file holding a block saved with --json block; "+
+			"repeat or separate addresses with commas to fail over between them in order")
+	flags.StringVar(&s.cacheDir, "cache-dir", "",
+		"cache blocks fetched from core in this directory, by chain ID and height, and reuse them while core has the same block")
+	flags.BoolVar(&s.noCache, "no-cache", false, "fetch every block from core even if --cache-dir is set")
+	flags.StringVar(&s.tmplText, "output-template", "", "Go text/template used to format the command result")
+	flags.BoolVar(&s.jsonOutput, "json", false, "print the command result as JSON, with byte fields hex-encoded")
+	flags.BoolVar(&s.ndjsonOutput, "ndjson", false,
+		"print results and errors as JSON one per line, like --json, for streaming the output of range and follow")
+	flags.StringVar(&s.outputFile, "output-file", "", "write the command result to this file instead of stdout")
+	flags.StringVar(&s.codecName, "codec", rsmt2d.Leopard,
+		fmt.Sprintf("Reed-Solomon codec to extend and reconstruct blocks with, one of %s", strings.Join(stateless.CodecNames(), ", ")))
+	flags.StringVar(&s.codecMemory, "codec-memory", "",
+		"cap in bytes on the codec's working memory during extension; no codec supports it")
+	flags.MarkHidden("codec-memory")
+	flags.IntVar(&s.extendConcurrency, "extend-concurrency", 0,
+		"hash at most this many row and column trees of a square at once when computing its roots, 0 for all at once")
+	flags.BoolVar(&s.useTLS, "tls", false, "connect to core over TLS, verifying it against the system certificate pool")
+	flags.StringVar(&s.caCert, "ca-cert", "",
+		"PEM file of CA certificates to verify core's TLS certificate against; implies --tls")
+	flags.BoolVar(&s.tlsSkipVerify, "tls-insecure-skip-verify", false,
+		"connect over TLS without verifying core's certificate, for self-signed dev nodes; implies --tls")
+	flags.StringVar(&s.authToken, "auth-token", "",
+		"bearer token to send core on every call; prefer the "+authTokenEnv+" environment variable, which stays out of shell history")
+	flags.DurationVar(&s.fetchTimeout, "timeout", 0, "time limit for each block fetch from core, 0 for none")
+	flags.DurationVar(&s.partTimeout, "part-timeout", stateless.DefaultPartTimeout,
+		"time limit for receiving each part of a block streamed from core, 0 for none")
+	flags.BoolVar(&s.sequential, "sequential-reassembly", false,
+		"receive every part of a block before reassembling it, instead of reassembling parts as they arrive")
+	flags.IntVar(&s.maxRecvSize, "max-recv-msg-size", stateless.DefaultMaxRecvMsgSize,
+		"largest message in bytes accepted from core, raise it if fetching large blocks fails with ResourceExhausted")
+	flags.DurationVar(&s.keepalive, "keepalive-time", stateless.DefaultKeepaliveTime,
+		"ping core after this long without activity to keep the connection alive, 0 to disable")
+	flags.DurationVar(&s.keepaliveWait, "keepalive-timeout", stateless.DefaultKeepaliveTimeout,
+		"drop the connection to core if a keepalive ping isn't answered within this long")
+	flags.BoolVar(&s.keepaliveIdle, "keepalive-permit-without-stream", false,
+		"also ping core between fetches, for long-running commands; the node must allow it")
+	flags.IntVar(&s.fetchRetries, "retries", 0,
+		"times to retry a block fetch failing because core is unavailable or timed out")
+	flags.DurationVar(&s.fetchRetryBackoff, "retry-backoff", 500*time.Millisecond,
+		"wait before the first retry of a block fetch, doubled for every further retry")
+	flags.Uint64Var(&s.appVersionOverride, "app-version", 0,
+		"extend blocks under this app version instead of their header's, for testing square construction")
+	flags.BoolVar(&s.nmtIgnoreMax, "nmt-ignore-max-ns", true,
+		"build the NMTs of extended blocks ignoring the max namespace, as celestia-app does")
+	flags.IntVar(&s.nmtNSSize, "nmt-namespace-size", libshare.NamespaceSize,
+		"namespace size in bytes of the NMTs of extended blocks, read from the start of each share")
+	flags.BoolVar(&s.validateAll, "validate-all", false,
+		"before printing an extended header, check its block's commit signatures, validator set, block ID, "+
+			"data root and parity, and fail with every check that did not pass")
+	flags.StringVar(&s.expectedChainID, "chain-id", "", "fail if a fetched block belongs to a chain other than this one")
+	flags.StringVar(&s.logLevel, "log-level", "info",
+		"minimum level of diagnostics logged to stderr: debug, info, warn or error")
+	flags.BoolVar(&s.verbose, "verbose", false, "log at debug level, same as --log-level debug")
+	flags.BoolVar(&s.timings, "timings", false,
+		"print how long fetching, reassembling and extending blocks took to stderr")
+	flags.StringVar(&s.metricsAddr, "metrics-addr", "",
+		"serve Prometheus metrics of the same stages at /metrics on this address")
+	flags.StringVar(&s.pprofAddr, "pprof-addr", "",
+		"serve net/http/pprof profiles at /debug/pprof/ on this address while the command runs")
+	flags.BoolVar(&s.dryRun, "dry-run", false,
+		"check the arguments and print the first request to core instead of connecting to it")
+	root.MarkFlagsMutuallyExclusive("json", "output-template")
+	root.MarkFlagsMutuallyExclusive("ndjson", "output-template")
+
+	root.AddCommand(s.commands()...)
+	return root
 }
 
-func (c CoreAccessor) getSignedBlock(h string) (*SignedBlock, error) {
-	// Third argument is block height
-	height, err := strconv.Atoi(h)
-	if err != nil {
-		return nil, err
+// setup applies the global flags before a command runs: it configures
+// logging and output, opens the --core block source if one was given, and
+// creates the output file.
+func (s *session) setup() error {
+	if s.verbose {
+		s.logLevel = "debug"
+	}
+	if err := setupLogging(s.logLevel); err != nil {
+		return err
 	}
 
-	stream, err := c.client.BlockByHeight(c.ctx, &coregrpc.BlockByHeightRequest{Height: int64(height)})
-	if err != nil {
-		return nil, err
+	if s.ndjsonOutput {
+		s.jsonOutput = true
 	}
-	block, err := receiveBlockByHeight(stream)
-	if err != nil {
-		return nil, err
+	if s.tmplText != "" {
+		tmpl, err := parseOutputTemplate(s.tmplText)
+		if err != nil {
+			return err
+		}
+		s.outputTemplate = tmpl
+	}
+	if err := s.setCodec(s.codecName); err != nil {
+		return err
 	}
-	return block, nil
-}
 
-func receiveBlockByHeight(streamer coregrpc.BlockAPI_BlockByHeightClient) (
-	*SignedBlock,
-	error,
-) {
-	parts := make([]*tmproto.Part, 0)
+	if err := s.setNMTOptions(s.nmtIgnoreMax, s.nmtNSSize); err != nil {
+		return err
+	}
+	if s.extendConcurrency < 0 {
+		return usageError(fmt.Errorf("invalid --extend-concurrency %d: must not be negative", s.extendConcurrency))
+	}
 
-	// receive the first part to get the block meta, commit, and validator set
-	firstPart, err := streamer.Recv()
-	if err != nil {
-		return nil, err
+	var observers []stateless.StageObserver
+	if s.timings {
+		s.recorder = &timingRecorder{}
+		observers = append(observers, s.recorder.observe)
 	}
-	commit, err := types.CommitFromProto(firstPart.Commit)
-	if err != nil {
-		return nil, err
+	if s.metricsAddr != "" {
+		reg := prometheus.NewRegistry()
+		observers = append(observers, newStageMetrics(reg).observe)
+		if err := serveMetrics(s.metricsAddr, reg); err != nil {
+			return err
+		}
 	}
-	validatorSet, err := types.ValidatorSetFromProto(firstPart.ValidatorSet)
-	if err != nil {
-		return nil, err
+	if s.pprofAddr != "" {
+		if err := servePprof(s.pprofAddr); err != nil {
+			return err
+		}
+	}
+	if len(observers) > 0 {
+		stateless.SetStageObserver(func(stage stateless.Stage, d time.Duration, squareSize int) {
+			for _, observe := range observers {
+				observe(stage, d, squareSize)
+			}
+		})
 	}
-	parts = append(parts, firstPart.BlockPart)
 
-	// receive the rest of the block
-	isLast := firstPart.IsLast
-	for !isLast {
-		resp, err := streamer.Recv()
+	if len(s.cores) > 0 {
+		source, err := s.openSource()
 		if err != nil {
-			return nil, err
+			return err
 		}
-		parts = append(parts, resp.BlockPart)
-		isLast = resp.IsLast
+		s.source = source
 	}
-	block, err := partsToBlock(parts)
-	if err != nil {
-		return nil, err
+
+	// The output file only replaces an existing one once the command is done
+	s.resultWriter = os.Stdout
+	if s.outputFile != "" && !s.dryRun {
+		out, err := createAtomicFile(s.outputFile)
+		if err != nil {
+			return err
+		}
+		s.out = out
+		s.resultWriter = out
 	}
-	return &SignedBlock{
-		Header:       &block.Header,
-		Commit:       commit,
-		Data:         &block.Data,
-		ValidatorSet: validatorSet,
-	}, nil
+	return nil
 }
 
-// partsToBlock takes a slice of parts and generates the corresponding block.
-// It empties the slice to optimize the memory usage.
-func partsToBlock(parts []*tmproto.Part) (*types.Block, error) {
-	partSet := types.NewPartSetFromHeader(types.PartSetHeader{
-		Total: uint32(len(parts)),
-	})
-	for _, part := range parts {
-		ok, err := partSet.AddPartWithoutProof(&types.Part{Index: part.Index, Bytes: part.Bytes})
-		if err != nil {
-			return nil, err
+// openSource opens the block source named by --core.
+func (s *session) openSource() (blockSource, error) {
+	return s.openSourceAt(s.cores)
+}
+
+// openSourceAt opens the block source at addrs, connecting like --core
+// would: a block file if it names an existing file, and core gRPC
+// endpoints otherwise. Under --dry-run nothing is opened.
+func (s *session) openSourceAt(addrs []string) (blockSource, error) {
+	if s.dryRun {
+		return &dryRunSource{addrs}, nil
+	}
+	if len(addrs) == 1 {
+		if fi, err := os.Stat(addrs[0]); err == nil && !fi.IsDir() {
+			slog.Debug("reading block from file", "path", addrs[0])
+			return openBlockFile(addrs[0])
 		}
-		if !ok {
+	}
+
+	if s.maxRecvSize <= 0 {
+		return nil, fmt.Errorf("--max-recv-msg-size must be positive, got %d", s.maxRecvSize)
+	}
+	dialOpts := []grpc.DialOption{stateless.WithMaxRecvMsgSize(s.maxRecvSize)}
+	if s.keepalive > 0 {
+		dialOpts = append(dialOpts, stateless.WithKeepalive(s.keepalive, s.keepaliveWait, s.keepaliveIdle))
+	}
+	useTLS := s.useTLS || s.caCert != "" || s.tlsSkipVerify
+	if useTLS {
+		tlsOpt, err := stateless.WithTLS(s.caCert, s.tlsSkipVerify)
+		if err != nil {
 			return nil, err
 		}
+		dialOpts = append(dialOpts, tlsOpt)
 	}
-	pbb := new(tmproto.Block)
-	bz, err := io.ReadAll(partSet.GetReader())
-	if err != nil {
-		return nil, err
+	token := s.authToken
+	if token == "" {
+		token = os.Getenv(authTokenEnv)
 	}
-	err = proto.Unmarshal(bz, pbb)
-	if err != nil {
-		return nil, err
+	if token != "" {
+		dialOpts = append(dialOpts, withAuthToken(token)...)
 	}
-	block, err := types.BlockFromProto(pbb)
+	slog.Debug("dialing core", "addresses", addrs, "tls", useTLS, "auth_token", token != "")
+	core, err := stateless.NewCoreAccessor(strings.Join(addrs, ","), dialOpts...)
 	if err != nil {
 		return nil, err
 	}
-	return block, nil
-}
-
-// extendBlock extends the given block data, returning the resulting
-// ExtendedDataSquare (EDS). If there are no transactions in the block,
-// nil is returned in place of the eds.
-func extendBlock(data *types.Data, appVersion uint64, options ...nmt.Option) (*rsmt2d.ExtendedDataSquare, error) {
-	if app.IsEmptyBlockRef(data, appVersion) {
-		return share.EmptyEDS(), nil
-	}
-
-	// Construct the data square from the block's transactions
-	square, err := libsquare.Construct(
-		data.Txs.ToSliceOfBytes(),
-		appconsts.SquareSizeUpperBound(appVersion),
-		appconsts.SubtreeRootThreshold(appVersion),
-	)
-	if err != nil {
-		return nil, err
+	core.SetPartTimeout(s.partTimeout)
+	core.SetSequentialReassembly(s.sequential)
+	if s.cacheDir != "" && !s.noCache {
+		return newBlockCache(core, s.cacheDir), nil
 	}
-	return extendShares(libshare.ToBytes(square), options...)
+	return core, nil
 }
 
-func extendShares(s [][]byte, options ...nmt.Option) (*rsmt2d.ExtendedDataSquare, error) {
-	// Check that the length of the square is a power of 2.
-	if !libsquare.IsPowerOfTwo(len(s)) {
-		return nil, fmt.Errorf("number of shares is not a power of 2: got %d", len(s))
+// finish releases what setup acquired once the command returned err: it
+// closes the block source, commits the output file unless the command
+// failed, and prints the timings. It returns the first error, a command
+// stopped by --dry-run having succeeded.
+func (s *session) finish(err error) error {
+	if errors.Is(err, errDryRun) {
+		err = nil
 	}
-	// here we construct a tree
-	// Note: uses the nmt wrapper to construct the tree.
-	squareSize := libsquare.Size(len(s))
-	return rsmt2d.ComputeExtendedDataSquare(s,
-		appconsts.DefaultCodec(),
-		wrapper.NewConstructor(uint64(squareSize),
-			options...))
-}
-
-// makeExtendedHeader assembles new ExtendedHeader.
-func makeExtendedHeader(
-	h *types.Header,
-	comm *types.Commit,
-	vals *types.ValidatorSet,
-	eds *rsmt2d.ExtendedDataSquare,
-) (*ExtendedHeader, error) {
-	var (
-		dah da.DataAvailabilityHeader
-		err error
-	)
-	switch eds {
-	case nil:
-		dah = da.MinDataAvailabilityHeader()
-	default:
-		dah, err = da.NewDataAvailabilityHeader(eds)
+	if s.source != nil {
+		if closeErr := s.source.Close(); err == nil && closeErr != nil {
+			err = fmt.Errorf("closing core connection: %w", closeErr)
+		}
+	}
+	if s.out != nil {
 		if err != nil {
-			return nil, err
+			s.out.abort()
+		} else if err = s.out.commit(); err == nil {
+			fmt.Printf("wrote %d bytes to %s\n", s.out.written, s.outputFile)
 		}
 	}
-
-	eh := &ExtendedHeader{
-		Header:       *h,
-		DAH:          &dah,
-		Commit:       comm,
-		ValidatorSet: vals,
+	if s.recorder != nil {
+		if werr := s.recorder.writeTable(os.Stderr); werr != nil && err == nil {
+			err = werr
+		}
 	}
-	return eh, nil
+	return err
 }
 
-func main() {
-	args := os.Args[1:]
-	if len(args) == 0 {
-		os.Exit(0)
-	}
-
-	// First argument is the core address
-	coreAccessor, err := NewCoreAccessor(args[0])
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+// blocks returns the block source for a command that fetches blocks.
+func (s *session) blocks() (blockSource, error) {
+	if s.source == nil {
+		return nil, usageError(errors.New("--core is required to fetch blocks"))
 	}
+	return s.source, nil
+}
 
-	// Second argument is command
-	switch args[1] {
-	case "eds":
-		fmt.Println("eds")
-		// Third argument is block height
-		block, err := coreAccessor.getSignedBlock(args[2])
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
-		eds, err := extendBlock(block.Data, block.Header.Version.App)
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
-		// create extended header
-		eh, err := makeExtendedHeader(block.Header, block.Commit, block.ValidatorSet, eds)
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
-		fmt.Println(eh)
-	case "share":
-		fmt.Println("share")
-		// Third argument is block height
-		block, err := coreAccessor.getSignedBlock(args[2])
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
-		eds, err := extendBlock(block.Data, block.Header.Version.App)
+// needsCore adapts the body of a command that fetches blocks from the
+// session's block source into a cobra RunE.
+func (s *session) needsCore(run func(src blockSource, args []string) error) func(*cobra.Command, []string) error {
+	return func(_ *cobra.Command, args []string) error {
+		src, err := s.blocks()
 		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+			return err
 		}
-		// Fourth and fifth arguments are indices
-		r, err := strconv.Atoi(args[3])
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
-		c, err := strconv.Atoi(args[4])
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
-		fmt.Println(eds.GetCell(uint(r), uint(c)))
-	case "blob":
-		fmt.Println("blob")
-		// TODO
-	case "block":
-		fmt.Println("block")
-		// Third argument is block height
-		block, err := coreAccessor.getSignedBlock(args[2])
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
-
-		fmt.Println(block)
-	default:
-		os.Exit(0)
+		return run(src, args)
 	}
-	os.Exit(0)
 }
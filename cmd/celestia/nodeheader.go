@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/adlerjohn/celestia-node-stateless/pkg/stateless"
+	"github.com/celestiaorg/celestia-node/header"
+)
+
+// nodeHeaderFormats are the encodings of celestia-node's ExtendedHeader
+// --node-format accepts: its JSON, as a node's API serves it, and the
+// protobuf encoding it stores and gossips.
+var nodeHeaderFormats = map[string]struct {
+	marshal   func(*header.ExtendedHeader) ([]byte, error)
+	unmarshal func([]byte) (*header.ExtendedHeader, error)
+}{
+	"json": {
+		marshal: func(eh *header.ExtendedHeader) ([]byte, error) { return json.Marshal(eh) },
+		unmarshal: func(bz []byte) (*header.ExtendedHeader, error) {
+			eh := new(header.ExtendedHeader)
+			return eh, json.Unmarshal(bz, eh)
+		},
+	},
+	"binary": {
+		marshal:   header.MarshalExtendedHeader,
+		unmarshal: header.UnmarshalExtendedHeader,
+	},
+}
+
+// encodeNodeHeader encodes eh as celestia-node's ExtendedHeader in format,
+// one of nodeHeaderFormats. The encoding is decoded back with celestia-node
+// and must encode to the same bytes again, and the header must pass the
+// validation a node runs on headers it receives, so that a node accepts
+// what is written.
+func encodeNodeHeader(eh *stateless.ExtendedHeader, format string) ([]byte, error) {
+	f := nodeHeaderFormats[format]
+	nodeHeader := eh.NodeHeader()
+	bz, err := f.marshal(nodeHeader)
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := f.unmarshal(bz)
+	if err != nil {
+		return nil, fmt.Errorf("celestia-node can't decode the header: %w", err)
+	}
+	reencoded, err := f.marshal(decoded)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(bz, reencoded) {
+		return nil, fmt.Errorf("header doesn't round-trip through celestia-node's %s encoding", format)
+	}
+	if err := decoded.Validate(); err != nil {
+		return nil, verificationFailed(fmt.Errorf("celestia-node rejects the header: %w", err))
+	}
+	return bz, nil
+}
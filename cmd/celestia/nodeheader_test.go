@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/adlerjohn/celestia-node-stateless/pkg/stateless"
+)
+
+func TestEncodeNodeHeader(t *testing.T) {
+	s := testSession(t)
+	// extendedHeader builds the ExtendedHeader of block.
+	extendedHeader := func(block *stateless.SignedBlock) *stateless.ExtendedHeader {
+		eds, err := s.extendBlock(block)
+		if err != nil {
+			t.Fatal(err)
+		}
+		eh, err := s.makeExtendedHeader(block, eds)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return eh
+	}
+	eh := extendedHeader(testBlock(t, 7, []byte("transfer"), []byte("delegate")))
+	// A header whose commit is for another block
+	spliced := *extendedHeader(testBlock(t, 7, []byte("another")))
+	spliced.Commit = eh.Commit
+
+	for format, f := range nodeHeaderFormats {
+		t.Run(format, func(t *testing.T) {
+			bz, err := encodeNodeHeader(eh, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded, err := f.unmarshal(bz)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(decoded.Hash(), eh.Hash()) {
+				t.Errorf("decoded header hash %X, want %X", decoded.Hash(), eh.Hash())
+			}
+			if !decoded.DAH.Equals(eh.DAH) {
+				t.Error("decoded DAH differs")
+			}
+			if !bytes.Equal(decoded.Commit.Hash(), eh.Commit.Hash()) {
+				t.Errorf("decoded commit hash %X, want %X", decoded.Commit.Hash(), eh.Commit.Hash())
+			}
+			if !bytes.Equal(decoded.ValidatorSet.Hash(), eh.ValidatorSet.Hash()) {
+				t.Errorf("decoded validator set hash %X, want %X", decoded.ValidatorSet.Hash(), eh.ValidatorSet.Hash())
+			}
+
+			_, err = encodeNodeHeader(&spliced, format)
+			if code := exitCode(err); err == nil || code != exitVerification {
+				t.Errorf("spliced commit: got error %v with exit code %d, want exit code %d", err, code, exitVerification)
+			}
+		})
+	}
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/celestiaorg/celestia-app/v3/pkg/appconsts"
+	"github.com/celestiaorg/celestia-app/v3/pkg/da"
+	"github.com/celestiaorg/celestia-app/v3/pkg/wrapper"
+	"github.com/celestiaorg/celestia-node/share"
+	libshare "github.com/celestiaorg/go-square/v2/share"
+	"github.com/celestiaorg/nmt"
+	"github.com/celestiaorg/rsmt2d"
+	"github.com/tendermint/tendermint/crypto/merkle"
+)
+
+// namespaceRow holds the shares of one row under a namespace together with
+// their NMT proof against the row root, or an absence proof if the row's
+// namespace range covers the namespace without containing it.
+type namespaceRow struct {
+	Row    int              `json:"row"`
+	Shares []libshare.Share `json:"shares"`
+	Proof  *nmt.Proof       `json:"proof"`
+}
+
+// proveNamespace builds the namespaceRow for ns in the given extended row.
+func proveNamespace(shares [][]byte, ns libshare.Namespace, row int) (namespaceRow, error) {
+	// The wrapper assigns the parity namespace outside the original data,
+	// the tree underneath is kept at hand for its namespace proofs.
+	tree := wrapper.NewErasuredNamespacedMerkleTree(uint64(len(shares)/2), uint(row))
+	nmtTree := nmt.New(
+		appconsts.NewBaseHashFunc(),
+		nmt.NamespaceIDSize(libshare.NamespaceSize),
+		nmt.IgnoreMaxNamespace(true),
+	)
+	tree.SetTree(nmtTree)
+	for _, sh := range shares {
+		if err := tree.Push(sh); err != nil {
+			return namespaceRow{}, err
+		}
+	}
+	proof, err := nmtTree.ProveNamespace(ns.Bytes())
+	if err != nil {
+		return namespaceRow{}, err
+	}
+	// An absence proof covers the leaf proving absence, not shares of ns
+	if proof.IsOfAbsence() {
+		return namespaceRow{Row: row, Proof: &proof}, nil
+	}
+	found, err := libshare.FromBytes(shares[proof.Start():proof.End()])
+	if err != nil {
+		return namespaceRow{}, err
+	}
+	return namespaceRow{Row: row, Shares: found, Proof: &proof}, nil
+}
+
+// verify checks the row's shares and proof against rowRoot.
+func (r *namespaceRow) verify(rowRoot []byte, ns libshare.Namespace) error {
+	if r.Proof == nil || r.Proof.IsEmptyProof() {
+		return fmt.Errorf("row %d: missing proof", r.Row)
+	}
+	if r.Proof.IsOfAbsence() != (len(r.Shares) == 0) {
+		return fmt.Errorf("row %d: %d shares with absence proof %t", r.Row, len(r.Shares), r.Proof.IsOfAbsence())
+	}
+	leaves := make([][]byte, len(r.Shares))
+	for i, sh := range r.Shares {
+		leaves[i] = append(sh.Namespace().Bytes(), sh.ToBytes()...)
+	}
+	if !r.Proof.VerifyNamespace(share.NewSHA256Hasher(), ns.Bytes(), leaves, rowRoot) {
+		return fmt.Errorf("row %d: namespace proof does not verify against row root %x", r.Row, rowRoot)
+	}
+	return nil
+}
+
+// namespaceProofs are the namespace proofs of every row of a square whose
+// namespace range covers a namespace, in row order. Rows that cover the
+// namespace without containing it carry an absence proof.
+type namespaceProofs []namespaceRow
+
+// proveNamespaceRows builds the namespaceRow for ns of every row of eds
+// whose root in dah covers ns.
+func proveNamespaceRows(eds *rsmt2d.ExtendedDataSquare, dah *da.DataAvailabilityHeader, ns libshare.Namespace) (namespaceProofs, error) {
+	rows, err := share.RowsWithNamespace(dah, ns)
+	if err != nil {
+		return nil, err
+	}
+	proofs := make(namespaceProofs, len(rows))
+	for i, row := range rows {
+		proofs[i], err = proveNamespace(eds.Row(uint(row)), ns, row)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", row, err)
+		}
+	}
+	return proofs, nil
+}
+
+func (p namespaceProofs) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "rows: %d", len(p))
+	for _, row := range p {
+		kind := "presence"
+		if row.Proof.IsOfAbsence() {
+			kind = "absence"
+		}
+		fmt.Fprintf(&b, "\nrow %d: %d shares, %s proof start %d end %d siblings %d",
+			row.Row, len(row.Shares), kind, row.Proof.Start(), row.Proof.End(), len(row.Proof.Nodes()))
+		for i, node := range row.Proof.Nodes() {
+			fmt.Fprintf(&b, "\n  %d: %x", i, node)
+		}
+	}
+	return b.String()
+}
+
+// verify checks that p holds exactly the rows whose root in rowRoots
+// covers ns, so that no share of ns was omitted, and that each row's
+// namespace proof verifies against its root.
+func (p namespaceProofs) verify(rowRoots [][]byte, ns libshare.Namespace) error {
+	rows, err := share.RowsWithNamespace(&share.AxisRoots{RowRoots: rowRoots}, ns)
+	if err != nil {
+		return err
+	}
+	if len(p) != len(rows) {
+		return fmt.Errorf("got %d rows, %d rows cover namespace %x", len(p), len(rows), ns.Bytes())
+	}
+	for i, row := range rows {
+		if p[i].Row != row {
+			return fmt.Errorf("row %d is %d, expected %d", i, p[i].Row, row)
+		}
+		if err := p[i].verify(rowRoots[row], ns); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rowNamespaceData is the shares of a namespace in one row with their
+// proof, encoded like celestia-node's shwap.RowNamespaceData.
+type rowNamespaceData struct {
+	Shares []libshare.Share `json:"shares"`
+	Proof  *nmt.Proof       `json:"proof"`
+}
+
+// namespaceData is the namespace proofs of a square in the shape of
+// celestia-node's shwap.NamespaceData, in which clients of a node's share
+// module get them: the same rows, without their indices, which the row
+// roots imply.
+type namespaceData struct {
+	rows []int
+	data []rowNamespaceData
+}
+
+// newNamespaceData builds the namespaceData of ns in eds, verifying it
+// against dah.
+func newNamespaceData(eds *rsmt2d.ExtendedDataSquare, dah *da.DataAvailabilityHeader, ns libshare.Namespace) (*namespaceData, error) {
+	proofs, err := proveNamespaceRows(eds, dah, ns)
+	if err != nil {
+		return nil, err
+	}
+	if err := proofs.verify(dah.RowRoots, ns); err != nil {
+		return nil, fmt.Errorf("namespace data does not verify: %w", err)
+	}
+	nd := &namespaceData{rows: make([]int, len(proofs)), data: make([]rowNamespaceData, len(proofs))}
+	for i, row := range proofs {
+		nd.rows[i] = row.Row
+		nd.data[i] = rowNamespaceData{Shares: row.Shares, Proof: row.Proof}
+	}
+	return nd, nil
+}
+
+func (d *namespaceData) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.data)
+}
+
+func (d *namespaceData) String() string {
+	var b strings.Builder
+	shares := 0
+	for _, row := range d.data {
+		shares += len(row.Shares)
+	}
+	fmt.Fprintf(&b, "rows: %d, shares: %d", len(d.data), shares)
+	for i, row := range d.data {
+		if row.Proof.IsOfAbsence() {
+			fmt.Fprintf(&b, "\nrow %d: absence proof", d.rows[i])
+			continue
+		}
+		fmt.Fprintf(&b, "\nrow %d: shares %d to %d", d.rows[i], row.Proof.Start(), row.Proof.End())
+		for _, sh := range row.Shares {
+			fmt.Fprintf(&b, "\n  %x", sh.ToBytes())
+		}
+	}
+	return b.String()
+}
+
+// absenceProof proves that a block has no shares of a namespace: the rows
+// whose namespace range covers it carry an absence proof, and every other
+// row's root excludes it by its range alone, as when the namespace sorts
+// before or after all shares of the row.
+type absenceProof struct {
+	Height    int64              `json:"height"`
+	Namespace libshare.Namespace `json:"namespace"`
+	Rows      namespaceProofs    `json:"rows"`
+	// Excluded is the number of rows whose namespace range excludes it.
+	Excluded int `json:"excluded_rows"`
+}
+
+// newAbsenceProof proves ns absent from the block at height with extended
+// square eds and DAH dah, verifying the proofs against the row roots. It
+// fails if the block has shares of ns.
+func newAbsenceProof(height int64, eds *rsmt2d.ExtendedDataSquare, dah *da.DataAvailabilityHeader, ns libshare.Namespace) (*absenceProof, error) {
+	rows, err := proveNamespaceRows(eds, dah, ns)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		if !row.Proof.IsOfAbsence() {
+			return nil, fmt.Errorf("namespace %x is present in block %d: row %d has %d of its shares",
+				ns.Bytes(), height, row.Row, len(row.Shares))
+		}
+	}
+	if err := rows.verify(dah.RowRoots, ns); err != nil {
+		return nil, err
+	}
+	return &absenceProof{Height: height, Namespace: ns, Rows: rows, Excluded: len(dah.RowRoots) - len(rows)}, nil
+}
+
+func (p *absenceProof) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "namespace %x is provably absent from block %d\n", p.Namespace.Bytes(), p.Height)
+	fmt.Fprintf(&b, "rows excluding it by their namespace range: %d\n", p.Excluded)
+	b.WriteString(p.Rows.String())
+	return b.String()
+}
+
+func readNamespaceProofs(path string) (namespaceProofs, error) {
+	bz, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p namespaceProofs
+	if err := json.Unmarshal(bz, &p); err != nil {
+		return nil, decodeFailed(fmt.Errorf("decoding namespace proofs %s: %w", path, err))
+	}
+	return p, nil
+}
+
+// namespaceProofBundle is a self-verifying export of a namespace at one
+// height. Every row root is carried with its Merkle proof into the data
+// hash, so a verifier holding only the data hash can authenticate the row
+// roots, determine which rows cover the namespace, and check each of their
+// namespace proofs.
+type namespaceProofBundle struct {
+	Height    int64              `json:"height"`
+	Namespace libshare.Namespace `json:"namespace"`
+	RowRoots  [][]byte           `json:"row_roots"`
+	RowProofs []*merkle.Proof    `json:"row_proofs"`
+	Rows      []namespaceRow     `json:"rows"`
+}
+
+// newNamespaceProofBundle collects the shares and proofs under ns for every
+// row of eds whose namespace range covers ns.
+func newNamespaceProofBundle(height int64, eds *rsmt2d.ExtendedDataSquare, ns libshare.Namespace) (*namespaceProofBundle, error) {
+	dah, err := da.NewDataAvailabilityHeader(eds)
+	if err != nil {
+		return nil, err
+	}
+	// The data hash is the Merkle root over the row roots followed by the
+	// column roots, so the first half of the proofs are the row proofs.
+	_, proofs := merkle.ProofsFromByteSlices(append(append([][]byte{}, dah.RowRoots...), dah.ColumnRoots...))
+
+	rows, err := proveNamespaceRows(eds, &dah, ns)
+	if err != nil {
+		return nil, err
+	}
+	return &namespaceProofBundle{
+		Height:    height,
+		Namespace: ns,
+		RowRoots:  dah.RowRoots,
+		RowProofs: proofs[:len(dah.RowRoots)],
+		Rows:      rows,
+	}, nil
+}
+
+func writeNamespaceProofBundle(path string, b *namespaceProofBundle) error {
+	bz, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, bz, 0o644)
+}
+
+func readNamespaceProofBundle(path string) (*namespaceProofBundle, error) {
+	bz, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	b := new(namespaceProofBundle)
+	if err := json.Unmarshal(bz, b); err != nil {
+		return nil, decodeFailed(fmt.Errorf("decoding namespace proofs %s: %w", path, err))
+	}
+	return b, nil
+}
+
+// verify checks the bundle against dataHash: every row root must be proven
+// into the data hash, the bundle must hold exactly the rows whose namespace
+// range covers the namespace, and each row's namespace proof must verify
+// against its row root.
+func (b *namespaceProofBundle) verify(dataHash []byte) error {
+	width := len(b.RowRoots)
+	if width == 0 {
+		return errors.New("bundle has no row roots")
+	}
+	if len(b.RowProofs) != width {
+		return fmt.Errorf("bundle has %d row roots but %d row proofs", width, len(b.RowProofs))
+	}
+	for i, proof := range b.RowProofs {
+		if proof == nil {
+			return fmt.Errorf("row %d: missing row proof", i)
+		}
+		// Row roots occupy the first half of the data hash leaves
+		if proof.Index != int64(i) || proof.Total != int64(2*width) {
+			return fmt.Errorf("row %d: proof is for leaf %d of %d, expected %d of %d",
+				i, proof.Index, proof.Total, i, 2*width)
+		}
+		if err := proof.Verify(dataHash, b.RowRoots[i]); err != nil {
+			return fmt.Errorf("row %d: row root not in data hash: %w", i, err)
+		}
+	}
+
+	return namespaceProofs(b.Rows).verify(b.RowRoots, b.Namespace)
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/adlerjohn/celestia-node-stateless/pkg/stateless"
+	"github.com/celestiaorg/celestia-app/v3/pkg/da"
+	libshare "github.com/celestiaorg/go-square/v2/share"
+	"github.com/celestiaorg/rsmt2d"
+	tmbytes "github.com/tendermint/tendermint/libs/bytes"
+	"github.com/tendermint/tendermint/types"
+)
+
+// parseOutputTemplate parses the text of an --output-template flag.
+func parseOutputTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("output").
+		Funcs(template.FuncMap{"hex": hex.EncodeToString}).
+		Option("missingkey=error").
+		Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid output template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// marshalOutput encodes v as JSON, indented unless ndjsonOutput is set.
+func (s *session) marshalOutput(v any) ([]byte, error) {
+	if s.ndjsonOutput {
+		return json.Marshal(v)
+	}
+	return json.MarshalIndent(v, "", "  ")
+}
+
+// dahJSON renders a DataAvailabilityHeader with hex-encoded roots.
+type dahJSON struct {
+	RowRoots    []tmbytes.HexBytes `json:"row_roots"`
+	ColumnRoots []tmbytes.HexBytes `json:"column_roots"`
+}
+
+func newDAHJSON(dah *da.DataAvailabilityHeader) *dahJSON {
+	if dah == nil {
+		return nil
+	}
+	toHex := func(roots [][]byte) []tmbytes.HexBytes {
+		out := make([]tmbytes.HexBytes, len(roots))
+		for i, root := range roots {
+			out[i] = root
+		}
+		return out
+	}
+	return &dahJSON{RowRoots: toHex(dah.RowRoots), ColumnRoots: toHex(dah.ColumnRoots)}
+}
+
+// jsonView returns the value to JSON-encode for a command result. Raw
+// bytes, which encoding/json would render as base64, are hex-encoded
+// instead, as are the roots of any DAH. Header and commit hashes are
+// already hex-encoded by their types.
+func jsonView(v any) any {
+	switch v := v.(type) {
+	case []byte:
+		return tmbytes.HexBytes(v)
+	case *da.DataAvailabilityHeader:
+		return newDAHJSON(v)
+	case *stateless.ExtendedHeader:
+		return struct {
+			Header       *types.Header       `json:"header"`
+			Commit       *types.Commit       `json:"commit"`
+			ValidatorSet *types.ValidatorSet `json:"validator_set"`
+			DAH          *dahJSON            `json:"dah"`
+		}{&v.Header, v.Commit, v.ValidatorSet, newDAHJSON(v.DAH)}
+	default:
+		return v
+	}
+}
+
+// shareEncodings are the text encodings the share command's --encoding
+// accepts besides raw.
+var shareEncodings = map[string]func([]byte) string{
+	"hex":    hex.EncodeToString,
+	"base64": base64.StdEncoding.EncodeToString,
+}
+
+// encodedShare renders a cell of an extended square as text, along with
+// the namespace its leading bytes hold if it is an original share.
+type encodedShare struct {
+	cell   []byte
+	parity bool
+	encode func([]byte) string
+}
+
+func (s *encodedShare) String() string {
+	ns := "none (parity share)"
+	if !s.parity {
+		ns = s.encode(s.cell[:libshare.NamespaceSize])
+	}
+	return fmt.Sprintf("namespace: %s\nshare: %s", ns, s.encode(s.cell))
+}
+
+// checkResult is the result of a verification command that passed; one
+// that fails returns a verificationFailed error instead.
+type checkResult struct {
+	Check string `json:"check"`
+	// Height is the height of the block checked, zero if the check is of
+	// no one block.
+	Height int64 `json:"height,omitempty"`
+	OK     bool  `json:"ok"`
+	// Detail, when set, says what was checked beyond the check's name.
+	Detail string `json:"detail,omitempty"`
+}
+
+func newCheckResult(check string, height int64) *checkResult {
+	return &checkResult{Check: check, Height: height, OK: true}
+}
+
+func (r *checkResult) String() string {
+	if r.Detail == "" {
+		return "PASS"
+	}
+	return "PASS " + r.Detail
+}
+
+// commitCheck is the result of verify: the check that passed and the
+// voting power that signed the commit.
+type commitCheck struct {
+	checkResult
+	Power *stateless.CommitPower `json:"power"`
+}
+
+func (c *commitCheck) String() string {
+	return c.Power.String() + "\n" + c.checkResult.String()
+}
+
+// printResult writes a command's result to resultWriter, as JSON if
+// jsonOutput is set or using outputTemplate if one was given.
+func (s *session) printResult(v any) error {
+	if s.jsonOutput {
+		bz, err := s.marshalOutput(jsonView(v))
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(s.resultWriter, string(bz))
+		return err
+	}
+	if s.outputTemplate == nil {
+		_, err := fmt.Fprintln(s.resultWriter, v)
+		return err
+	}
+	if err := s.outputTemplate.Execute(s.resultWriter, v); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(s.resultWriter)
+	return err
+}
+
+// shareAxis is a whole row or column of an extended square, or half of
+// one, as the share command prints it with --row or --col.
+type shareAxis struct {
+	// Axis is "row" or "col".
+	Axis  string `json:"axis"`
+	Index uint   `json:"index"`
+	// Half is the part of the axis printed: data, parity or full.
+	Half string `json:"half"`
+	// From and To are the first and last position along the axis of the
+	// shares printed.
+	From   uint               `json:"from"`
+	To     uint               `json:"to"`
+	Shares []tmbytes.HexBytes `json:"shares"`
+
+	cells  [][]byte
+	width  uint
+	encode func([]byte) string
+}
+
+// axisHalves are the values --half accepts.
+var axisHalves = []string{"data", "parity", "full"}
+
+// checkAxisHalf returns a usage error unless half is one of axisHalves.
+func checkAxisHalf(half string) error {
+	if !slices.Contains(axisHalves, half) {
+		return usageError(fmt.Errorf("invalid --half %q, expected one of %s", half, strings.Join(axisHalves, ", ")))
+	}
+	return nil
+}
+
+// newShareAxis returns the row of eds at index if axis is "row", or else
+// the column, rendering its shares with encode. half selects the original
+// shares, the parity shares, or all of them.
+func newShareAxis(eds *rsmt2d.ExtendedDataSquare, axis string, index uint, half string, encode func([]byte) string) (*shareAxis, error) {
+	a := &shareAxis{Axis: axis, Index: index, Half: half, width: eds.Width(), encode: encode}
+	if axis == "row" {
+		if err := checkCellIndex("row", index, a.width); err != nil {
+			return nil, err
+		}
+		a.cells = eds.Row(index)
+	} else {
+		if err := checkCellIndex("column", index, a.width); err != nil {
+			return nil, err
+		}
+		a.cells = eds.Col(index)
+	}
+	if err := checkAxisHalf(half); err != nil {
+		return nil, err
+	}
+	switch half {
+	case "data":
+		a.cells = a.cells[:a.width/2]
+	case "parity":
+		a.From = a.width / 2
+		a.cells = a.cells[a.From:]
+	}
+	a.To = a.From + uint(len(a.cells)) - 1
+	a.Shares = make([]tmbytes.HexBytes, len(a.cells))
+	for i, cell := range a.cells {
+		a.Shares[i] = cell
+	}
+	return a, nil
+}
+
+func (a *shareAxis) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %d, %s: positions %d to %d of %d", a.Axis, a.Index, a.Half, a.From, a.To, a.width)
+	half := a.width / 2
+	for i, cell := range a.cells {
+		r, c := a.Index, a.From+uint(i)
+		if a.Axis == "col" {
+			r, c = c, r
+		}
+		share := &encodedShare{cell: cell, parity: r >= half || c >= half, encode: a.encode}
+		fmt.Fprintf(&b, "\ncell (%d, %d)\n%s", r, c, share)
+	}
+	return b.String()
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/adlerjohn/celestia-node-stateless/pkg/stateless"
+)
+
+func TestPrintCheckResult(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		// set configures the session as the output flags would.
+		set    func(s *session)
+		detail string
+		want   string
+	}{
+		{"text", func(*session) {}, "", "PASS\n"},
+		{"text with detail", func(*session) {}, "12", "PASS 12\n"},
+		{"json", func(s *session) { s.jsonOutput = true }, "", "{\n  \"check\": \"verify-row\",\n  \"height\": 12,\n  \"ok\": true\n}\n"},
+		{"ndjson", func(s *session) { s.jsonOutput, s.ndjsonOutput = true, true }, "", `{"check":"verify-row","height":12,"ok":true}` + "\n"},
+		{"template", func(s *session) {
+			tmpl, err := parseOutputTemplate("{{.Check}} {{.Height}} {{.OK}}")
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.outputTemplate = tmpl
+		}, "", "verify-row 12 true\n"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := testSession(t)
+			out := new(bytes.Buffer)
+			s.resultWriter = out
+			tc.set(s)
+			result := newCheckResult("verify-row", 12)
+			result.Detail = tc.detail
+			if err := s.printResult(result); err != nil {
+				t.Fatal(err)
+			}
+			if got := out.String(); got != tc.want {
+				t.Errorf("printed %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPrintCommitCheck(t *testing.T) {
+	check := &commitCheck{*newCheckResult("verify", 12), &stateless.CommitPower{Signed: 7, Total: 10}}
+	for _, tc := range []struct {
+		name string
+		json bool
+		want string
+	}{
+		{"text", false, "signed voting power 7/10 (70.00%)\nPASS\n"},
+		{"json", true, `{"check":"verify","height":12,"ok":true,"power":{"signed":7,"total":10}}` + "\n"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := testSession(t)
+			out := new(bytes.Buffer)
+			s.resultWriter = out
+			s.jsonOutput, s.ndjsonOutput = tc.json, tc.json
+			if err := s.printResult(check); err != nil {
+				t.Fatal(err)
+			}
+			if got := out.String(); got != tc.want {
+				t.Errorf("printed %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestShareAxisHalf(t *testing.T) {
+	eds, _ := sampleSquare(t, 20000)
+	for _, tc := range []struct {
+		name, axis, half string
+		index, from, to  uint
+		// header is the first line String prints.
+		header string
+	}{
+		{"full row", "row", "full", 3, 0, 15, "row 3, full: positions 0 to 15 of 16"},
+		{"data row", "row", "data", 3, 0, 7, "row 3, data: positions 0 to 7 of 16"},
+		{"parity row", "row", "parity", 3, 8, 15, "row 3, parity: positions 8 to 15 of 16"},
+		{"data col", "col", "data", 12, 0, 7, "col 12, data: positions 0 to 7 of 16"},
+		{"parity col", "col", "parity", 12, 8, 15, "col 12, parity: positions 8 to 15 of 16"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			axis, err := newShareAxis(eds, tc.axis, tc.index, tc.half, shareEncodings["hex"])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if axis.From != tc.from || axis.To != tc.to {
+				t.Errorf("positions %d to %d, want %d to %d", axis.From, axis.To, tc.from, tc.to)
+			}
+			full := eds.Row(axis.Index)
+			if axis.Axis == "col" {
+				full = eds.Col(axis.Index)
+			}
+			if len(axis.Shares) != int(tc.to-tc.from+1) {
+				t.Fatalf("%d shares, want %d", len(axis.Shares), tc.to-tc.from+1)
+			}
+			for i, share := range axis.Shares {
+				if !bytes.Equal(share, full[tc.from+uint(i)]) {
+					t.Errorf("share %d is not the share at position %d", i, tc.from+uint(i))
+				}
+			}
+			if header, _, _ := strings.Cut(axis.String(), "\n"); header != tc.header {
+				t.Errorf("header %q, want %q", header, tc.header)
+			}
+		})
+	}
+	_, err := newShareAxis(eds, "row", 3, "extended", shareEncodings["hex"])
+	checkUsageError(t, err, `invalid --half "extended"`)
+	if _, err := newShareAxis(eds, "col", 16, "full", shareEncodings["hex"]); err == nil {
+		t.Error("column 16 of a 16-wide square: no error")
+	}
+}
+
+func TestErrorWriter(t *testing.T) {
+	s := testSession(t)
+	if w := s.errorWriter(); w != os.Stderr {
+		t.Errorf("plain errors go to %v, want stderr", w)
+	}
+	s.jsonOutput = true
+	if w := s.errorWriter(); w != os.Stdout {
+		t.Errorf("JSON errors go to %v, want stdout", w)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/celestiaorg/rsmt2d"
+)
+
+// verifyParity re-runs the Reed-Solomon encoding on the first half of
+// every row and column of eds and checks that the result matches the
+// second, parity half. It works on any square regardless of where it came
+// from, and returns an error naming the first mismatching cell.
+func verifyParity(eds *rsmt2d.ExtendedDataSquare, codec rsmt2d.Codec) error {
+	width := eds.Width()
+	half := width / 2
+	for i := uint(0); i < width; i++ {
+		if err := verifyAxisParity(codec, eds.Row(i), half); err != nil {
+			return fmt.Errorf("row %d: %w", i, err)
+		}
+		if err := verifyAxisParity(codec, eds.Col(i), half); err != nil {
+			return fmt.Errorf("column %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// verifyAxisParity encodes the first half shares of a row or column and
+// compares the output to the second half.
+func verifyAxisParity(codec rsmt2d.Codec, shares [][]byte, half uint) error {
+	parity, err := codec.Encode(shares[:half])
+	if err != nil {
+		return err
+	}
+	for j, want := range parity {
+		idx := half + uint(j)
+		if !bytes.Equal(shares[idx], want) {
+			return fmt.Errorf("parity mismatch at index %d", idx)
+		}
+	}
+	return nil
+}
+
+// verifyEDS extends the original quadrant of eds again, from scratch under
+// appVersion, and checks that the result matches eds cell for cell. Unlike
+// verifyParity it also catches a square extended at the wrong size. It
+// returns an error naming the first differing cell.
+func (s *session) verifyEDS(eds *rsmt2d.ExtendedDataSquare, appVersion uint64) error {
+	reextended, err := s.extenderFor(appVersion).ExtendShares(eds.FlattenedODS())
+	if err != nil {
+		return fmt.Errorf("re-extending the original quadrant: %w", err)
+	}
+	if got, want := reextended.Width(), eds.Width(); got != want {
+		return fmt.Errorf("re-extended square is %d wide, expected %d", got, want)
+	}
+	width := eds.Width()
+	for row := uint(0); row < width; row++ {
+		for col := uint(0); col < width; col++ {
+			if !bytes.Equal(eds.GetCell(row, col), reextended.GetCell(row, col)) {
+				return fmt.Errorf("cell (%d, %d) differs from the re-extended square", row, col)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,366 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/celestiaorg/celestia-app/v3/pkg/da"
+	"github.com/celestiaorg/celestia-app/v3/pkg/wrapper"
+	"github.com/celestiaorg/celestia-node/share"
+	libshare "github.com/celestiaorg/go-square/v2/share"
+	"github.com/celestiaorg/nmt"
+	"github.com/celestiaorg/nmt/namespace"
+	"github.com/celestiaorg/rsmt2d"
+	tmbytes "github.com/tendermint/tendermint/libs/bytes"
+)
+
+// readDAH reads a JSON-encoded DataAvailabilityHeader from path.
+func readDAH(path string) (*da.DataAvailabilityHeader, error) {
+	bz, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dah := new(da.DataAvailabilityHeader)
+	if err := json.Unmarshal(bz, dah); err != nil {
+		return nil, decodeFailed(fmt.Errorf("decoding DAH %s: %w", path, err))
+	}
+	if err := dah.ValidateBasic(); err != nil {
+		return nil, fmt.Errorf("invalid DAH %s: %w", path, err)
+	}
+	return dah, nil
+}
+
+// readNMTProof reads a JSON-encoded NMT proof from path.
+func readNMTProof(path string) (*nmt.Proof, error) {
+	bz, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	proof := new(nmt.Proof)
+	if err := json.Unmarshal(bz, proof); err != nil {
+		return nil, decodeFailed(fmt.Errorf("decoding proof %s: %w", path, err))
+	}
+	return proof, nil
+}
+
+// verifyShareAgainstDAH verifies that the given share under namespace ns is
+// included in the row root at index row of the DAH, using an NMT proof
+// over that row. Each failing check returns a distinct error.
+func verifyShareAgainstDAH(
+	dah *da.DataAvailabilityHeader,
+	row int,
+	ns libshare.Namespace,
+	sh []byte,
+	proof *nmt.Proof,
+) error {
+	return verifyShareWithHasher(share.NewSHA256Hasher(), dah, row, ns, sh, proof)
+}
+
+// verifyShareWithHasher is verifyShareAgainstDAH hashing with h, which it
+// resets before use, so that concurrent verifiers can each reuse their own.
+func verifyShareWithHasher(
+	h hash.Hash,
+	dah *da.DataAvailabilityHeader,
+	row int,
+	ns libshare.Namespace,
+	sh []byte,
+	proof *nmt.Proof,
+) error {
+	if row < 0 || row >= len(dah.RowRoots) {
+		return fmt.Errorf("row %d out of range for %d-row DAH", row, len(dah.RowRoots))
+	}
+	if len(sh) != libshare.ShareSize {
+		return fmt.Errorf("share is %d bytes, expected %d", len(sh), libshare.ShareSize)
+	}
+	// Parity shares are namespaced with the parity namespace by the tree,
+	// their own prefix is arbitrary erasure-coded data.
+	if !ns.IsParityShares() && !bytes.Equal(sh[:libshare.NamespaceSize], ns.Bytes()) {
+		return fmt.Errorf("share namespace %x does not match %x", sh[:libshare.NamespaceSize], ns.Bytes())
+	}
+	if proof.End()-proof.Start() != 1 {
+		return fmt.Errorf("proof covers %d shares, expected 1", proof.End()-proof.Start())
+	}
+	h.Reset()
+	ok := proof.VerifyInclusion(h, namespace.ID(ns.Bytes()), [][]byte{sh}, dah.RowRoots[row])
+	if !ok {
+		return fmt.Errorf("NMT proof does not verify against row root %x", dah.RowRoots[row])
+	}
+	return nil
+}
+
+// verifyProof verifies an NMT proof of shares under namespace ns against
+// root, the root of a row or column tree. Unless complete is set, shares
+// are the leaves of the proof's range, checked with VerifyInclusion. With
+// complete, shares must be every share of ns under root, none proving that
+// ns is absent, as VerifyNamespace checks.
+func verifyProof(root []byte, ns libshare.Namespace, shares [][]byte, proof *nmt.Proof, complete bool) error {
+	if want := 2*libshare.NamespaceSize + share.NewSHA256Hasher().Size(); len(root) != want {
+		return fmt.Errorf("root is %d bytes, expected %d", len(root), want)
+	}
+	for i, sh := range shares {
+		if len(sh) != libshare.ShareSize {
+			return fmt.Errorf("share %d is %d bytes, expected %d", i, len(sh), libshare.ShareSize)
+		}
+		// Parity shares are namespaced with the parity namespace by the
+		// tree, their own prefix is arbitrary erasure-coded data.
+		if !ns.IsParityShares() && !bytes.Equal(sh[:libshare.NamespaceSize], ns.Bytes()) {
+			return fmt.Errorf("share %d namespace %x does not match %x", i, sh[:libshare.NamespaceSize], ns.Bytes())
+		}
+	}
+
+	if complete {
+		leaves := make([][]byte, len(shares))
+		for i, sh := range shares {
+			leaves[i] = append(ns.Bytes(), sh...)
+		}
+		if !proof.VerifyNamespace(share.NewSHA256Hasher(), ns.Bytes(), leaves, root) {
+			return fmt.Errorf("namespace proof of %d shares does not verify against root %x", len(shares), root)
+		}
+		return nil
+	}
+	if n := proof.End() - proof.Start(); len(shares) != n {
+		return fmt.Errorf("proof covers %d shares, got %d", n, len(shares))
+	}
+	if !proof.VerifyInclusion(share.NewSHA256Hasher(), namespace.ID(ns.Bytes()), shares, root) {
+		return fmt.Errorf("NMT proof of shares %d to %d does not verify against root %x", proof.Start(), proof.End(), root)
+	}
+	return nil
+}
+
+// readShares reads a JSON array of base64-encoded shares from path.
+func readShares(path string) ([][]byte, error) {
+	bz, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var shares [][]byte
+	if err := json.Unmarshal(bz, &shares); err != nil {
+		return nil, decodeFailed(fmt.Errorf("decoding shares %s: %w", path, err))
+	}
+	return shares, nil
+}
+
+// verifyRow verifies that shares form the complete extended row at index
+// row of the DAH by rebuilding the row's NMT and comparing its root to the
+// DAH row root. A wrong share count and a root mismatch return distinct
+// errors.
+func verifyRow(dah *da.DataAvailabilityHeader, row int, shares [][]byte) error {
+	width := len(dah.RowRoots)
+	if row < 0 || row >= width {
+		return fmt.Errorf("row %d out of range for %d-row DAH", row, width)
+	}
+	if len(shares) != width {
+		return fmt.Errorf("got %d shares, expected square width %d", len(shares), width)
+	}
+	tree := wrapper.NewErasuredNamespacedMerkleTree(uint64(width/2), uint(row))
+	for i, sh := range shares {
+		if len(sh) != libshare.ShareSize {
+			return fmt.Errorf("share %d is %d bytes, expected %d", i, len(sh), libshare.ShareSize)
+		}
+		if err := tree.Push(sh); err != nil {
+			return fmt.Errorf("share %d: %w", i, err)
+		}
+	}
+	root, err := tree.Root()
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(root, dah.RowRoots[row]) {
+		return fmt.Errorf("row root %x does not match DAH row root %x", root, dah.RowRoots[row])
+	}
+	return nil
+}
+
+// proveShare builds an NMT inclusion proof for the share at (row, col) of
+// eds against the corresponding DAH row root.
+func proveShare(eds *rsmt2d.ExtendedDataSquare, row, col uint) (*nmt.Proof, error) {
+	return proveAxisShare(eds.Row(row), row, col)
+}
+
+// proveColShare builds an NMT inclusion proof for the share at (row, col)
+// of eds against the corresponding DAH column root.
+func proveColShare(eds *rsmt2d.ExtendedDataSquare, row, col uint) (*nmt.Proof, error) {
+	return proveAxisShare(eds.Col(col), col, row)
+}
+
+// proveAxisShare proves the share at index i of shares, the row or column
+// at axisIndex of an extended square. The erasured tree builds rows and
+// columns alike: a share is original only if both its index along the
+// axis and the axis index are in the first half.
+func proveAxisShare(shares [][]byte, axisIndex, i uint) (*nmt.Proof, error) {
+	tree := wrapper.NewErasuredNamespacedMerkleTree(uint64(len(shares)/2), axisIndex)
+	for _, sh := range shares {
+		if err := tree.Push(sh); err != nil {
+			return nil, err
+		}
+	}
+	proof, err := tree.ProveRange(int(i), int(i)+1)
+	if err != nil {
+		return nil, err
+	}
+	return &proof, nil
+}
+
+// shareProof is a share of the extended square together with its NMT
+// inclusion proof against the DAH row root. Namespace is the namespace the
+// row tree assigns the share, which is what the proof is verified under.
+type shareProof struct {
+	Row       uint             `json:"row"`
+	Col       uint             `json:"col"`
+	RowRoot   tmbytes.HexBytes `json:"row_root"`
+	Namespace tmbytes.HexBytes `json:"namespace"`
+	Share     tmbytes.HexBytes `json:"share"`
+	Proof     *nmt.Proof       `json:"proof"`
+}
+
+// parseCellIndex parses arg as a row or column index, as named by axis,
+// into a square of the given width.
+func parseCellIndex(axis, arg string, width uint) (uint, error) {
+	i, err := parseCellArg(axis, arg)
+	if err != nil {
+		return 0, err
+	}
+	return i, checkCellIndex(axis, i, width)
+}
+
+// parseCellArg parses arg as a row or column index, as named by axis,
+// before the width of the square is known: commands check their arguments
+// with it before fetching the block, and the index against the square with
+// checkCellIndex after.
+func parseCellArg(axis, arg string) (uint, error) {
+	i, err := strconv.Atoi(arg)
+	if err != nil {
+		return 0, usageError(fmt.Errorf("invalid %s %q: not an integer", axis, arg))
+	}
+	if i < 0 {
+		return 0, usageError(fmt.Errorf("invalid %s %d: negative", axis, i))
+	}
+	return uint(i), nil
+}
+
+// checkCellIndex checks that the row or column index i, as named by axis,
+// falls in a square of the given width.
+func checkCellIndex(axis string, i, width uint) error {
+	if i >= width {
+		return fmt.Errorf("%s %d out of range for %d-wide square", axis, i, width)
+	}
+	return nil
+}
+
+// newShareProof proves the share at (row, col) of eds.
+func newShareProof(eds *rsmt2d.ExtendedDataSquare, row, col uint) (*shareProof, error) {
+	width := eds.Width()
+	if row >= width || col >= width {
+		return nil, fmt.Errorf("cell (%d, %d) out of range for %d-wide square", row, col, width)
+	}
+	rowRoots, err := eds.RowRoots()
+	if err != nil {
+		return nil, err
+	}
+	sh := eds.GetCell(row, col)
+	ns, err := cellNamespace(width, row, col, sh)
+	if err != nil {
+		return nil, err
+	}
+	proof, err := proveShare(eds, row, col)
+	if err != nil {
+		return nil, err
+	}
+	return &shareProof{
+		Row:       row,
+		Col:       col,
+		RowRoot:   rowRoots[row],
+		Namespace: ns.Bytes(),
+		Share:     sh,
+		Proof:     proof,
+	}, nil
+}
+
+func (p *shareProof) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "row %d col %d\n", p.Row, p.Col)
+	fmt.Fprintf(&b, "row root: %x\n", []byte(p.RowRoot))
+	fmt.Fprintf(&b, "namespace: %x\n", []byte(p.Namespace))
+	fmt.Fprintf(&b, "share: %x\n", []byte(p.Share))
+	fmt.Fprintf(&b, "proof: start %d end %d siblings %d", p.Proof.Start(), p.Proof.End(), len(p.Proof.Nodes()))
+	for i, node := range p.Proof.Nodes() {
+		fmt.Fprintf(&b, "\n%d: %x", i, node)
+	}
+	return b.String()
+}
+
+// colShareProof is a share of the extended square together with its NMT
+// inclusion proof against the DAH column root, the counterpart of
+// shareProof for the other axis.
+type colShareProof struct {
+	Row       uint             `json:"row"`
+	Col       uint             `json:"col"`
+	ColRoot   tmbytes.HexBytes `json:"col_root"`
+	Namespace tmbytes.HexBytes `json:"namespace"`
+	Share     tmbytes.HexBytes `json:"share"`
+	Proof     *nmt.Proof       `json:"proof"`
+}
+
+// newColShareProof proves the share at (row, col) of eds against its
+// column root, checking the proof before returning it.
+func newColShareProof(eds *rsmt2d.ExtendedDataSquare, row, col uint) (*colShareProof, error) {
+	width := eds.Width()
+	if row >= width || col >= width {
+		return nil, fmt.Errorf("cell (%d, %d) out of range for %d-wide square", row, col, width)
+	}
+	colRoots, err := eds.ColRoots()
+	if err != nil {
+		return nil, err
+	}
+	sh := eds.GetCell(row, col)
+	ns, err := cellNamespace(width, row, col, sh)
+	if err != nil {
+		return nil, err
+	}
+	proof, err := proveColShare(eds, row, col)
+	if err != nil {
+		return nil, err
+	}
+	if !proof.VerifyInclusion(share.NewSHA256Hasher(), namespace.ID(ns.Bytes()), [][]byte{sh}, colRoots[col]) {
+		return nil, fmt.Errorf("NMT proof of cell (%d, %d) does not verify against column root %x", row, col, colRoots[col])
+	}
+	return &colShareProof{
+		Row:       row,
+		Col:       col,
+		ColRoot:   colRoots[col],
+		Namespace: ns.Bytes(),
+		Share:     sh,
+		Proof:     proof,
+	}, nil
+}
+
+func (p *colShareProof) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "row %d col %d\n", p.Row, p.Col)
+	fmt.Fprintf(&b, "col root: %x\n", []byte(p.ColRoot))
+	fmt.Fprintf(&b, "namespace: %x\n", []byte(p.Namespace))
+	fmt.Fprintf(&b, "share: %x\n", []byte(p.Share))
+	fmt.Fprintf(&b, "proof: start %d end %d siblings %d", p.Proof.Start(), p.Proof.End(), len(p.Proof.Nodes()))
+	for i, node := range p.Proof.Nodes() {
+		fmt.Fprintf(&b, "\n%d: %x", i, node)
+	}
+	return b.String()
+}
+
+// cellNamespace returns the namespace an NMT row tree assigns to the share
+// sh at (row, col) of a square of the given width: the share's own
+// namespace in the original data, the parity namespace elsewhere.
+func cellNamespace(width, row, col uint, sh []byte) (libshare.Namespace, error) {
+	if row >= width/2 || col >= width/2 {
+		return libshare.ParitySharesNamespace, nil
+	}
+	if len(sh) < libshare.NamespaceSize {
+		return libshare.Namespace{}, fmt.Errorf("share is %d bytes, too short for a namespace", len(sh))
+	}
+	return libshare.NewNamespaceFromBytes(sh[:libshare.NamespaceSize])
+}
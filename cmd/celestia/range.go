@@ -0,0 +1,394 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adlerjohn/celestia-node-stateless/pkg/stateless"
+	"github.com/celestiaorg/celestia-app/v3/app"
+	"github.com/celestiaorg/rsmt2d"
+)
+
+// extendHeight fetches the block at height and builds its ExtendedHeader.
+func (s *session) extendHeight(coreAccessor blockSource, height int64) (*stateless.ExtendedHeader, error) {
+	block, err := s.getSignedBlock(coreAccessor, strconv.FormatInt(height, 10))
+	if err != nil {
+		return nil, err
+	}
+	eds, err := s.extendBlock(block)
+	if err != nil {
+		return nil, err
+	}
+	return s.makeExtendedHeader(block, eds)
+}
+
+// rangeStages are the stages of a range pipeline, each run by its own pool
+// of workers on one height at a time: fetch fetches the block, extend
+// extends its data, and verify checks it and builds its ExtendedHeader.
+type rangeStages struct {
+	fetch  func(height int64) (*stateless.SignedBlock, error)
+	extend func(block *stateless.SignedBlock) (*rsmt2d.ExtendedDataSquare, error)
+	verify func(block *stateless.SignedBlock, eds *rsmt2d.ExtendedDataSquare) (*stateless.ExtendedHeader, error)
+}
+
+// rangeJob is one height moving through a range pipeline. done is closed
+// once it left the last stage, or failed at one.
+type rangeJob struct {
+	height int64
+	block  *stateless.SignedBlock
+	eds    *rsmt2d.ExtendedDataSquare
+	eh     *stateless.ExtendedHeader
+	err    error
+	done   chan struct{}
+}
+
+// pipelineRange runs every height from start to end inclusive through the
+// stages, each with concurrency workers, and passes the resulting
+// ExtendedHeaders, or the error the height failed with, to emit in height
+// order. An error from emit stops the range.
+//
+// The stages are connected by channels holding concurrency heights, and at
+// most 3*concurrency heights are in the pipeline at once: a height only
+// enters it once it has a slot in the ordered queue, and the height emit
+// waits on holds a slot until it is emitted. A slow stage therefore holds
+// back the fetching, and memory stays flat however long the range.
+func pipelineRange(
+	start, end int64,
+	concurrency int,
+	stages rangeStages,
+	emit func(height int64, eh *stateless.ExtendedHeader, err error) error,
+) error {
+	if start > end {
+		return usageError(fmt.Errorf("start height %d is above end height %d", start, end))
+	}
+	if concurrency < 1 {
+		return usageError(fmt.Errorf("concurrency must be at least 1, got %d", concurrency))
+	}
+	queue := make(chan *rangeJob, 3*concurrency-1)
+	fetchIn := make(chan *rangeJob, concurrency)
+	extendIn := make(chan *rangeJob, concurrency)
+	verifyIn := make(chan *rangeJob, concurrency)
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		defer close(queue)
+		defer close(fetchIn)
+		for height := start; height <= end; height++ {
+			job := &rangeJob{height: height, done: make(chan struct{})}
+			select {
+			case queue <- job:
+			case <-done:
+				return
+			}
+			select {
+			case fetchIn <- job:
+			case <-done:
+				return
+			}
+		}
+	}()
+	runRangeStage(concurrency, fetchIn, extendIn, func(job *rangeJob) (err error) {
+		job.block, err = stages.fetch(job.height)
+		return err
+	})
+	runRangeStage(concurrency, extendIn, verifyIn, func(job *rangeJob) (err error) {
+		job.eds, err = stages.extend(job.block)
+		return err
+	})
+	runRangeStage(concurrency, verifyIn, nil, func(job *rangeJob) (err error) {
+		job.eh, err = stages.verify(job.block, job.eds)
+		return err
+	})
+
+	for job := range queue {
+		<-job.done
+		if err := emit(job.height, job.eh, job.err); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runRangeStage starts workers goroutines applying work to the jobs from
+// in and passing them on to out, which is closed once in is drained. Jobs
+// that already failed are passed on untouched. The last stage, with a nil
+// out, marks every job done instead.
+func runRangeStage(workers int, in <-chan *rangeJob, out chan<- *rangeJob, work func(*rangeJob) error) {
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for job := range in {
+				if job.err == nil {
+					job.err = work(job)
+				}
+				if out == nil || job.err != nil {
+					// Only the header is kept once the job leaves the pipeline
+					job.block, job.eds = nil, nil
+					close(job.done)
+					continue
+				}
+				out <- job
+			}
+		}()
+	}
+	if out != nil {
+		go func() {
+			wg.Wait()
+			close(out)
+		}()
+	}
+}
+
+// linkChecker checks that the ExtendedHeaders of consecutive heights passed
+// to it link up, each naming the block before it as its last block, so a
+// re-org while fetching a range doesn't go unnoticed.
+type linkChecker struct {
+	prev *stateless.ExtendedHeader
+}
+
+// check checks that eh links to the header check was last called with, if
+// that was the header of the height before, and remembers eh.
+func (c *linkChecker) check(eh *stateless.ExtendedHeader) error {
+	prev := c.prev
+	c.prev = eh
+	if prev == nil || eh.Height != prev.Height+1 {
+		return nil
+	}
+	if hash := prev.Hash(); !bytes.Equal(eh.LastBlockID.Hash, hash) {
+		return verificationFailed(fmt.Errorf("suspected re-org: last block ID %X is not the hash %X of block %d",
+			eh.LastBlockID.Hash, hash, prev.Height))
+	}
+	return nil
+}
+
+// appVersionTracker flags the blocks passed to it in turn whose app version
+// differs from that of the block before, across an upgrade, since the new
+// version may extend blocks under different constants. App versions only
+// go up, so a block of a lower version than one before it is an error: the
+// node serving it is broken or its data corrupt.
+type appVersionTracker struct {
+	// prev is the header of the highest app version checked so far.
+	prev *stateless.ExtendedHeader
+	// overridden is whether --app-version overrides the blocks' versions.
+	overridden bool
+}
+
+// check logs a warning if eh's app version is above that of the headers
+// check was called with before, failing if it is below, and remembers eh
+// unless it failed.
+func (t *appVersionTracker) check(eh *stateless.ExtendedHeader) error {
+	prev := t.prev
+	if prev != nil && eh.Version.App < prev.Version.App {
+		slog.Error("app version downgraded",
+			"height", eh.Height, "previous_height", prev.Height, "from", prev.Version.App, "to", eh.Version.App)
+		return verificationFailed(fmt.Errorf("app version %d is below version %d of block %d",
+			eh.Version.App, prev.Version.App, prev.Height))
+	}
+	t.prev = eh
+	if prev == nil || eh.Version.App == prev.Version.App {
+		return nil
+	}
+	from, to := prev.Version.App, eh.Version.App
+	slog.Warn("app version changed",
+		"height", eh.Height, "previous_height", prev.Height, "from", from, "to", to,
+		"square_size_upper_bound", fmt.Sprintf("%d -> %d", stateless.SquareSizeUpperBound(from), stateless.SquareSizeUpperBound(to)),
+		"subtree_root_threshold", fmt.Sprintf("%d -> %d", stateless.SubtreeRootThreshold(from), stateless.SubtreeRootThreshold(to)),
+		"app_version_override", t.overridden)
+	return nil
+}
+
+// rangeSummary aggregates a range for --summary-only: how many blocks it
+// covered and how large they were, the heights that failed, and how long it
+// took.
+type rangeSummary struct {
+	Blocks   int `json:"blocks"`
+	Empty    int `json:"empty"`
+	NonEmpty int `json:"non_empty"`
+	// Shares and Bytes are the original shares of the blocks' squares and
+	// the bytes of their transactions.
+	Shares int     `json:"shares"`
+	Bytes  int     `json:"bytes"`
+	Failed []int64 `json:"failed"`
+	// Elapsed is the time the whole range took, and AverageExtend the
+	// time extending a block took on average.
+	Elapsed       time.Duration `json:"elapsed_ns"`
+	AverageExtend time.Duration `json:"average_extend_ns"`
+	// Intervals are the times between the blocks of the range.
+	Intervals intervalStats `json:"intervals"`
+
+	start time.Time
+	// extends is how many blocks were extended, in extendTotal.
+	extends     int
+	extendTotal time.Duration
+	// pending holds the blocks extended but not yet emitted, by height,
+	// since blocks are extended concurrently and out of order.
+	mu      sync.Mutex
+	pending map[int64]extendedBlock
+}
+
+// extendedBlock is what rangeSummary keeps of a block it extended.
+type extendedBlock struct {
+	empty         bool
+	shares, bytes int
+	latency       time.Duration
+}
+
+func newRangeSummary() *rangeSummary {
+	return &rangeSummary{Failed: []int64{}, start: time.Now(), pending: make(map[int64]extendedBlock)}
+}
+
+// extended records that block, of the given app version, was extended into
+// eds in latency. It is safe for concurrent use.
+func (r *rangeSummary) extended(block *stateless.SignedBlock, version uint64, eds *rsmt2d.ExtendedDataSquare, latency time.Duration) {
+	size := 0
+	for _, tx := range block.Data.Txs {
+		size += len(tx)
+	}
+	width := int(eds.Width() / 2)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pending[block.Header.Height] = extendedBlock{
+		empty:   app.IsEmptyBlockRef(block.Data, version),
+		shares:  width * width,
+		bytes:   size,
+		latency: latency,
+	}
+}
+
+// add counts the block at height, as failed if err is set, which emit
+// calls in height order.
+func (r *rangeSummary) add(height int64, err error) {
+	r.mu.Lock()
+	block, ok := r.pending[height]
+	delete(r.pending, height)
+	r.mu.Unlock()
+	r.Blocks++
+	if ok {
+		r.extends++
+		r.extendTotal += block.latency
+	}
+	if err != nil {
+		r.Failed = append(r.Failed, height)
+		return
+	}
+	if block.empty {
+		r.Empty++
+	} else {
+		r.NonEmpty++
+	}
+	r.Shares += block.shares
+	r.Bytes += block.bytes
+}
+
+// finish sets the times of the range once it is done, with intervals the
+// times between its blocks.
+func (r *rangeSummary) finish(intervals intervalStats) {
+	r.Elapsed = time.Since(r.start)
+	r.Intervals = intervals
+	if r.extends > 0 {
+		r.AverageExtend = r.extendTotal / time.Duration(r.extends)
+	}
+}
+
+func (r *rangeSummary) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "blocks: %d (%d non-empty, %d empty), %d failed\n", r.Blocks, r.NonEmpty, r.Empty, len(r.Failed))
+	fmt.Fprintf(&b, "shares: %d, bytes: %d\n", r.Shares, r.Bytes)
+	fmt.Fprintf(&b, "elapsed: %s, average extension: %s", r.Elapsed, r.AverageExtend)
+	if r.Intervals.Count > 0 {
+		b.WriteString("\nblock " + r.Intervals.String())
+	}
+	if len(r.Failed) > 0 {
+		failed := make([]string, len(r.Failed))
+		for i, height := range r.Failed {
+			failed[i] = strconv.FormatInt(height, 10)
+		}
+		b.WriteString("\nfailed heights: " + strings.Join(failed, ", "))
+	}
+	return b.String()
+}
+
+// blockTime is a block's time, and the time since the block before it, as
+// range prints it with --block-times.
+type blockTime struct {
+	Height int64     `json:"height"`
+	Time   time.Time `json:"time"`
+	// Interval is the time since the block before, nil for the first
+	// block of the range or one after a failed height.
+	Interval *time.Duration `json:"interval_ns,omitempty"`
+}
+
+func (t *blockTime) String() string {
+	interval := "-"
+	if t.Interval != nil {
+		interval = t.Interval.String()
+	}
+	return fmt.Sprintf("%d\t%s\t%s", t.Height, t.Time.Format(time.RFC3339Nano), interval)
+}
+
+// blockTimer collects the times between the consecutive blocks passed to
+// it, in height order.
+type blockTimer struct {
+	prev      *stateless.ExtendedHeader
+	intervals []time.Duration
+}
+
+// add returns the time of eh, and the interval since the block before it
+// if that was the header add was last called with.
+func (t *blockTimer) add(eh *stateless.ExtendedHeader) *blockTime {
+	prev := t.prev
+	t.prev = eh
+	record := &blockTime{Height: eh.Height, Time: eh.Time}
+	if prev != nil && eh.Height == prev.Height+1 {
+		interval := eh.Time.Sub(prev.Time)
+		t.intervals = append(t.intervals, interval)
+		record.Interval = &interval
+	}
+	return record
+}
+
+// intervalStats are the minimum, maximum, average and standard deviation
+// of a series of block intervals.
+type intervalStats struct {
+	Count   int           `json:"count"`
+	Min     time.Duration `json:"min_ns"`
+	Max     time.Duration `json:"max_ns"`
+	Average time.Duration `json:"average_ns"`
+	StdDev  time.Duration `json:"stddev_ns"`
+}
+
+// stats returns the statistics of the intervals collected so far.
+func (t *blockTimer) stats() intervalStats {
+	stats := intervalStats{Count: len(t.intervals)}
+	if stats.Count == 0 {
+		return stats
+	}
+	stats.Min, stats.Max = slices.Min(t.intervals), slices.Max(t.intervals)
+	var sum float64
+	for _, interval := range t.intervals {
+		sum += float64(interval)
+	}
+	mean := sum / float64(stats.Count)
+	var squares float64
+	for _, interval := range t.intervals {
+		squares += (float64(interval) - mean) * (float64(interval) - mean)
+	}
+	stats.Average = time.Duration(mean)
+	stats.StdDev = time.Duration(math.Sqrt(squares / float64(stats.Count)))
+	return stats
+}
+
+func (s intervalStats) String() string {
+	return fmt.Sprintf("intervals: %d, min %s, max %s, average %s, stddev %s",
+		s.Count, s.Min, s.Max, s.Average, s.StdDev)
+}
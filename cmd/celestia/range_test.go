@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adlerjohn/celestia-node-stateless/pkg/stateless"
+	"github.com/celestiaorg/celestia-app/v3/pkg/appconsts"
+	"github.com/celestiaorg/rsmt2d"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestAppVersionTracker(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		versions []uint64
+		// fail are the indices of the blocks that fail the check.
+		fail []int
+	}{
+		{"one version", []uint64{2, 2, 2}, nil},
+		{"upgrade", []uint64{1, 2, 2, 3}, nil},
+		{"downgrade", []uint64{2, 3, 2}, []int{2}},
+		{"downgrade for several blocks", []uint64{3, 2, 2, 3}, []int{1, 2}},
+		{"downgrade below an earlier upgrade", []uint64{1, 2, 3, 2, 1}, []int{3, 4}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var tracker appVersionTracker
+			var failed []int
+			for i, version := range tc.versions {
+				eh := &stateless.ExtendedHeader{Header: types.Header{Height: int64(i + 1)}}
+				eh.Version.App = version
+				if err := tracker.check(eh); err != nil {
+					if code := exitCode(err); code != exitVerification {
+						t.Errorf("block %d: error %q exits with code %d, want %d", i, err, code, exitVerification)
+					}
+					failed = append(failed, i)
+				}
+			}
+			if !slices.Equal(failed, tc.fail) {
+				t.Errorf("blocks %v failed, want %v", failed, tc.fail)
+			}
+		})
+	}
+}
+
+func TestRangeSummaryOnly(t *testing.T) {
+	addr := startFakeCore(t,
+		testBlock(t, 1),
+		testBlock(t, 2, []byte("transfer"), []byte("delegate")),
+		testBlock(t, 3, make([]byte, 2000)),
+		testBlock(t, 4),
+	)
+	out, err := runCLI(t, addr, "--json", "range", "1", "4", "--summary-only", "--check-links=false")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got rangeSummary
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("summary %q is not one JSON object: %v", out, err)
+	}
+	// Both small txs share a compact share, and the 2000 byte tx spans 5
+	// shares, so its square is 4x4
+	type totals struct{ blocks, empty, nonEmpty, shares, bytes, failed int }
+	if got, want := (totals{got.Blocks, got.Empty, got.NonEmpty, got.Shares, got.Bytes, len(got.Failed)}),
+		(totals{4, 2, 2, 1 + 1 + 1 + 16, 16 + 2000, 0}); got != want {
+		t.Errorf("totals %+v, want %+v", got, want)
+	}
+	if got.Elapsed <= 0 || got.AverageExtend <= 0 || got.AverageExtend > got.Elapsed {
+		t.Errorf("elapsed %s, average extension %s", got.Elapsed, got.AverageExtend)
+	}
+
+	out, err = runCLI(t, addr, "range", "1", "4", "--summary-only", "--check-links=false")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out, "blocks: 4 (2 non-empty, 2 empty), 0 failed\nshares: 19, bytes: 2016\n") {
+		t.Errorf("summary %q", out)
+	}
+
+	_, err = runCLI(t, addr, "range", "1", "5", "--summary-only", "--check-links=false")
+	if err == nil || !strings.Contains(err.Error(), "1 of 5 heights failed") {
+		t.Errorf("got error %v, want height 5 to fail", err)
+	}
+}
+
+func TestRangeBlockTimes(t *testing.T) {
+	blocks := []*stateless.SignedBlock{testBlock(t, 1), testBlock(t, 2), testBlock(t, 3), testBlock(t, 4)}
+	// Blocks are 6s apart; moving block 3 makes the intervals 6s, 9s and 3s
+	blocks[2] = testSignedBlockAt(t, 3, blocks[2].Header.Time.Add(3*time.Second), appconsts.LatestVersion, blocks[2].Header.DataHash)
+	addr := startFakeCore(t, blocks...)
+	args := []string{"range", "1", "4", "--block-times", "--check-links=false"}
+
+	out, err := runCLI(t, addr, append([]string{"--ndjson"}, args...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("printed %d lines, want a time per block and the statistics:\n%s", len(lines), out)
+	}
+	for i, want := range []time.Duration{0, 6 * time.Second, 9 * time.Second, 3 * time.Second} {
+		var got blockTime
+		if err := json.Unmarshal([]byte(lines[i]), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Height != int64(i+1) || !got.Time.Equal(blocks[i].Header.Time) {
+			t.Errorf("line %d: height %d at %s, want %d at %s", i, got.Height, got.Time, i+1, blocks[i].Header.Time)
+		}
+		switch {
+		case i == 0 && got.Interval != nil:
+			t.Errorf("first block has interval %s", *got.Interval)
+		case i > 0 && (got.Interval == nil || *got.Interval != want):
+			t.Errorf("block %d: interval %v, want %s", got.Height, got.Interval, want)
+		}
+	}
+	var stats intervalStats
+	if err := json.Unmarshal([]byte(lines[4]), &stats); err != nil {
+		t.Fatal(err)
+	}
+	// The intervals deviate from their 6s average by 0s, 3s and 3s
+	want := intervalStats{Count: 3, Min: 3 * time.Second, Max: 9 * time.Second, Average: 6 * time.Second, StdDev: 2449489742}
+	if stats != want {
+		t.Errorf("statistics %+v, want %+v", stats, want)
+	}
+
+	out, err = runCLI(t, addr, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out, "1\t2023-11-14T22:13:26Z\t-\n2\t2023-11-14T22:13:32Z\t6s\n") ||
+		!strings.HasSuffix(out, "\nintervals: 3, min 3s, max 9s, average 6s, stddev 2.449489742s\n") {
+		t.Errorf("block times %q", out)
+	}
+
+	out, err = runCLI(t, addr, "--json", "range", "1", "4", "--summary-only", "--check-links=false")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var summary rangeSummary
+	if err := json.Unmarshal([]byte(out), &summary); err != nil {
+		t.Fatal(err)
+	}
+	if summary.Intervals != want {
+		t.Errorf("summary statistics %+v, want %+v", summary.Intervals, want)
+	}
+}
+
+func TestPipelineRange(t *testing.T) {
+	stages := rangeStages{
+		fetch: func(height int64) (*stateless.SignedBlock, error) {
+			return &stateless.SignedBlock{Header: &types.Header{Height: height}}, nil
+		},
+		extend: func(*stateless.SignedBlock) (*rsmt2d.ExtendedDataSquare, error) { return nil, nil },
+		verify: func(block *stateless.SignedBlock, _ *rsmt2d.ExtendedDataSquare) (*stateless.ExtendedHeader, error) {
+			return &stateless.ExtendedHeader{Header: *block.Header}, nil
+		},
+	}
+	errStop := errors.New("stop")
+	for _, tc := range []struct {
+		name        string
+		start, end  int64
+		concurrency int
+		// stopAt is the height emit fails at, 0 for none.
+		stopAt int64
+		want   []int64
+		// code is the exit code of the error, 0 if there is none.
+		code int
+	}{
+		{"range", 3, 9, 2, 0, []int64{3, 4, 5, 6, 7, 8, 9}, 0},
+		{"one height", 5, 5, 4, 0, []int64{5}, 0},
+		{"start above end", 9, 3, 2, 0, nil, exitUsage},
+		{"no concurrency", 3, 9, 0, 0, nil, exitUsage},
+		// The producer is blocked on a full pipeline when emit stops it
+		{"stopped early", 1, 1000, 1, 2, []int64{1, 2}, exitFailure},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var emitted []int64
+			err := pipelineRange(tc.start, tc.end, tc.concurrency, stages, func(height int64, eh *stateless.ExtendedHeader, err error) error {
+				if err != nil {
+					t.Fatalf("height %d failed: %v", height, err)
+				}
+				if eh.Height != height {
+					t.Errorf("emitted header %d as height %d", eh.Height, height)
+				}
+				emitted = append(emitted, height)
+				if height == tc.stopAt {
+					return errStop
+				}
+				return nil
+			})
+			switch {
+			case tc.code == 0 && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tc.code != 0 && exitCode(err) != tc.code:
+				t.Fatalf("error %v exits with code %d, want %d", err, exitCode(err), tc.code)
+			}
+			if !slices.Equal(emitted, tc.want) {
+				t.Errorf("emitted heights %v, want %v", emitted, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+
+	"github.com/adlerjohn/celestia-node-stateless/pkg/stateless"
+	"github.com/celestiaorg/celestia-app/v3/pkg/da"
+	"github.com/celestiaorg/nmt"
+	"github.com/celestiaorg/rsmt2d"
+	tmjson "github.com/tendermint/tendermint/libs/json"
+	"github.com/tendermint/tendermint/types"
+)
+
+// receiptSample is a single share of the extended square together with
+// its NMT proof against the DAH row root.
+type receiptSample struct {
+	Row   uint       `json:"row"`
+	Col   uint       `json:"col"`
+	Share []byte     `json:"share"`
+	Proof *nmt.Proof `json:"proof"`
+}
+
+// availabilityReceipt bundles everything needed to check, without the
+// block, that a header was committed by its validator set and that a
+// random sample of its extended square is committed to by its DAH.
+//
+// Receipts are serialized as tendermint JSON so that the validator public
+// keys round-trip.
+type availabilityReceipt struct {
+	Header       *types.Header              `json:"header"`
+	Commit       *types.Commit              `json:"commit"`
+	ValidatorSet *types.ValidatorSet        `json:"validator_set"`
+	DAH          *da.DataAvailabilityHeader `json:"dah"`
+	Samples      []receiptSample            `json:"samples"`
+}
+
+// newAvailabilityReceipt builds a receipt for block with n samples drawn
+// uniformly at random, with replacement, from eds.
+func newAvailabilityReceipt(block *stateless.SignedBlock, eds *rsmt2d.ExtendedDataSquare, n int) (*availabilityReceipt, error) {
+	dah, err := da.NewDataAvailabilityHeader(eds)
+	if err != nil {
+		return nil, err
+	}
+	width := eds.Width()
+	samples := make([]receiptSample, n)
+	for i := range samples {
+		row, col := uint(rand.Intn(int(width))), uint(rand.Intn(int(width)))
+		proof, err := proveShare(eds, row, col)
+		if err != nil {
+			return nil, fmt.Errorf("sample (%d, %d): %w", row, col, err)
+		}
+		samples[i] = receiptSample{Row: row, Col: col, Share: eds.GetCell(row, col), Proof: proof}
+	}
+	return &availabilityReceipt{
+		Header:       block.Header,
+		Commit:       block.Commit,
+		ValidatorSet: block.ValidatorSet,
+		DAH:          &dah,
+		Samples:      samples,
+	}, nil
+}
+
+func writeReceipt(path string, r *availabilityReceipt) error {
+	bz, err := tmjson.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, bz, 0o644)
+}
+
+func readReceipt(path string) (*availabilityReceipt, error) {
+	bz, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	r := new(availabilityReceipt)
+	if err := tmjson.Unmarshal(bz, r); err != nil {
+		return nil, decodeFailed(fmt.Errorf("decoding receipt %s: %w", path, err))
+	}
+	return r, nil
+}
+
+// verify checks the receipt offline: that its validator set matches the
+// header, that the commit carries more than 2/3 of that set's voting power
+// for the header, that the DAH hashes to the header's data hash, and that
+// every sample proof verifies against the DAH.
+func (r *availabilityReceipt) verify() error {
+	if r.Header == nil || r.Commit == nil || r.ValidatorSet == nil || r.DAH == nil {
+		return errors.New("receipt is missing its header, commit, validator set or DAH")
+	}
+	if err := r.Header.ValidateBasic(); err != nil {
+		return fmt.Errorf("invalid header: %w", err)
+	}
+	if err := r.Commit.ValidateBasic(); err != nil {
+		return fmt.Errorf("invalid commit: %w", err)
+	}
+	if err := r.ValidatorSet.ValidateBasic(); err != nil {
+		return fmt.Errorf("invalid validator set: %w", err)
+	}
+	if err := r.DAH.ValidateBasic(); err != nil {
+		return fmt.Errorf("invalid DAH: %w", err)
+	}
+	block := &stateless.SignedBlock{Header: r.Header, Commit: r.Commit, ValidatorSet: r.ValidatorSet}
+	if err := stateless.VerifyCommit(block); err != nil {
+		return err
+	}
+	if err := stateless.VerifyDAH(r.Header, r.DAH); err != nil {
+		return err
+	}
+	width := uint(len(r.DAH.RowRoots))
+	for i, s := range r.Samples {
+		if s.Proof == nil {
+			return fmt.Errorf("sample %d: missing proof", i)
+		}
+		if s.Col >= width {
+			return fmt.Errorf("sample %d: column %d out of range for width %d", i, s.Col, width)
+		}
+		ns, err := cellNamespace(width, s.Row, s.Col, s.Share)
+		if err != nil {
+			return fmt.Errorf("sample %d: %w", i, err)
+		}
+		if err := verifyShareAgainstDAH(r.DAH, int(s.Row), ns, s.Share, s.Proof); err != nil {
+			return fmt.Errorf("sample %d (%d, %d): %w", i, s.Row, s.Col, err)
+		}
+	}
+	return nil
+}
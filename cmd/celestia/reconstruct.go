@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/adlerjohn/celestia-node-stateless/pkg/stateless"
+	"github.com/celestiaorg/rsmt2d"
+)
+
+// quadrantShares returns the cells of eds in row-major order with every
+// share outside quadrant q set to nil. Quadrants are numbered row-major,
+// 0 being the original data square.
+func quadrantShares(eds *rsmt2d.ExtendedDataSquare, q int) ([][]byte, error) {
+	if q < 0 || q > 3 {
+		return nil, fmt.Errorf("quadrant must be 0 to 3, got %d", q)
+	}
+	width := eds.Width()
+	half := width / 2
+	shares := make([][]byte, width*width)
+	for row := uint(0); row < width; row++ {
+		for col := uint(0); col < width; col++ {
+			if int(row/half)*2+int(col/half) == q {
+				shares[row*width+col] = eds.GetCell(row, col)
+			}
+		}
+	}
+	return shares, nil
+}
+
+// dropShares returns the cells of eds in row-major order with each share
+// set to nil with probability fraction.
+func dropShares(eds *rsmt2d.ExtendedDataSquare, fraction float64) ([][]byte, error) {
+	if fraction < 0 || fraction > 1 {
+		return nil, fmt.Errorf("drop fraction must be between 0 and 1, got %g", fraction)
+	}
+	shares := eds.Flattened()
+	for i := range shares {
+		if rand.Float64() < fraction {
+			shares[i] = nil
+		}
+	}
+	return shares, nil
+}
+
+// sampleProvenShares returns n distinct cells of eds picked at random, each
+// with an NMT proof against its row root or, for every other cell, its
+// column root, as light clients sampling the square would hold them.
+func sampleProvenShares(eds *rsmt2d.ExtendedDataSquare, n int) ([]stateless.ProvenShare, error) {
+	width := eds.Width()
+	if n <= 0 || n > int(width*width) {
+		return nil, fmt.Errorf("sample count must be 1 to %d, got %d", width*width, n)
+	}
+	shares := make([]stateless.ProvenShare, n)
+	for i, cell := range rand.Perm(int(width * width))[:n] {
+		s := stateless.ProvenShare{Row: uint(cell) / width, Col: uint(cell) % width, Axis: rsmt2d.Row}
+		s.Share = eds.GetCell(s.Row, s.Col)
+		var err error
+		if i%2 == 0 {
+			s.Proof, err = proveShare(eds, s.Row, s.Col)
+		} else {
+			s.Axis = rsmt2d.Col
+			s.Proof, err = proveColShare(eds, s.Row, s.Col)
+		}
+		if err != nil {
+			return nil, err
+		}
+		shares[i] = s
+	}
+	return shares, nil
+}
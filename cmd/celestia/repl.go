@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/chzyer/readline"
+	"github.com/spf13/cobra"
+)
+
+// historyFile is where the REPL persists command history, relative to the
+// user's home directory.
+const historyFile = ".celestia_history"
+
+// runREPL reads commands from stdin and runs each one in session s, against
+// the same core connection, until `quit`, `exit`, or EOF. Errors are
+// printed and do not end the session.
+func runREPL(s *session) error {
+	cfg := &readline.Config{
+		Prompt:          "celestia> ",
+		InterruptPrompt: "^C",
+		EOFPrompt:       "quit",
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		cfg.HistoryFile = filepath.Join(home, historyFile)
+	}
+	rl, err := readline.NewEx(cfg)
+	if err != nil {
+		return err
+	}
+	defer rl.Close()
+
+	for {
+		line, err := rl.Readline()
+		switch {
+		case errors.Is(err, readline.ErrInterrupt):
+			continue
+		case errors.Is(err, io.EOF):
+			return nil
+		case err != nil:
+			return err
+		}
+
+		args := strings.Fields(line)
+		if len(args) == 0 {
+			continue
+		}
+		switch args[0] {
+		case "quit", "exit":
+			return nil
+		case "repl":
+			fmt.Fprintln(os.Stderr, "already in repl")
+			continue
+		}
+		if err := s.runCommand(args); err != nil {
+			s.printError(s.errorWriter(), err, exitCode(err))
+		}
+	}
+}
+
+// runCommand runs the command line args in session s, as the REPL does a
+// line. Each run gets fresh commands so no flag carries over to the next.
+func (s *session) runCommand(args []string) error {
+	cmd := s.subcommands()
+	cmd.SetArgs(tipOffsetArgs(cmd, args))
+	return cmd.Execute()
+}
+
+// subcommands returns a root command for running commands in session s
+// after the global flags were applied: it has no flags of its own and
+// doesn't set the session up again.
+func (s *session) subcommands() *cobra.Command {
+	cmd := &cobra.Command{
+		Use: "",
+		PersistentPreRun: func(cmd *cobra.Command, _ []string) {
+			cmd.SilenceUsage = true
+		},
+		SilenceErrors: true,
+	}
+	cmd.CompletionOptions.DisableDefaultCmd = true
+	cmd.SetFlagErrorFunc(negativeArgError)
+	cmd.AddCommand(s.commands()...)
+	return cmd
+}
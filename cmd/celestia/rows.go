@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/celestiaorg/rsmt2d"
+	tmbytes "github.com/tendermint/tendermint/libs/bytes"
+)
+
+// edsRow is one row of an extended square as the rows command writes it.
+type edsRow struct {
+	Row    uint               `json:"row"`
+	Shares []tmbytes.HexBytes `json:"shares"`
+}
+
+// writeRows writes the rows of eds to w one at a time, flushing after each,
+// so that no more than a row is buffered for output. By default each row
+// is a line of hex-encoded shares; with jsonOutput it is a JSON object, and
+// with outputTemplate the template is executed once per row.
+func (s *session) writeRows(w io.Writer, eds *rsmt2d.ExtendedDataSquare) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for i := uint(0); i < eds.Width(); i++ {
+		row := eds.Row(i)
+		var err error
+		switch {
+		case s.jsonOutput:
+			shares := make([]tmbytes.HexBytes, len(row))
+			for j, sh := range row {
+				shares[j] = sh
+			}
+			err = enc.Encode(&edsRow{Row: i, Shares: shares})
+		case s.outputTemplate != nil:
+			if err = s.outputTemplate.Execute(bw, struct {
+				Row    uint
+				Shares [][]byte
+			}{i, row}); err == nil {
+				err = bw.WriteByte('\n')
+			}
+		default:
+			err = writeHexRow(bw, i, row)
+		}
+		if err != nil {
+			return err
+		}
+		if err := bw.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeHexRow writes the shares of row i as a line of hex strings.
+func writeHexRow(w *bufio.Writer, i uint, row [][]byte) error {
+	if _, err := fmt.Fprintf(w, "row %d:", i); err != nil {
+		return err
+	}
+	enc := hex.NewEncoder(w)
+	for _, sh := range row {
+		if err := w.WriteByte(' '); err != nil {
+			return err
+		}
+		if _, err := enc.Write(sh); err != nil {
+			return err
+		}
+	}
+	return w.WriteByte('\n')
+}
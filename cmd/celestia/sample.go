@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"hash"
+	"math/rand"
+	"strings"
+	"sync"
+
+	"github.com/celestiaorg/celestia-app/v3/pkg/da"
+	"github.com/celestiaorg/celestia-node/share"
+	"github.com/celestiaorg/rsmt2d"
+)
+
+// sampleFailure is a sample whose proof did not verify.
+type sampleFailure struct {
+	Row   uint   `json:"row"`
+	Col   uint   `json:"col"`
+	Error string `json:"error"`
+}
+
+// sampleReport is the outcome of sampling an extended square the way a
+// light node would.
+type sampleReport struct {
+	Seed     int64           `json:"seed"`
+	Samples  int             `json:"samples"`
+	Passed   int             `json:"passed"`
+	Failures []sampleFailure `json:"failures"`
+}
+
+// sampleEDS draws n cells of eds uniformly at random, with replacement,
+// using a generator seeded with seed, proves each against its row root and
+// verifies the proof against dah. The samples are proven and verified by
+// workers goroutines, each with its own hasher, and reported in the order
+// they were drawn.
+func sampleEDS(eds *rsmt2d.ExtendedDataSquare, dah *da.DataAvailabilityHeader, n int, seed int64, workers int) *sampleReport {
+	rng := rand.New(rand.NewSource(seed))
+	width := eds.Width()
+	type sample struct {
+		row, col uint
+		err      error
+	}
+	samples := make([]sample, n)
+	for i := range samples {
+		samples[i].row, samples[i].col = uint(rng.Intn(int(width))), uint(rng.Intn(int(width)))
+	}
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(workers, n) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h := share.NewSHA256Hasher()
+			for i := range next {
+				samples[i].err = verifySample(h, eds, dah, samples[i].row, samples[i].col)
+			}
+		}()
+	}
+	for i := range samples {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	report := &sampleReport{Seed: seed, Samples: n, Failures: []sampleFailure{}}
+	for _, sample := range samples {
+		if sample.err != nil {
+			report.Failures = append(report.Failures, sampleFailure{Row: sample.row, Col: sample.col, Error: sample.err.Error()})
+			continue
+		}
+		report.Passed++
+	}
+	return report
+}
+
+// verifySample proves the cell at (row, col) of eds and verifies the proof
+// against the row root of dah, hashing with h.
+func verifySample(h hash.Hash, eds *rsmt2d.ExtendedDataSquare, dah *da.DataAvailabilityHeader, row, col uint) error {
+	sh := eds.GetCell(row, col)
+	proof, err := proveShare(eds, row, col)
+	if err != nil {
+		return err
+	}
+	ns, err := cellNamespace(eds.Width(), row, col, sh)
+	if err != nil {
+		return err
+	}
+	return verifyShareWithHasher(h, dah, int(row), ns, sh, proof)
+}
+
+func (r *sampleReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "seed: %d\n", r.Seed)
+	fmt.Fprintf(&b, "passed: %d of %d samples", r.Passed, r.Samples)
+	for _, f := range r.Failures {
+		fmt.Fprintf(&b, "\nfailed (%d, %d): %s", f.Row, f.Col, f.Error)
+	}
+	return b.String()
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/adlerjohn/celestia-node-stateless/pkg/stateless"
+	"github.com/celestiaorg/celestia-app/v3/pkg/appconsts"
+	"github.com/celestiaorg/celestia-app/v3/pkg/da"
+	"github.com/celestiaorg/rsmt2d"
+	"github.com/tendermint/tendermint/types"
+)
+
+// sampleSquare returns the extended square of a block holding one tx of
+// size bytes, and its DAH.
+func sampleSquare(t testing.TB, size int) (*rsmt2d.ExtendedDataSquare, *da.DataAvailabilityHeader) {
+	t.Helper()
+	data := &types.Data{Txs: types.Txs{bytes.Repeat([]byte{0x7a}, size)}}
+	eds, err := stateless.ExtendBlock(data, appconsts.LatestVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dah, err := da.NewDataAvailabilityHeader(eds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return eds, &dah
+}
+
+// TestSampleEDSWorkers checks that the number of verify workers changes
+// neither the samples drawn for a seed nor the order they're reported in.
+func TestSampleEDSWorkers(t *testing.T) {
+	eds, dah := sampleSquare(t, 20000)
+	// Spoil two row roots so that some samples fail.
+	bad := *dah
+	bad.RowRoots = append([][]byte(nil), dah.RowRoots...)
+	bad.RowRoots[1], bad.RowRoots[6] = dah.RowRoots[6], dah.RowRoots[1]
+
+	want := sampleEDS(eds, &bad, 200, 42, 1)
+	if want.Passed == 0 || len(want.Failures) == 0 {
+		t.Fatalf("%d passed and %d failed, want both", want.Passed, len(want.Failures))
+	}
+	for _, workers := range []int{2, 7, 500} {
+		t.Run(fmt.Sprintf("workers=%d", workers), func(t *testing.T) {
+			if got := sampleEDS(eds, &bad, 200, 42, workers); !reflect.DeepEqual(got, want) {
+				t.Errorf("report %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+// BenchmarkSampleEDS proves and verifies 1000 samples of a 64x64 square
+// with a growing number of verify workers. Run it on a machine with
+// several cores to see the speedup.
+func BenchmarkSampleEDS(b *testing.B) {
+	eds, dah := sampleSquare(b, 400000)
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				if report := sampleEDS(eds, dah, 1000, 1, workers); len(report.Failures) != 0 {
+					b.Fatalf("%d samples failed", len(report.Failures))
+				}
+			}
+		})
+	}
+}
+
+func TestSampleArgs(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"sample count", []string{"sample", "5", "16"}, ""},
+		{"sample count not a number", []string{"sample", "5", "notanumber"}, `invalid sample count "notanumber"`},
+		{"negative sample count", []string{"sample", "--", "5", "-16"}, `invalid sample count "-16"`},
+		{"no verify workers", []string{"sample", "5", "16", "--verify-workers", "0"}, "invalid --verify-workers 0"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := runCLI(t, "localhost:9090", append([]string{"--dry-run"}, tc.args...)...)
+			checkUsageError(t, err, tc.want)
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/adlerjohn/celestia-node-stateless/pkg/stateless"
+	"github.com/celestiaorg/rsmt2d"
+	tmbytes "github.com/tendermint/tendermint/libs/bytes"
+)
+
+// serveShutdownTimeout bounds how long serve waits for requests in flight
+// once it is told to stop.
+const serveShutdownTimeout = 10 * time.Second
+
+// server answers HTTP queries for the extended data of blocks fetched from
+// src, which all requests share.
+type server struct {
+	session *session
+	src     blockSource
+}
+
+// handler routes the server's endpoints.
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /extended_header/{height}", s.respond(s.extendedHeader))
+	mux.HandleFunc("GET /share/{height}/{row}/{col}", s.respond(s.share))
+	mux.HandleFunc("GET /dah/{height}", s.respond(s.dah))
+	return mux
+}
+
+// respond adapts an endpoint returning a result into a handler writing it
+// as JSON, like --json would print it. Errors are written as an object
+// with an error field, under the HTTP status of their class.
+func (s *server) respond(endpoint func(r *http.Request) (any, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status := http.StatusOK
+		v, err := endpoint(r)
+		if err != nil {
+			status = httpStatus(err)
+			v = struct {
+				Error string `json:"error"`
+			}{err.Error()}
+			slog.Debug("request failed", "path", r.URL.Path, "status", status, "err", err)
+		}
+		bz, err := json.Marshal(jsonView(v))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write(append(bz, '\n'))
+	}
+}
+
+// httpStatus returns the HTTP status for the class of err, mirroring its
+// exit code.
+func httpStatus(err error) int {
+	switch exitCode(err) {
+	case exitUsage:
+		return http.StatusBadRequest
+	case exitNotFound:
+		return http.StatusNotFound
+	case exitNetwork:
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// extendBlockAt fetches and extends the block at the height in the
+// request's path.
+func (s *server) extendBlockAt(r *http.Request) (*rsmt2d.ExtendedDataSquare, *stateless.ExtendedHeader, error) {
+	h := r.PathValue("height")
+	if h != "latest" {
+		if _, err := strconv.ParseInt(h, 10, 64); err != nil {
+			return nil, nil, usageError(fmt.Errorf("invalid height %q", h))
+		}
+	}
+	block, err := s.session.getSignedBlock(s.src, h)
+	if err != nil {
+		return nil, nil, err
+	}
+	eds, err := s.session.extendBlock(block)
+	if err != nil {
+		return nil, nil, err
+	}
+	eh, err := s.session.makeExtendedHeader(block, eds)
+	if err != nil {
+		return nil, nil, err
+	}
+	return eds, eh, nil
+}
+
+func (s *server) extendedHeader(r *http.Request) (any, error) {
+	_, eh, err := s.extendBlockAt(r)
+	if err != nil {
+		return nil, err
+	}
+	return eh, nil
+}
+
+func (s *server) dah(r *http.Request) (any, error) {
+	_, eh, err := s.extendBlockAt(r)
+	if err != nil {
+		return nil, err
+	}
+	return newDAHRoots(eh.DAH), nil
+}
+
+// servedShare is a cell of an extended square returned by /share.
+type servedShare struct {
+	Row    uint             `json:"row"`
+	Col    uint             `json:"col"`
+	Share  tmbytes.HexBytes `json:"share"`
+	Parity bool             `json:"parity"`
+}
+
+func (s *server) share(r *http.Request) (any, error) {
+	eds, _, err := s.extendBlockAt(r)
+	if err != nil {
+		return nil, err
+	}
+	width := eds.Width()
+	row, err := parseCellIndex("row", r.PathValue("row"), width)
+	if err != nil {
+		return nil, usageError(err)
+	}
+	col, err := parseCellIndex("column", r.PathValue("col"), width)
+	if err != nil {
+		return nil, usageError(err)
+	}
+	return &servedShare{
+		Row:    row,
+		Col:    col,
+		Share:  eds.GetCell(row, col),
+		Parity: row >= width/2 || col >= width/2,
+	}, nil
+}
+
+// serve answers HTTP queries on addr until ctx is done, then waits for
+// the requests in flight.
+func (s *server) serve(ctx context.Context, addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Handler: s.handler(), ReadHeaderTimeout: 10 * time.Second}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.Serve(ln)
+	}()
+	slog.Info("serving", "address", ln.Addr().String())
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
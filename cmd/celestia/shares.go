@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/adlerjohn/celestia-node-stateless/pkg/stateless"
+	libshare "github.com/celestiaorg/go-square/v2/share"
+	"github.com/celestiaorg/rsmt2d"
+)
+
+func writeODSFile(path string, eds *rsmt2d.ExtendedDataSquare) error {
+	f, err := createAtomicFile(path)
+	if err != nil {
+		return err
+	}
+	if err := stateless.WriteODS(f, eds); err != nil {
+		f.abort()
+		return err
+	}
+	return f.commit()
+}
+
+// readODSShares reads the shares of an original data square from a file
+// written by `shares`, or from a file of raw shares concatenated in
+// row-major order with nothing around them.
+func readODSShares(path string) ([][]byte, error) {
+	bz, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if stateless.IsODS(bz) {
+		shares, err := stateless.ReadODS(bytes.NewReader(bz))
+		if err != nil {
+			return nil, decodeFailed(fmt.Errorf("decoding shares file %s: %w", path, err))
+		}
+		return shares, nil
+	}
+	if len(bz) == 0 || len(bz)%libshare.ShareSize != 0 {
+		return nil, decodeFailed(fmt.Errorf("%s is %d bytes, not a whole number of %d-byte shares",
+			path, len(bz), libshare.ShareSize))
+	}
+	shares := make([][]byte, len(bz)/libshare.ShareSize)
+	for i := range shares {
+		shares[i] = bz[i*libshare.ShareSize : (i+1)*libshare.ShareSize]
+	}
+	return shares, nil
+}
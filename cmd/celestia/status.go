@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/adlerjohn/celestia-node-stateless/pkg/stateless"
+)
+
+// nodeStatus renders a core node's status for the status command.
+type nodeStatus stateless.NodeStatus
+
+func (s *nodeStatus) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "chain id: %s\n", s.ChainID)
+	fmt.Fprintf(&b, "latest height: %d\n", s.LatestHeight)
+	fmt.Fprintf(&b, "latest block time: %s\n", s.LatestBlockTime)
+	fmt.Fprintf(&b, "earliest height: %d\n", s.EarliestHeight)
+	fmt.Fprintf(&b, "catching up: %t", s.CatchingUp)
+	return b.String()
+}
+
+// checkStatus prints the status of the core node, and fails if the node
+// can't be reached, is catching up, or is on another chain than
+// expectedChainID.
+func (s *session) checkStatus(core *stateless.CoreAccessor) error {
+	ctx := context.Background()
+	if s.fetchTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.fetchTimeout)
+		defer cancel()
+	}
+	status, err := core.Status(ctx)
+	if err != nil {
+		return fmt.Errorf("querying core status: %w", err)
+	}
+	if err := s.printResult((*nodeStatus)(status)); err != nil {
+		return err
+	}
+	if s.expectedChainID != "" && status.ChainID != s.expectedChainID {
+		return fmt.Errorf("core node is on chain %q, expected %q", status.ChainID, s.expectedChainID)
+	}
+	if status.CatchingUp {
+		return fmt.Errorf("core node is catching up, at height %d", status.LatestHeight)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/adlerjohn/celestia-node-stateless/pkg/stateless"
+	"github.com/celestiaorg/celestia-app/v3/pkg/da"
+	libshare "github.com/celestiaorg/go-square/v2/share"
+	tmbytes "github.com/tendermint/tendermint/libs/bytes"
+)
+
+// blockSummary is the metadata of a block the summary command prints.
+type blockSummary struct {
+	Height     int64     `json:"height"`
+	ChainID    string    `json:"chain_id"`
+	AppVersion uint64    `json:"app_version"`
+	Time       time.Time `json:"time"`
+	Txs        int       `json:"txs"`
+	// Size is the total size in bytes of the block's transactions.
+	Size       int              `json:"size"`
+	SquareSize int              `json:"square_size"`
+	DataRoot   tmbytes.HexBytes `json:"data_root"`
+	Empty      bool             `json:"empty"`
+	// Content tells a square holding only reserved namespaces apart from
+	// an empty one and from one holding blobs.
+	Content *blockContent `json:"content"`
+	// SquareSizeUpperBound and SubtreeRootThreshold are the extension
+	// constants of the app version the block was extended under.
+	SquareSizeUpperBound int `json:"square_size_upper_bound"`
+	SubtreeRootThreshold int `json:"subtree_root_threshold"`
+	// Upgrade is the app version upgrade scheduled as of the query, if
+	// core could tell. NoUpgrade is set when it told there is none.
+	Upgrade   *stateless.Upgrade `json:"upgrade,omitempty"`
+	NoUpgrade bool               `json:"no_upgrade,omitempty"`
+}
+
+// newBlockSummary summarizes block, whose DAH is dah and original data
+// square shares.
+func (s *session) newBlockSummary(block *stateless.SignedBlock, dah *da.DataAvailabilityHeader, shares []libshare.Share) *blockSummary {
+	size := 0
+	for _, tx := range block.Data.Txs {
+		size += len(tx)
+	}
+	dahSum := newDAHSummary(dah)
+	version := s.appVersion(block.Header)
+	return &blockSummary{
+		Height:     block.Header.Height,
+		ChainID:    block.Header.ChainID,
+		AppVersion: block.Header.Version.App,
+		Time:       block.Header.Time,
+		Txs:        len(block.Data.Txs),
+		Size:       size,
+		SquareSize: dahSum.SquareSize,
+		DataRoot:   dahSum.DataRoot,
+		Empty:      dahSum.Empty,
+		Content:    newBlockContent(newNamespaceUsages(shares)),
+
+		SquareSizeUpperBound: stateless.SquareSizeUpperBound(version),
+		SubtreeRootThreshold: stateless.SubtreeRootThreshold(version),
+	}
+}
+
+func (s *blockSummary) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "height: %d\n", s.Height)
+	fmt.Fprintf(&b, "chain id: %s\n", s.ChainID)
+	fmt.Fprintf(&b, "app version: %d (square size upper bound %d, subtree root threshold %d)\n",
+		s.AppVersion, s.SquareSizeUpperBound, s.SubtreeRootThreshold)
+	switch {
+	case s.Upgrade != nil:
+		fmt.Fprintf(&b, "scheduled upgrade: app version %d at height %d\n", s.Upgrade.AppVersion, s.Upgrade.Height)
+	case s.NoUpgrade:
+		b.WriteString("scheduled upgrade: none\n")
+	}
+	fmt.Fprintf(&b, "time: %s\n", s.Time.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&b, "txs: %d (%d bytes)\n", s.Txs, s.Size)
+	fmt.Fprintf(&b, "square size: %d\n", s.SquareSize)
+	fmt.Fprintf(&b, "data root: %s\n", s.DataRoot)
+	fmt.Fprintf(&b, "empty: %t\n", s.Empty)
+	fmt.Fprintf(&b, "content: %s", s.Content)
+	return b.String()
+}
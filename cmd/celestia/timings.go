@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/adlerjohn/celestia-node-stateless/pkg/stateless"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// stageTiming is one run of a pipeline stage reported by the stateless
+// package.
+type stageTiming struct {
+	stage      stateless.Stage
+	duration   time.Duration
+	squareSize int
+}
+
+// timingRecorder collects stage timings for --timings.
+type timingRecorder struct {
+	mu      sync.Mutex
+	timings []stageTiming
+}
+
+func (r *timingRecorder) observe(stage stateless.Stage, d time.Duration, squareSize int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.timings = append(r.timings, stageTiming{stage: stage, duration: d, squareSize: squareSize})
+}
+
+// writeTable writes the recorded timings in the order the stages finished,
+// with the square size and share count of every extension.
+func (r *timingRecorder) writeTable(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "stage\tduration\tsquare size\tshares\t")
+	for _, t := range r.timings {
+		size, shares := "-", "-"
+		if t.stage == stateless.StageExtend {
+			size = fmt.Sprint(t.squareSize)
+			shares = fmt.Sprint(t.squareSize * t.squareSize)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t\n", t.stage, t.duration.Round(time.Microsecond), size, shares)
+	}
+	return tw.Flush()
+}
+
+// stageMetrics exports stage timings as Prometheus metrics.
+type stageMetrics struct {
+	durations  *prometheus.HistogramVec
+	squareSize prometheus.Histogram
+	shares     prometheus.Counter
+}
+
+func newStageMetrics(reg prometheus.Registerer) *stageMetrics {
+	m := &stageMetrics{
+		durations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "celestia_stateless_stage_duration_seconds",
+			Help:    "Duration of fetching, reassembling and extending blocks.",
+			Buckets: prometheus.ExponentialBuckets(0.001, 2, 16),
+		}, []string{"stage"}),
+		squareSize: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "celestia_stateless_square_size",
+			Help:    "Width of the original data square of extended blocks.",
+			Buckets: prometheus.ExponentialBuckets(1, 2, 10),
+		}),
+		shares: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "celestia_stateless_extended_shares_total",
+			Help: "Original shares in all extended blocks.",
+		}),
+	}
+	reg.MustRegister(m.durations, m.squareSize, m.shares)
+	return m
+}
+
+func (m *stageMetrics) observe(stage stateless.Stage, d time.Duration, squareSize int) {
+	m.durations.WithLabelValues(string(stage)).Observe(d.Seconds())
+	if stage == stateless.StageExtend {
+		m.squareSize.Observe(float64(squareSize))
+		m.shares.Add(float64(squareSize * squareSize))
+	}
+}
+
+// serveMetrics serves the metrics in reg at /metrics on addr in the
+// background for the rest of the process.
+func serveMetrics(addr string, reg *prometheus.Registry) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+	return serveBackground("metrics", addr, mux)
+}
+
+// servePprof serves the net/http/pprof profiles at /debug/pprof/ on addr
+// in the background for the rest of the process.
+func servePprof(addr string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return serveBackground("profiles", addr, mux)
+}
+
+// serveBackground serves handler on addr in the background for the rest
+// of the process, logging what it serves as name.
+func serveBackground(name, addr string, handler http.Handler) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("serving %s: %w", name, err)
+	}
+	srv := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("serving "+name, "err", err)
+		}
+	}()
+	slog.Info("serving "+name, "address", ln.Addr().String())
+	return nil
+}
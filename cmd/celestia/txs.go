@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/celestiaorg/celestia-app/v3/pkg/proof"
+	blobtypes "github.com/celestiaorg/celestia-app/v3/x/blob/types"
+	libsquare "github.com/celestiaorg/go-square/v2"
+	libshare "github.com/celestiaorg/go-square/v2/share"
+	"github.com/celestiaorg/go-square/v2/tx"
+	"github.com/celestiaorg/rsmt2d"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	tmbytes "github.com/tendermint/tendermint/libs/bytes"
+	"github.com/tendermint/tendermint/types"
+)
+
+// pfbBlob is a blob declared by a MsgPayForBlobs.
+type pfbBlob struct {
+	Namespace  tmbytes.HexBytes `json:"namespace"`
+	Size       uint32           `json:"size"`
+	Commitment tmbytes.HexBytes `json:"commitment"`
+}
+
+// txSummary describes a transaction of a block by the types of its
+// messages and the blobs its PayForBlobs declare. Error is set instead if
+// the transaction can't be decoded.
+type txSummary struct {
+	Index    int       `json:"index"`
+	Size     int       `json:"size"`
+	BlobTx   bool      `json:"blob_tx"`
+	Messages []string  `json:"messages,omitempty"`
+	Blobs    []pfbBlob `json:"blobs,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+type txSummaries []txSummary
+
+// summarizeTxs decodes every transaction of txs.
+func summarizeTxs(txs types.Txs) txSummaries {
+	decode := txDecoder()
+	summaries := make(txSummaries, len(txs))
+	for i, rawTx := range txs {
+		s := txSummary{Index: i, Size: len(rawTx)}
+		// Blob transactions wrap the signed transaction with its blobs
+		sdkTxBytes := []byte(rawTx)
+		if blobTx, isBlobTx, err := tx.UnmarshalBlobTx(rawTx); isBlobTx {
+			if err != nil {
+				s.Error = fmt.Sprintf("malformed blob transaction: %v", err)
+				summaries[i] = s
+				continue
+			}
+			s.BlobTx = true
+			sdkTxBytes = blobTx.Tx
+		}
+		sdkTx, err := decode(sdkTxBytes)
+		if err != nil {
+			s.Error = err.Error()
+			summaries[i] = s
+			continue
+		}
+		for _, msg := range sdkTx.GetMsgs() {
+			s.Messages = append(s.Messages, sdk.MsgTypeURL(msg))
+			if pfb, ok := msg.(*blobtypes.MsgPayForBlobs); ok {
+				s.Blobs = append(s.Blobs, pfbBlobs(pfb)...)
+			}
+		}
+		summaries[i] = s
+	}
+	return summaries
+}
+
+// pfbBlobs lists the blobs pfb declares. The namespace, size and
+// commitment lists of a valid PayForBlobs have the same length; any
+// surplus entries of a malformed one are left out.
+func pfbBlobs(pfb *blobtypes.MsgPayForBlobs) []pfbBlob {
+	n := min(len(pfb.Namespaces), len(pfb.BlobSizes), len(pfb.ShareCommitments))
+	blobs := make([]pfbBlob, n)
+	for i := range blobs {
+		blobs[i] = pfbBlob{
+			Namespace:  pfb.Namespaces[i],
+			Size:       pfb.BlobSizes[i],
+			Commitment: pfb.ShareCommitments[i],
+		}
+	}
+	return blobs
+}
+
+func (s txSummaries) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "txs: %d", len(s))
+	for _, t := range s {
+		fmt.Fprintf(&b, "\n%d: %d bytes", t.Index, t.Size)
+		if t.Error != "" {
+			fmt.Fprintf(&b, ", undecodable: %s", t.Error)
+			continue
+		}
+		fmt.Fprintf(&b, ", %s", strings.Join(t.Messages, " "))
+		for _, blob := range t.Blobs {
+			fmt.Fprintf(&b, "\n  blob namespace %x size %d commitment %x",
+				[]byte(blob.Namespace), blob.Size, []byte(blob.Commitment))
+		}
+	}
+	return b.String()
+}
+
+// txShareRange returns the range of shares of the original data square of
+// eds that hold the transaction at index of txs, the transactions eds was
+// built from under the given square size upper bound and subtree root
+// threshold. The range is checked to lie within the square.
+func txShareRange(eds *rsmt2d.ExtendedDataSquare, txs [][]byte, index, upperBound, subtreeRootThreshold int) (libshare.Range, error) {
+	if index < 0 || index >= len(txs) {
+		return libshare.Range{}, fmt.Errorf("no transaction %d, the block has %d", index, len(txs))
+	}
+	shareRange, err := libsquare.TxShareRange(txs, index, upperBound, subtreeRootThreshold)
+	if err != nil {
+		return libshare.Range{}, fmt.Errorf("transaction %d: %w", index, err)
+	}
+	width := int(eds.Width() / 2)
+	if shareRange.Start < 0 || shareRange.Start >= shareRange.End || shareRange.End > width*width {
+		return libshare.Range{}, fmt.Errorf("transaction %d maps to shares %d to %d, outside the %dx%d square",
+			index, shareRange.Start, shareRange.End, width, width)
+	}
+	return shareRange, nil
+}
+
+// txNamespace returns the namespace the shares of rawTx are under: the
+// PayForBlobs namespace for a blob transaction, whose blobs are left out
+// of its shares, and the transaction namespace otherwise.
+func txNamespace(rawTx []byte) libshare.Namespace {
+	if _, isBlobTx, _ := tx.UnmarshalBlobTx(rawTx); isBlobTx {
+		return libshare.PayForBlobNamespace
+	}
+	return libshare.TxNamespace
+}
+
+// txProof proves a transaction included under a block's data root, in
+// the ShareProof encoding blobProof uses: the shares holding the
+// transaction, an NMT proof of them for every row they span, and a Merkle
+// proof of each of those row roots to the data root.
+type txProof struct {
+	proof.ShareProof
+	index      int
+	shareRange libshare.Range
+	dataRoot   []byte
+}
+
+// newTxProof proves the transaction at index of the block data extended
+// into eds, and checks the proof against dataRoot.
+func newTxProof(eds *rsmt2d.ExtendedDataSquare, data *types.Data, index, upperBound, subtreeRootThreshold int, dataRoot []byte) (*txProof, error) {
+	txs := data.Txs.ToSliceOfBytes()
+	shareRange, err := txShareRange(eds, txs, index, upperBound, subtreeRootThreshold)
+	if err != nil {
+		return nil, err
+	}
+	sp, err := proof.NewShareInclusionProofFromEDS(eds, txNamespace(txs[index]), shareRange)
+	if err != nil {
+		return nil, err
+	}
+	if err := sp.Validate(dataRoot); err != nil {
+		return nil, fmt.Errorf("transaction %d proof doesn't verify against data root %X: %w", index, dataRoot, err)
+	}
+	return &txProof{ShareProof: sp, index: index, shareRange: shareRange, dataRoot: dataRoot}, nil
+}
+
+func (p *txProof) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "transaction %d: shares %d to %d in rows %d to %d\n",
+		p.index, p.shareRange.Start, p.shareRange.End, p.RowProof.StartRow, p.RowProof.EndRow)
+	fmt.Fprintf(&b, "data root: %X", p.dataRoot)
+	for i, sp := range p.ShareProofs {
+		fmt.Fprintf(&b, "\nrow %d: root %x, shares %d to %d, siblings %d",
+			p.RowProof.StartRow+uint32(i), p.RowProof.RowRoots[i], sp.Start, sp.End, len(sp.Nodes))
+	}
+	return b.String()
+}
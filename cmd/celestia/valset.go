@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+// validatorSetReport summarizes a validator set and how its voting power
+// is distributed.
+type validatorSetReport struct {
+	Height     int64
+	Validators []*types.Validator
+	TotalPower int64
+	// TopN is the number of largest validators whose combined share of the
+	// total voting power is reported in TopNPower.
+	TopN      int
+	TopNPower int64
+}
+
+// newValidatorSetReport builds a report over vals, ordering validators by
+// descending voting power.
+func newValidatorSetReport(height int64, vals *types.ValidatorSet, topN int) *validatorSetReport {
+	validators := make([]*types.Validator, len(vals.Validators))
+	copy(validators, vals.Validators)
+	sort.SliceStable(validators, func(i, j int) bool {
+		return validators[i].VotingPower > validators[j].VotingPower
+	})
+
+	if topN > len(validators) {
+		topN = len(validators)
+	}
+	var topNPower int64
+	for _, v := range validators[:topN] {
+		topNPower += v.VotingPower
+	}
+	return &validatorSetReport{
+		Height:     height,
+		Validators: validators,
+		TotalPower: vals.TotalVotingPower(),
+		TopN:       topN,
+		TopNPower:  topNPower,
+	}
+}
+
+func (r *validatorSetReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "height: %d\n", r.Height)
+	fmt.Fprintf(&b, "validators: %d\n", len(r.Validators))
+	fmt.Fprintf(&b, "total voting power: %d\n", r.TotalPower)
+	fmt.Fprintf(&b, "top %d voting power: %d (%.2f%%)\n", r.TopN, r.TopNPower, r.percent(r.TopNPower))
+	fmt.Fprintf(&b, "%-40s %14s %8s %18s", "address", "voting power", "share", "proposer priority")
+	for _, v := range r.Validators {
+		fmt.Fprintf(&b, "\n%-40s %14d %7.2f%% %18d", v.Address, v.VotingPower, r.percent(v.VotingPower), v.ProposerPriority)
+	}
+	return b.String()
+}
+
+func (r *validatorSetReport) percent(power int64) float64 {
+	if r.TotalPower == 0 {
+		return 0
+	}
+	return 100 * float64(power) / float64(r.TotalPower)
+}
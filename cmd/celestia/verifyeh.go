@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/adlerjohn/celestia-node-stateless/pkg/stateless"
+	"github.com/celestiaorg/celestia-app/v3/pkg/da"
+	"github.com/celestiaorg/rsmt2d"
+	"github.com/tendermint/tendermint/types"
+)
+
+// readExtendedHeader reads an ExtendedHeader saved with `--json eds`.
+func readExtendedHeader(path string) (*stateless.ExtendedHeader, error) {
+	bz, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	eh, err := decodeExtendedHeader(bz)
+	if err != nil {
+		return nil, decodeFailed(fmt.Errorf("decoding extended header %s: %w", path, err))
+	}
+	return eh, nil
+}
+
+// headerCheck is the outcome of one check of an ExtendedHeader, failed if
+// Error is set.
+type headerCheck struct {
+	Check string `json:"check"`
+	Error string `json:"error,omitempty"`
+}
+
+type headerChecks []headerCheck
+
+// checkExtendedHeader checks that the DAH of eh hashes to its DataHash and,
+// if trustedRoot is set, to trustedRoot, that its validator set is well
+// formed and the one the header names, and that its commit is signed by
+// that set. Every check runs even once one failed.
+func checkExtendedHeader(eh *stateless.ExtendedHeader, trustedRoot []byte) headerChecks {
+	var checks headerChecks
+	check := func(name string, err error) {
+		c := headerCheck{Check: name}
+		if err != nil {
+			c.Error = err.Error()
+		}
+		checks = append(checks, c)
+	}
+
+	check("data root", stateless.VerifyDAH(&eh.Header, eh.DAH))
+	if trustedRoot != nil {
+		var err error
+		if hash := eh.DAH.Hash(); !bytes.Equal(hash, trustedRoot) {
+			err = fmt.Errorf("DAH hash %X does not match trusted data root %X", hash, trustedRoot)
+		}
+		check("trusted data root", err)
+	}
+	check("validator set", stateless.VerifyValidatorSet(eh.ValidatorSet, &eh.Header))
+	check("commit", stateless.VerifyCommit(&stateless.SignedBlock{
+		Header:       &eh.Header,
+		Commit:       eh.Commit,
+		ValidatorSet: eh.ValidatorSet,
+	}))
+	return checks
+}
+
+// failed returns the number of failed checks.
+func (c headerChecks) failed() int {
+	n := 0
+	for _, check := range c {
+		if check.Error != "" {
+			n++
+		}
+	}
+	return n
+}
+
+func (c headerChecks) String() string {
+	var b strings.Builder
+	for i, check := range c {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		if check.Error == "" {
+			fmt.Fprintf(&b, "PASS %s", check.Check)
+		} else {
+			fmt.Fprintf(&b, "FAIL %s: %s", check.Check, check.Error)
+		}
+	}
+	return b.String()
+}
+
+// makeExtendedHeader builds the ExtendedHeader of block from its extended
+// square eds, first running validateBlock if --validate-all is set.
+func (s *session) makeExtendedHeader(block *stateless.SignedBlock, eds *rsmt2d.ExtendedDataSquare) (*stateless.ExtendedHeader, error) {
+	if s.validateAll {
+		if err := s.validateBlock(block, eds); err != nil {
+			return nil, err
+		}
+	}
+	return s.extendedHeader(block.Header, block.Commit, block.ValidatorSet, eds)
+}
+
+// extendedHeader assembles the ExtendedHeader of h from its extended square
+// eds, failing with stateless.ErrDAHMismatch if the DAH of eds doesn't hash
+// to the header's DataHash. When the settings extend squares validators
+// never committed to, a mismatch is only warned about instead.
+func (s *session) extendedHeader(h *types.Header, comm *types.Commit, vals *types.ValidatorSet, eds *rsmt2d.ExtendedDataSquare) (*stateless.ExtendedHeader, error) {
+	eh, err := stateless.MakeExtendedHeader(h, comm, vals, eds)
+	if !errors.Is(err, stateless.ErrDAHMismatch) || !s.dahMayDiffer(h) {
+		return eh, err
+	}
+	slog.Warn("DAH does not match the data root under custom NMT options or app version",
+		"height", h.Height, "err", err)
+	return stateless.AssembleExtendedHeader(h, comm, vals, eds)
+}
+
+// dahMayDiffer reports whether the square of h is extended with custom NMT
+// options, or under an --app-version other than its own, either of which
+// can yield a DAH other than the data root validators committed to.
+func (s *session) dahMayDiffer(h *types.Header) bool {
+	return len(s.nmtOptions) > 0 || s.appVersionOverride != 0 && s.appVersionOverride != h.Version.App
+}
+
+// validateBlock runs every check there is on block and its extended square
+// eds: the validator set hashes to the header's, the commit is for the
+// header's block ID, the DAH of eds hashes to the header's DataHash, the
+// commit signatures carry more than 2/3 of the voting power, and the
+// parity shares of eds re-encode from its data. The signatures are only
+// checked once the validator set and block ID are right, since they can't
+// be otherwise. Every failure is reported, as a verificationFailed error.
+func (s *session) validateBlock(block *stateless.SignedBlock, eds *rsmt2d.ExtendedDataSquare) error {
+	dah, err := da.NewDataAvailabilityHeader(eds)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	headerErr := stateless.VerifyHeader(block, &dah)
+	if headerErr != nil {
+		errs = append(errs, headerErr)
+	}
+	if !errors.Is(headerErr, stateless.ErrInvalidValidatorSet) && !errors.Is(headerErr, stateless.ErrCommitVerification) {
+		if err := stateless.VerifyCommit(block); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := verifyParity(eds, s.extendCodec); err != nil {
+		errs = append(errs, fmt.Errorf("parity: %w", err))
+	}
+	if len(errs) > 0 {
+		return verificationFailed(errors.Join(errs...))
+	}
+	return nil
+}
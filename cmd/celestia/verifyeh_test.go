@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/adlerjohn/celestia-node-stateless/pkg/stateless"
+	libshare "github.com/celestiaorg/go-square/v2/share"
+)
+
+// TestMakeExtendedHeaderNMTOptions checks that the data root only fails to
+// match under celestia-app's NMT configuration, since custom --nmt-*
+// options can't yield the root validators committed to.
+func TestMakeExtendedHeaderNMTOptions(t *testing.T) {
+	block := testBlock(t, 3, []byte("transfer"))
+	tampered := testSignedBlock(t, 3, block.Header.Version.App, bytes.Repeat([]byte{1}, 32), []byte("transfer"))
+	for _, tc := range []struct {
+		name               string
+		block              *stateless.SignedBlock
+		ignoreMaxNamespace bool
+		nsSize             int
+		// mismatch is whether the DAH doesn't hash to the data root.
+		mismatch bool
+		wantErr  bool
+	}{
+		{"default options", block, true, libshare.NamespaceSize, false, false},
+		{"default options, wrong data root", tampered, true, libshare.NamespaceSize, true, true},
+		{"max namespace not ignored", block, false, libshare.NamespaceSize, true, false},
+		{"8 byte namespaces", block, true, 8, true, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := testSession(t)
+			if err := s.setNMTOptions(tc.ignoreMaxNamespace, tc.nsSize); err != nil {
+				t.Fatal(err)
+			}
+			eds, err := s.extendBlock(tc.block)
+			if err != nil {
+				t.Fatal(err)
+			}
+			eh, err := s.makeExtendedHeader(tc.block, eds)
+			if tc.wantErr {
+				if !errors.Is(err, stateless.ErrDAHMismatch) {
+					t.Fatalf("got error %v, want %v", err, stateless.ErrDAHMismatch)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := !bytes.Equal(eh.DAH.Hash(), tc.block.Header.DataHash); got != tc.mismatch {
+				t.Errorf("DAH hash %X against data root %X: mismatch %t, want %t",
+					eh.DAH.Hash(), tc.block.Header.DataHash, got, tc.mismatch)
+			}
+		})
+	}
+}
+
+// TestMakeExtendedHeaderAppVersionOverride checks that a data root mismatch
+// is only warned about when --app-version overrides the block's own
+// version.
+func TestMakeExtendedHeaderAppVersionOverride(t *testing.T) {
+	block := testSignedBlock(t, 3, 3, bytes.Repeat([]byte{1}, 32), []byte("transfer"))
+	for _, tc := range []struct {
+		name     string
+		override uint64
+		wantErr  bool
+	}{
+		{"no override", 0, true},
+		{"override to the block's version", 3, true},
+		{"override to another version", 1, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := testSession(t)
+			s.appVersionOverride = tc.override
+			eds, err := s.extendBlock(block)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = s.makeExtendedHeader(block, eds)
+			if tc.wantErr && !errors.Is(err, stateless.ErrDAHMismatch) {
+				t.Errorf("got error %v, want %v", err, stateless.ErrDAHMismatch)
+			}
+			if !tc.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+
+	libshare "github.com/celestiaorg/go-square/v2/share"
+	"github.com/celestiaorg/rsmt2d"
+	tmbytes "github.com/tendermint/tendermint/libs/bytes"
+)
+
+// Colors of the square map, as xterm 256-color indices so the terminal
+// grid and the PNG agree. Blob namespaces cycle through blobColors in
+// square order.
+var (
+	shareKindColors = [numShareKinds]uint8{
+		txShare:                     33,
+		pfbShare:                    39,
+		primaryReservedPaddingShare: 244,
+		namespacePaddingShare:       248,
+		tailPaddingShare:            238,
+		reservedShare:               45,
+	}
+	parityColor uint8 = 53
+	blobColors        = []uint8{196, 208, 226, 46, 201, 214, 118, 129, 160, 220, 34, 171, 203, 154, 93, 166}
+)
+
+// xtermRGB returns the color of an xterm 256-color index from the 6x6x6
+// cube or the grayscale ramp; the 16 system colors vary by terminal and
+// aren't used.
+func xtermRGB(c uint8) color.RGBA {
+	if c >= 232 {
+		v := 8 + 10*(c-232)
+		return color.RGBA{v, v, v, 0xff}
+	}
+	levels := [6]uint8{0, 95, 135, 175, 215, 255}
+	c -= 16
+	return color.RGBA{levels[c/36], levels[c/6%6], levels[c%6], 0xff}
+}
+
+// mapEntry is one color of a square map: a kind of share, a blob
+// namespace or the parity quadrants, and how many shares it colors.
+type mapEntry struct {
+	Label     string           `json:"label"`
+	Namespace tmbytes.HexBytes `json:"namespace,omitempty"`
+	Color     string           `json:"color"`
+	Shares    int              `json:"shares"`
+	xterm     uint8
+}
+
+// squareMap is the original data square of a block, or with its parity
+// quadrants the extended square, as a grid of cells colored by namespace.
+type squareMap struct {
+	width int
+	// cells holds the index into legend of every cell, row by row.
+	cells  []int
+	legend []mapEntry
+}
+
+// newSquareMap maps the original data square of eds, or all of eds if
+// extended is set. Each reserved share kind has its own color, and each
+// blob namespace the next color of blobColors.
+func newSquareMap(eds *rsmt2d.ExtendedDataSquare, extended bool) (*squareMap, error) {
+	odsWidth := int(eds.Width() / 2)
+	m := &squareMap{width: odsWidth}
+	if extended {
+		m.width = int(eds.Width())
+	}
+	m.cells = make([]int, m.width*m.width)
+	index := make(map[string]int)
+	entry := func(key string, e mapEntry) int {
+		i, ok := index[key]
+		if !ok {
+			i = len(m.legend)
+			index[key] = i
+			rgb := xtermRGB(e.xterm)
+			e.Color = fmt.Sprintf("#%02x%02x%02x", rgb.R, rgb.G, rgb.B)
+			m.legend = append(m.legend, e)
+		}
+		m.legend[i].Shares++
+		return i
+	}
+	blobs := 0
+	for r := 0; r < odsWidth; r++ {
+		for c := 0; c < odsWidth; c++ {
+			s, err := libshare.NewShare(eds.GetCell(uint(r), uint(c)))
+			if err != nil {
+				return nil, fmt.Errorf("share (%d, %d): %w", r, c, err)
+			}
+			k := classifyShare(s)
+			if k != blobShare {
+				m.cells[r*m.width+c] = entry(k.String(), mapEntry{Label: k.String(), xterm: shareKindColors[k]})
+				continue
+			}
+			ns := s.Namespace().Bytes()
+			if _, ok := index[string(ns)]; !ok {
+				blobs++
+			}
+			m.cells[r*m.width+c] = entry(string(ns), mapEntry{
+				Label:     "blob",
+				Namespace: ns,
+				xterm:     blobColors[(blobs-1)%len(blobColors)],
+			})
+		}
+	}
+	// The parity quadrants come last, so that their entry follows those
+	// of the original data square
+	for r := 0; r < m.width; r++ {
+		for c := 0; c < m.width; c++ {
+			if r >= odsWidth || c >= odsWidth {
+				m.cells[r*m.width+c] = entry("parity", mapEntry{Label: "parity", xterm: parityColor})
+			}
+		}
+	}
+	return m, nil
+}
+
+func (m *squareMap) MarshalJSON() ([]byte, error) {
+	rows := make([][]int, m.width)
+	for r := range rows {
+		rows[r] = m.cells[r*m.width : (r+1)*m.width]
+	}
+	return json.Marshal(struct {
+		Width  int        `json:"width"`
+		Legend []mapEntry `json:"legend"`
+		Cells  [][]int    `json:"cells"`
+	}{m.width, m.legend, rows})
+}
+
+// String renders the map for a terminal with ANSI 256-color escapes. Each
+// character shows two rows of cells, the upper half block in the color of
+// the upper cell over the background color of the lower one, so that
+// cells come out roughly square.
+func (m *squareMap) String() string {
+	var b strings.Builder
+	for r := 0; r < m.width; r += 2 {
+		for c := 0; c < m.width; c++ {
+			upper := m.legend[m.cells[r*m.width+c]].xterm
+			if r+1 < m.width {
+				lower := m.legend[m.cells[(r+1)*m.width+c]].xterm
+				fmt.Fprintf(&b, "\x1b[38;5;%dm\x1b[48;5;%dm▀", upper, lower)
+			} else {
+				fmt.Fprintf(&b, "\x1b[38;5;%dm▀", upper)
+			}
+		}
+		b.WriteString("\x1b[0m\n")
+	}
+	for i, e := range m.legend {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		fmt.Fprintf(&b, "\x1b[38;5;%dm██\x1b[0m %s", e.xterm, e.Label)
+		if e.Namespace != nil {
+			fmt.Fprintf(&b, " %x", []byte(e.Namespace))
+		}
+		fmt.Fprintf(&b, ": %d shares", e.Shares)
+	}
+	return b.String()
+}
+
+// image renders the map with every cell a cellSize-pixel square.
+func (m *squareMap) image(cellSize int) image.Image {
+	side := m.width * cellSize
+	img := image.NewRGBA(image.Rect(0, 0, side, side))
+	for y := 0; y < side; y++ {
+		for x := 0; x < side; x++ {
+			img.SetRGBA(x, y, xtermRGB(m.legend[m.cells[(y/cellSize)*m.width+x/cellSize]].xterm))
+		}
+	}
+	return img
+}
+
+// writeSquareMapPNG writes the map as a PNG image to path.
+func writeSquareMapPNG(path string, m *squareMap, cellSize int) error {
+	f, err := createAtomicFile(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, m.image(cellSize)); err != nil {
+		f.abort()
+		return err
+	}
+	return f.commit()
+}
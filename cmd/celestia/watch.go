@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/adlerjohn/celestia-node-stateless/pkg/stateless"
+	"github.com/fsnotify/fsnotify"
+	"github.com/gogo/protobuf/proto"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+const (
+	// watchRetryInterval is how often pending block files are (re)tried.
+	watchRetryInterval = 500 * time.Millisecond
+	// watchMaxAttempts bounds how many times a file that fails to decode,
+	// extend or verify is retried before it is reported and dropped. Files
+	// still being written fail until the writer is done.
+	watchMaxAttempts = 20
+)
+
+// readBlockFile decodes a protobuf-encoded block from path.
+func readBlockFile(path string) (*types.Block, error) {
+	bz, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pbb := new(tmproto.Block)
+	if err := proto.Unmarshal(bz, pbb); err != nil {
+		return nil, err
+	}
+	return types.BlockFromProto(pbb)
+}
+
+// watchDir extends every protobuf-encoded block file found in or added to
+// dir and passes the resulting ExtendedHeader to emit, until ctx is done.
+// Blocks that become readable together are processed in height order.
+// Raw blocks carry no commit or validator set for their own height, so
+// those fields of the emitted headers are nil.
+func (s *session) watchDir(ctx context.Context, dir string, emit func(*stateless.ExtendedHeader) error) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+	if err := watcher.Add(dir); err != nil {
+		return err
+	}
+
+	// pending maps files not yet processed to their failed decode attempts
+	pending := make(map[string]int)
+	done := make(map[string]bool)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			pending[filepath.Join(dir, entry.Name())] = 0
+		}
+	}
+
+	// retry counts a failed attempt at the file at path, giving up on it
+	// once it has had watchMaxAttempts. A file is only done once its block
+	// extends and verifies, since a file still being written can decode
+	// when cut at a field boundary.
+	retry := func(path string, err error) {
+		attempts := pending[path] + 1
+		if attempts < watchMaxAttempts {
+			slog.Debug("retrying block file", "path", path, "attempts", attempts, "err", err)
+			pending[path] = attempts
+			return
+		}
+		slog.Error("giving up on block file", "path", path, "attempts", attempts, "err", err)
+		delete(pending, path)
+		done[path] = true
+	}
+
+	process := func() {
+		type blockFile struct {
+			path  string
+			block *types.Block
+		}
+		var files []blockFile
+		for path := range pending {
+			block, err := readBlockFile(path)
+			if err != nil {
+				retry(path, err)
+				continue
+			}
+			files = append(files, blockFile{path, block})
+		}
+		sort.Slice(files, func(i, j int) bool {
+			return files[i].block.Height < files[j].block.Height
+		})
+		for _, file := range files {
+			block := file.block
+			eds, err := s.extenderFor(s.appVersion(&block.Header)).Extend(&block.Data)
+			if err != nil {
+				retry(file.path, err)
+				continue
+			}
+			eh, err := s.extendedHeader(&block.Header, nil, nil, eds)
+			if err != nil {
+				retry(file.path, err)
+				continue
+			}
+			delete(pending, file.path)
+			done[file.path] = true
+			if err := emit(eh); err != nil {
+				slog.Error("processing block file failed", "path", file.path, "height", block.Height, "err", err)
+			}
+		}
+	}
+
+	ticker := time.NewTicker(watchRetryInterval)
+	defer ticker.Stop()
+	process()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if !event.Has(fsnotify.Create) && !event.Has(fsnotify.Write) {
+				continue
+			}
+			if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+				continue
+			}
+			if _, ok := pending[event.Name]; !ok && !done[event.Name] {
+				pending[event.Name] = 0
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			return err
+		case <-ticker.C:
+			process()
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adlerjohn/celestia-node-stateless/pkg/stateless"
+	"github.com/gogo/protobuf/proto"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+// TestWatchDirPartialWrite writes a block file in two chunks, the first
+// a block in its own right but for the wrong data, and checks the block is
+// emitted once the second is written.
+func TestWatchDirPartialWrite(t *testing.T) {
+	signed := testBlock(t, 7, []byte("transfer"), []byte("delegate"))
+	block := &types.Block{Header: *signed.Header, Data: *signed.Data, LastCommit: &types.Commit{}}
+	pbb, err := block.ToProto()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Protobuf fields can come in any order, and merge, so the block can
+	// be written as its header and last commit, which decode as a block of
+	// no data, followed by its data
+	first, err := proto.Marshal(&tmproto.Block{Header: pbb.Header, LastCommit: pbb.LastCommit})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := proto.Marshal(&tmproto.Block{Data: pbb.Data})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "7.pb")
+	if err := os.WriteFile(path, first, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readBlockFile(path); err != nil {
+		t.Fatalf("the first chunk doesn't decode on its own: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	emitted := make(chan *stateless.ExtendedHeader, 1)
+	watched := make(chan error, 1)
+	go func() {
+		watched <- testSession(t).watchDir(ctx, dir, func(eh *stateless.ExtendedHeader) error {
+			emitted <- eh
+			return nil
+		})
+	}()
+
+	// Let the first chunk fail to verify before the rest is written
+	time.Sleep(2 * watchRetryInterval)
+	select {
+	case eh := <-emitted:
+		t.Fatalf("emitted block %d from the first chunk", eh.Height)
+	default:
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write(second); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case eh := <-emitted:
+		if eh.Height != 7 || !bytes.Equal(eh.DAH.Hash(), signed.Header.DataHash) {
+			t.Errorf("emitted block %d with DAH hash %X, want block 7 with %X", eh.Height, eh.DAH.Hash(), signed.Header.DataHash)
+		}
+	case <-time.After(10 * watchRetryInterval):
+		t.Fatal("the completed block file was not emitted")
+	}
+	cancel()
+	if err := <-watched; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,65 @@
+package stateless
+
+import (
+	"log/slog"
+	"sync"
+
+	"github.com/celestiaorg/celestia-app/v3/pkg/appconsts"
+	v1 "github.com/celestiaorg/celestia-app/v3/pkg/appconsts/v1"
+	v2 "github.com/celestiaorg/celestia-app/v3/pkg/appconsts/v2"
+	v3 "github.com/celestiaorg/celestia-app/v3/pkg/appconsts/v3"
+)
+
+// squareConstants are the constants of an app version that shape the data
+// square of its blocks.
+type squareConstants struct {
+	squareSizeUpperBound int
+	subtreeRootThreshold int
+}
+
+// squareConstantsByVersion holds the square constants of every app version
+// this build of celestia-app defines, taken from the version's own package
+// rather than appconsts' accessors, which return the latest version's
+// constants for every version.
+var squareConstantsByVersion = map[uint64]squareConstants{
+	v1.Version: {v1.SquareSizeUpperBound, v1.SubtreeRootThreshold},
+	v2.Version: {v2.SquareSizeUpperBound, v2.SubtreeRootThreshold},
+	v3.Version: {v3.SquareSizeUpperBound, v3.SubtreeRootThreshold},
+}
+
+// warnedVersions records the unknown app versions already warned about.
+var warnedVersions sync.Map
+
+// constantsFor returns the square constants of appVersion. App versions
+// newer than the latest this build defines get the latest version's
+// constants, with a warning the first time, as celestia-app itself would
+// if it still handled them: if the constants changed, the square won't
+// match the block's data root, which MakeExtendedHeader and VerifyDAH
+// catch.
+func constantsFor(appVersion uint64) squareConstants {
+	c, ok := squareConstantsByVersion[appVersion]
+	if !ok {
+		if _, warned := warnedVersions.LoadOrStore(appVersion, true); !warned {
+			slog.Warn("unknown app version, extending under the square constants of the latest known one",
+				"app_version", appVersion, "latest_known", appconsts.LatestVersion)
+		}
+		c = squareConstantsByVersion[appconsts.LatestVersion]
+	}
+	// The compile-time override of celestia-app's test builds wins
+	if appconsts.OverrideSquareSizeUpperBoundStr != "" {
+		c.squareSizeUpperBound = appconsts.SquareSizeUpperBound(appVersion)
+	}
+	return c
+}
+
+// SquareSizeUpperBound returns the upper bound on the width of the
+// original data square of blocks of appVersion.
+func SquareSizeUpperBound(appVersion uint64) int {
+	return constantsFor(appVersion).squareSizeUpperBound
+}
+
+// SubtreeRootThreshold returns the subtree root threshold blob share
+// commitments of blocks of appVersion are computed with.
+func SubtreeRootThreshold(appVersion uint64) int {
+	return constantsFor(appVersion).subtreeRootThreshold
+}
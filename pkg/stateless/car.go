@@ -0,0 +1,447 @@
+package stateless
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/celestiaorg/celestia-app/v3/pkg/appconsts"
+	"github.com/celestiaorg/celestia-app/v3/pkg/da"
+	"github.com/celestiaorg/celestia-app/v3/pkg/wrapper"
+	libsquare "github.com/celestiaorg/go-square/v2"
+	libshare "github.com/celestiaorg/go-square/v2/share"
+	"github.com/celestiaorg/nmt"
+	"github.com/celestiaorg/rsmt2d"
+	"github.com/ipfs/go-cid"
+	mh "github.com/multiformats/go-multihash"
+)
+
+const (
+	// nmtCodec is the multicodec of NMT nodes in celestia-node's IPLD
+	// encoding.
+	nmtCodec = 0x7700
+	// sha256NamespaceFlagged is the multihash code celestia-node registers
+	// for namespaced SHA-256 NMT node hashes.
+	sha256NamespaceFlagged = 0x7701
+	// nmtHashSize is the size of an NMT node hash: the minimum and maximum
+	// namespace followed by the SHA-256 digest.
+	nmtHashSize = 2*libshare.NamespaceSize + 32
+	// carVersion is the CAR format version written and accepted.
+	carVersion = 1
+	// maxCARSection bounds the size of a single CAR section read back.
+	maxCARSection = 1 << 20
+)
+
+// nmtCid returns the CID celestia-node assigns to the NMT node with the
+// given hash.
+func nmtCid(hash []byte) (cid.Cid, error) {
+	if len(hash) != nmtHashSize {
+		return cid.Undef, fmt.Errorf("invalid NMT node hash size: got %d, expected %d", len(hash), nmtHashSize)
+	}
+	buf, err := mh.Encode(hash, sha256NamespaceFlagged)
+	if err != nil {
+		return cid.Undef, err
+	}
+	return cid.NewCidV1(nmtCodec, buf), nil
+}
+
+type carBlock struct {
+	cid  cid.Cid
+	data []byte
+}
+
+// WriteCAR writes eds to w as a CARv1 file in celestia-node's IPLD
+// encoding of the square. The roots are the DAH row roots followed by the
+// column roots. Leaves, the namespace-prefixed shares, come first in
+// quadrant order so the original data square sits at the start of the
+// file, followed by the inner nodes of every row and column tree.
+func WriteCAR(w io.Writer, eds *rsmt2d.ExtendedDataSquare) error {
+	width := eds.Width()
+	leaves := make([][]carBlock, width)
+	var inner []carBlock
+	seen := make(map[cid.Cid]bool)
+
+	var visitErr error
+	root := func(axis rsmt2d.Axis, index uint, shares [][]byte) ([]byte, error) {
+		// The row trees are built first and record every leaf by its
+		// position; the column trees share those leaves.
+		visit := func(hash []byte, children ...[]byte) {
+			if visitErr != nil || len(children) == 0 {
+				return
+			}
+			c, err := nmtCid(hash)
+			if err != nil {
+				visitErr = err
+				return
+			}
+			var data []byte
+			for _, child := range children {
+				data = append(data, child...)
+			}
+			if len(children) == 1 && axis == rsmt2d.Row {
+				leaves[index] = append(leaves[index], carBlock{c, data})
+			}
+			if len(children) == 2 && !seen[c] {
+				seen[c] = true
+				inner = append(inner, carBlock{c, data})
+			}
+		}
+		tree := wrapper.NewErasuredNamespacedMerkleTree(uint64(width/2), index, nmt.NodeVisitor(visit))
+		for _, sh := range shares {
+			if err := tree.Push(sh); err != nil {
+				return nil, err
+			}
+		}
+		r, err := tree.Root()
+		if err != nil {
+			return nil, err
+		}
+		return r, visitErr
+	}
+
+	var roots []cid.Cid
+	for _, axis := range []rsmt2d.Axis{rsmt2d.Row, rsmt2d.Col} {
+		for i := uint(0); i < width; i++ {
+			shares := eds.Row(i)
+			if axis == rsmt2d.Col {
+				shares = eds.Col(i)
+			}
+			r, err := root(axis, i, shares)
+			if err != nil {
+				return fmt.Errorf("%s %d: %w", axis, i, err)
+			}
+			c, err := nmtCid(r)
+			if err != nil {
+				return err
+			}
+			roots = append(roots, c)
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	if err := writeCARSection(bw, carHeader(roots)); err != nil {
+		return err
+	}
+	half := width / 2
+	for _, quadrant := range [][2]uint{{0, 0}, {0, half}, {half, 0}, {half, half}} {
+		for row := quadrant[0]; row < quadrant[0]+half; row++ {
+			for col := quadrant[1]; col < quadrant[1]+half; col++ {
+				b := leaves[row][col]
+				if err := writeCARSection(bw, b.cid.Bytes(), b.data); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	for _, b := range inner {
+		if err := writeCARSection(bw, b.cid.Bytes(), b.data); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// ReadCAR reads an EDS written by WriteCAR. Every block is checked against
+// its CID, the original data square is recovered by walking the row trees
+// from the roots, re-extended, and checked against the roots of the file.
+func ReadCAR(r io.Reader) (*rsmt2d.ExtendedDataSquare, error) {
+	br := bufio.NewReader(r)
+	header, err := readCARSection(br)
+	if err != nil {
+		return nil, fmt.Errorf("reading CAR header: %w", err)
+	}
+	roots, err := parseCARHeader(header)
+	if err != nil {
+		return nil, fmt.Errorf("reading CAR header: %w", err)
+	}
+	width := len(roots) / 2
+	if len(roots)%2 != 0 || !libsquare.IsPowerOfTwo(width) || width < 2 {
+		return nil, fmt.Errorf("CAR has %d roots, expected as many row as column roots for a square", len(roots))
+	}
+
+	hasher := nmt.NewNmtHasher(appconsts.NewBaseHashFunc(), libshare.NamespaceSize, true)
+	blocks := make(map[cid.Cid][]byte)
+	for {
+		section, err := readCARSection(br)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		n, c, err := cid.CidFromBytes(section)
+		if err != nil {
+			return nil, fmt.Errorf("reading CAR block: %w", err)
+		}
+		if err := verifyNMTBlock(hasher, c, section[n:]); err != nil {
+			return nil, err
+		}
+		blocks[c] = section[n:]
+	}
+
+	// The left half of the top rows is the original data square
+	var ods [][]byte
+	for row := 0; row < width/2; row++ {
+		rowLeaves, err := nmtLeaves(blocks, roots[row])
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", row, err)
+		}
+		if len(rowLeaves) != width {
+			return nil, fmt.Errorf("row %d: tree has %d leaves, expected %d", row, len(rowLeaves), width)
+		}
+		for _, leaf := range rowLeaves[:width/2] {
+			ods = append(ods, leaf[libshare.NamespaceSize:])
+		}
+	}
+
+	// A CAR file doesn't record the app version of its block
+	eds, err := ExtendShares(ods, appconsts.LatestVersion)
+	if err != nil {
+		return nil, err
+	}
+	dah, err := da.NewDataAvailabilityHeader(eds)
+	if err != nil {
+		return nil, err
+	}
+	for i, root := range append(append([][]byte{}, dah.RowRoots...), dah.ColumnRoots...) {
+		c, err := nmtCid(root)
+		if err != nil {
+			return nil, err
+		}
+		if !c.Equals(roots[i]) {
+			return nil, fmt.Errorf("extended square root %d is %s, CAR root is %s", i, c, roots[i])
+		}
+	}
+	return eds, nil
+}
+
+// verifyNMTBlock checks that data hashes to the NMT node c names.
+func verifyNMTBlock(hasher *nmt.NmtHasher, c cid.Cid, data []byte) error {
+	var (
+		hash []byte
+		err  error
+	)
+	if len(data) == 2*nmtHashSize {
+		hash, err = hasher.HashNode(data[:nmtHashSize], data[nmtHashSize:])
+	} else {
+		hash, err = hasher.HashLeaf(data)
+	}
+	if err != nil {
+		return fmt.Errorf("CAR block %s: %w", c, err)
+	}
+	expected, err := nmtCid(hash)
+	if err != nil {
+		return err
+	}
+	if !expected.Equals(c) {
+		return fmt.Errorf("CAR block %s does not match its data, which hashes to %s", c, expected)
+	}
+	return nil
+}
+
+// nmtLeaves returns the leaves, in order, of the NMT rooted at root.
+// Inner nodes hold the hashes of their two children; anything else is a
+// leaf.
+func nmtLeaves(blocks map[cid.Cid][]byte, root cid.Cid) ([][]byte, error) {
+	data, ok := blocks[root]
+	if !ok {
+		return nil, fmt.Errorf("missing CAR block %s", root)
+	}
+	if len(data) != 2*nmtHashSize {
+		return [][]byte{data}, nil
+	}
+	var leaves [][]byte
+	for _, hash := range [][]byte{data[:nmtHashSize], data[nmtHashSize:]} {
+		c, err := nmtCid(hash)
+		if err != nil {
+			return nil, err
+		}
+		sub, err := nmtLeaves(blocks, c)
+		if err != nil {
+			return nil, err
+		}
+		leaves = append(leaves, sub...)
+	}
+	return leaves, nil
+}
+
+func writeCARSection(w io.Writer, parts ...[]byte) error {
+	size := 0
+	for _, p := range parts {
+		size += len(p)
+	}
+	if _, err := w.Write(binary.AppendUvarint(nil, uint64(size))); err != nil {
+		return err
+	}
+	for _, p := range parts {
+		if _, err := w.Write(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readCARSection(r *bufio.Reader) ([]byte, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if size > maxCARSection {
+		return nil, fmt.Errorf("CAR section of %d bytes exceeds limit of %d", size, maxCARSection)
+	}
+	section := make([]byte, size)
+	if _, err := io.ReadFull(r, section); err != nil {
+		return nil, fmt.Errorf("reading CAR section: %w", io.ErrUnexpectedEOF)
+	}
+	return section, nil
+}
+
+// CBOR major types used by the DAG-CBOR CAR header.
+const (
+	cborUint  = 0
+	cborBytes = 2
+	cborText  = 3
+	cborArray = 4
+	cborMap   = 5
+	cborTag   = 6
+	// cborCidTag is the DAG-CBOR tag for a link.
+	cborCidTag = 42
+)
+
+func appendCBORHead(b []byte, major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return append(b, major<<5|byte(n))
+	case n <= 0xff:
+		return append(b, major<<5|24, byte(n))
+	case n <= 0xffff:
+		return binary.BigEndian.AppendUint16(append(b, major<<5|25), uint16(n))
+	case n <= 0xffffffff:
+		return binary.BigEndian.AppendUint32(append(b, major<<5|26), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, major<<5|27), n)
+	}
+}
+
+// carHeader encodes the CARv1 header {"roots": [...], "version": 1} in
+// canonical DAG-CBOR.
+func carHeader(roots []cid.Cid) []byte {
+	b := appendCBORHead(nil, cborMap, 2)
+	b = appendCBORHead(b, cborText, uint64(len("roots")))
+	b = append(b, "roots"...)
+	b = appendCBORHead(b, cborArray, uint64(len(roots)))
+	for _, c := range roots {
+		// DAG-CBOR links are byte strings behind a multibase identity prefix
+		b = appendCBORHead(b, cborTag, cborCidTag)
+		b = appendCBORHead(b, cborBytes, uint64(c.ByteLen()+1))
+		b = append(append(b, 0), c.Bytes()...)
+	}
+	b = appendCBORHead(b, cborText, uint64(len("version")))
+	b = append(b, "version"...)
+	return appendCBORHead(b, cborUint, carVersion)
+}
+
+func readCBORHead(r *bytes.Reader) (byte, uint64, error) {
+	first, err := r.ReadByte()
+	if err != nil {
+		return 0, 0, io.ErrUnexpectedEOF
+	}
+	major, info := first>>5, first&0x1f
+	if info < 24 {
+		return major, uint64(info), nil
+	}
+	if info > 27 {
+		return 0, 0, fmt.Errorf("unsupported CBOR additional info %d", info)
+	}
+	buf := make([]byte, 1<<(info-24))
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return 0, 0, io.ErrUnexpectedEOF
+	}
+	var n uint64
+	for _, c := range buf {
+		n = n<<8 | uint64(c)
+	}
+	return major, n, nil
+}
+
+func readCBORString(r *bytes.Reader, major byte) ([]byte, error) {
+	m, n, err := readCBORHead(r)
+	if err != nil {
+		return nil, err
+	}
+	if m != major || n > uint64(r.Len()) {
+		return nil, fmt.Errorf("malformed CBOR string")
+	}
+	s := make([]byte, n)
+	_, _ = r.Read(s)
+	return s, nil
+}
+
+// parseCARHeader decodes the roots of a CARv1 header.
+func parseCARHeader(header []byte) ([]cid.Cid, error) {
+	r := bytes.NewReader(header)
+	major, fields, err := readCBORHead(r)
+	if err != nil {
+		return nil, err
+	}
+	if major != cborMap {
+		return nil, errors.New("header is not a map")
+	}
+	var roots []cid.Cid
+	version := uint64(0)
+	for i := uint64(0); i < fields; i++ {
+		key, err := readCBORString(r, cborText)
+		if err != nil {
+			return nil, err
+		}
+		switch string(key) {
+		case "roots":
+			major, n, err := readCBORHead(r)
+			if err != nil {
+				return nil, err
+			}
+			if major != cborArray || n > uint64(r.Len()) {
+				return nil, errors.New("roots are not an array")
+			}
+			for j := uint64(0); j < n; j++ {
+				major, tag, err := readCBORHead(r)
+				if err != nil {
+					return nil, err
+				}
+				if major != cborTag || tag != cborCidTag {
+					return nil, fmt.Errorf("root %d is not a link", j)
+				}
+				link, err := readCBORString(r, cborBytes)
+				if err != nil {
+					return nil, err
+				}
+				if len(link) == 0 || link[0] != 0 {
+					return nil, fmt.Errorf("root %d is not a binary CID", j)
+				}
+				c, err := cid.Cast(link[1:])
+				if err != nil {
+					return nil, fmt.Errorf("root %d: %w", j, err)
+				}
+				roots = append(roots, c)
+			}
+		case "version":
+			major, n, err := readCBORHead(r)
+			if err != nil {
+				return nil, err
+			}
+			if major != cborUint {
+				return nil, errors.New("version is not an integer")
+			}
+			version = n
+		default:
+			return nil, fmt.Errorf("unexpected header field %q", key)
+		}
+	}
+	if version != carVersion {
+		return nil, fmt.Errorf("unsupported CAR version %d", version)
+	}
+	return roots, nil
+}
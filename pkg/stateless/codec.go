@@ -0,0 +1,101 @@
+package stateless
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/celestiaorg/celestia-app/v3/pkg/appconsts"
+	"github.com/celestiaorg/rsmt2d"
+	"github.com/klauspost/reedsolomon"
+)
+
+// PureGoLeopard is the name of the codec that computes the same Leopard
+// parity as rsmt2d's, without the assembly klauspost/reedsolomon uses on
+// CPUs that support it.
+const PureGoLeopard = "Leopard-purego"
+
+// codecs are the Reed-Solomon codecs a square can be extended with, by
+// name. Every one yields the parity celestia-app commits to, so they are
+// interchangeable for any block and differ only in speed.
+var codecs = map[string]func() rsmt2d.Codec{
+	rsmt2d.Leopard: func() rsmt2d.Codec { return appconsts.DefaultCodec() },
+	PureGoLeopard:  func() rsmt2d.Codec { return &pureGoLeopard{} },
+}
+
+// CodecNames returns the names CodecByName accepts, sorted.
+func CodecNames() []string {
+	names := make([]string, 0, len(codecs))
+	for name := range codecs {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// CodecByName returns the codec with the given name, one of CodecNames.
+func CodecByName(name string) (rsmt2d.Codec, error) {
+	newCodec, ok := codecs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown codec %q, expected one of %v", name, CodecNames())
+	}
+	return newCodec(), nil
+}
+
+// pureGoLeopard is rsmt2d.LeoRSCodec with every SIMD extension of
+// klauspost/reedsolomon turned off.
+type pureGoLeopard struct {
+	rsmt2d.LeoRSCodec
+	// encoders holds the reedsolomon.Encoder of each data shard count.
+	encoders sync.Map
+}
+
+func (c *pureGoLeopard) Encode(data [][]byte) ([][]byte, error) {
+	enc, err := c.encoder(len(data))
+	if err != nil {
+		return nil, err
+	}
+	shares := make([][]byte, 2*len(data))
+	copy(shares, data)
+	for i := len(data); i < len(shares); i++ {
+		shares[i] = make([]byte, len(data[0]))
+	}
+	if err := enc.Encode(shares); err != nil {
+		return nil, err
+	}
+	return shares[len(data):], nil
+}
+
+func (c *pureGoLeopard) Decode(data [][]byte) ([][]byte, error) {
+	enc, err := c.encoder(len(data) / 2)
+	if err != nil {
+		return nil, err
+	}
+	return data, enc.Reconstruct(data)
+}
+
+func (c *pureGoLeopard) Name() string {
+	return PureGoLeopard
+}
+
+// encoder returns the encoder of n data and n parity shards, built with the
+// options rsmt2d.LeoRSCodec uses and no SIMD.
+func (c *pureGoLeopard) encoder(n int) (reedsolomon.Encoder, error) {
+	if enc, ok := c.encoders.Load(n); ok {
+		return enc.(reedsolomon.Encoder), nil
+	}
+	enc, err := reedsolomon.New(n, n,
+		reedsolomon.WithLeopardGF(true),
+		reedsolomon.WithSSE2(false),
+		reedsolomon.WithSSSE3(false),
+		reedsolomon.WithAVX2(false),
+		reedsolomon.WithAVX512(false),
+		reedsolomon.WithGFNI(false),
+		reedsolomon.WithAVXGFNI(false),
+	)
+	if err != nil {
+		return nil, err
+	}
+	stored, _ := c.encoders.LoadOrStore(n, enc)
+	return stored.(reedsolomon.Encoder), nil
+}
@@ -0,0 +1,55 @@
+package stateless
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/celestiaorg/celestia-app/v3/pkg/da"
+	"github.com/tendermint/tendermint/types"
+)
+
+// TestCodecRoundTrip extends a block with every codec and reconstructs it
+// from its first quadrant with every codec, checking that they all yield
+// celestia-app's square.
+func TestCodecRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	data := &types.Data{Txs: randomTxs(t, rng)}
+	want, err := ExtendBlock(data, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, extendWith := range CodecNames() {
+		for _, repairWith := range CodecNames() {
+			t.Run(extendWith+" to "+repairWith, func(t *testing.T) {
+				codec, err := CodecByName(extendWith)
+				if err != nil {
+					t.Fatal(err)
+				}
+				eds, err := NewExtenderWithCodec(3, codec).Extend(data)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := dataRoot(t, eds); !bytes.Equal(got, dataRoot(t, want)) {
+					t.Fatalf("data root %X, want %X", got, dataRoot(t, want))
+				}
+				dah, err := da.NewDataAvailabilityHeader(eds)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if codec, err = CodecByName(repairWith); err != nil {
+					t.Fatal(err)
+				}
+				repaired, err := NewExtenderWithCodec(3, codec).Reconstruct(firstQuadrant(eds), &dah)
+				if err != nil {
+					t.Fatal(err)
+				}
+				// ExtendedDataSquare.Equals tells squares of different
+				// codecs apart, so compare the cells
+				if !bytes.Equal(bytes.Join(repaired.Flattened(), nil), bytes.Join(want.Flattened(), nil)) {
+					t.Error("reconstructed square differs from the extended one")
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package stateless
+
+import (
+	"fmt"
+
+	libsquare "github.com/celestiaorg/go-square/v2"
+)
+
+// ShareCoords is the position of a share in an extended square of
+// original width squareSize, whose cells are indexed row-major from 0 to
+// (2*squareSize)^2 - 1.
+type ShareCoords struct {
+	Index int `json:"index"`
+	Row   int `json:"row"`
+	Col   int `json:"col"`
+	// Quadrant is the quadrant of the extended square holding the share,
+	// numbered row-major: 0 is the original data square, 1 and 2 its row
+	// and column parity, and 3 the parity of parity.
+	Quadrant int `json:"quadrant"`
+}
+
+// Original reports whether the share is in the original data square.
+func (c ShareCoords) Original() bool {
+	return c.Quadrant == 0
+}
+
+func (c ShareCoords) String() string {
+	kind := "parity"
+	if c.Original() {
+		kind = "original"
+	}
+	return fmt.Sprintf("index %d: row %d, col %d, quadrant %d (%s)", c.Index, c.Row, c.Col, c.Quadrant, kind)
+}
+
+// CoordsFromIndex returns the coordinates of the share at idx in the
+// extended square of a squareSize-wide original data square.
+func CoordsFromIndex(idx, squareSize int) (ShareCoords, error) {
+	if err := checkSquareSize(squareSize); err != nil {
+		return ShareCoords{}, err
+	}
+	width := 2 * squareSize
+	if idx < 0 || idx >= width*width {
+		return ShareCoords{}, fmt.Errorf("index %d out of range for %d-wide extended square", idx, width)
+	}
+	return newShareCoords(idx/width, idx%width, squareSize), nil
+}
+
+// IndexFromCoords returns the index of the share at (row, col) in the
+// extended square of a squareSize-wide original data square.
+func IndexFromCoords(row, col, squareSize int) (int, error) {
+	if err := checkSquareSize(squareSize); err != nil {
+		return 0, err
+	}
+	width := 2 * squareSize
+	if row < 0 || row >= width || col < 0 || col >= width {
+		return 0, fmt.Errorf("cell (%d, %d) out of range for %d-wide extended square", row, col, width)
+	}
+	return row*width + col, nil
+}
+
+func newShareCoords(row, col, squareSize int) ShareCoords {
+	quadrant := 0
+	if row >= squareSize {
+		quadrant += 2
+	}
+	if col >= squareSize {
+		quadrant++
+	}
+	return ShareCoords{Index: row*2*squareSize + col, Row: row, Col: col, Quadrant: quadrant}
+}
+
+func checkSquareSize(squareSize int) error {
+	if squareSize <= 0 || !libsquare.IsPowerOfTwo(squareSize) {
+		return fmt.Errorf("%w: square size %d", ErrNotPowerOfTwo, squareSize)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package stateless
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCoordsFromIndex(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		idx        int
+		squareSize int
+		want       ShareCoords
+	}{
+		{"first share", 0, 2, ShareCoords{Index: 0, Row: 0, Col: 0, Quadrant: 0}},
+		{"last original column", 1, 2, ShareCoords{Index: 1, Row: 0, Col: 1, Quadrant: 0}},
+		{"first row parity column", 2, 2, ShareCoords{Index: 2, Row: 0, Col: 2, Quadrant: 1}},
+		{"end of first row", 3, 2, ShareCoords{Index: 3, Row: 0, Col: 3, Quadrant: 1}},
+		{"last original share", 5, 2, ShareCoords{Index: 5, Row: 1, Col: 1, Quadrant: 0}},
+		{"first column parity row", 8, 2, ShareCoords{Index: 8, Row: 2, Col: 0, Quadrant: 2}},
+		{"last column parity share", 13, 2, ShareCoords{Index: 13, Row: 3, Col: 1, Quadrant: 2}},
+		{"first parity of parity", 10, 2, ShareCoords{Index: 10, Row: 2, Col: 2, Quadrant: 3}},
+		{"last share", 15, 2, ShareCoords{Index: 15, Row: 3, Col: 3, Quadrant: 3}},
+		{"minimal square", 3, 1, ShareCoords{Index: 3, Row: 1, Col: 1, Quadrant: 3}},
+		{"largest square", 256*256 - 1, 128, ShareCoords{Index: 256*256 - 1, Row: 255, Col: 255, Quadrant: 3}},
+		{"largest square quadrant edge", 127*256 + 128, 128, ShareCoords{Index: 127*256 + 128, Row: 127, Col: 128, Quadrant: 1}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := CoordsFromIndex(tc.idx, tc.squareSize)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("CoordsFromIndex(%d, %d) = %+v, want %+v", tc.idx, tc.squareSize, got, tc.want)
+			}
+			if got.Original() != (tc.want.Quadrant == 0) {
+				t.Errorf("Original() = %t in quadrant %d", got.Original(), got.Quadrant)
+			}
+			idx, err := IndexFromCoords(got.Row, got.Col, tc.squareSize)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if idx != tc.idx {
+				t.Errorf("IndexFromCoords(%d, %d, %d) = %d, want %d", got.Row, got.Col, tc.squareSize, idx, tc.idx)
+			}
+		})
+	}
+}
+
+func TestCoordsFromIndexOutOfRange(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		idx        int
+		squareSize int
+		// notPowerOfTwo is whether the error is for the square size.
+		notPowerOfTwo bool
+	}{
+		{"negative index", -1, 2, false},
+		{"one past the last share", 16, 2, false},
+		{"zero square size", 0, 0, true},
+		{"negative square size", 0, -2, true},
+		{"square size not a power of 2", 0, 3, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := CoordsFromIndex(tc.idx, tc.squareSize)
+			if err == nil {
+				t.Fatal("no error")
+			}
+			if got := errors.Is(err, ErrNotPowerOfTwo); got != tc.notPowerOfTwo {
+				t.Errorf("error %q wraps ErrNotPowerOfTwo: %t, want %t", err, got, tc.notPowerOfTwo)
+			}
+		})
+	}
+}
+
+func TestIndexFromCoordsOutOfRange(t *testing.T) {
+	for _, tc := range []struct {
+		name                 string
+		row, col, squareSize int
+	}{
+		{"negative row", -1, 0, 2},
+		{"negative column", 0, -1, 2},
+		{"row past the square", 4, 0, 2},
+		{"column past the square", 0, 4, 2},
+		{"square size not a power of 2", 0, 0, 6},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if idx, err := IndexFromCoords(tc.row, tc.col, tc.squareSize); err == nil {
+				t.Fatalf("IndexFromCoords(%d, %d, %d) = %d, want an error", tc.row, tc.col, tc.squareSize, idx)
+			}
+		})
+	}
+}
@@ -0,0 +1,802 @@
+// Package stateless fetches blocks from a celestia-core node and rebuilds
+// their extended data square and ExtendedHeader locally, without running a
+// celestia node.
+package stateless
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	signaltypes "github.com/celestiaorg/celestia-app/v3/x/signal/types"
+	"github.com/gogo/protobuf/proto"
+	"github.com/tendermint/tendermint/crypto/merkle"
+	"github.com/tendermint/tendermint/crypto/tmhash"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	coregrpc "github.com/tendermint/tendermint/rpc/grpc"
+	"github.com/tendermint/tendermint/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
+)
+
+// SignedBlock is a block fetched from core together with the commit and
+// validator set for its height.
+type SignedBlock struct {
+	Header       *types.Header       `json:"header"`
+	Commit       *types.Commit       `json:"commit"`
+	Data         *types.Data         `json:"data"`
+	ValidatorSet *types.ValidatorSet `json:"validator_set"`
+}
+
+// CoreAccessor fetches blocks from one or more celestia-core gRPC
+// endpoints, failing over to the next when one is unavailable. It holds
+// the connections to the endpoints open until Close is called.
+type CoreAccessor struct {
+	endpoints []coreEndpoint
+	// preferred is the index of the endpoint that last served a request,
+	// which the next request tries first.
+	preferred atomic.Int32
+	// partTimeout bounds the wait for each streamed block part when
+	// positive.
+	partTimeout time.Duration
+	// sequential turns off the pipelined reassembly of streamed blocks.
+	sequential bool
+}
+
+// coreEndpoint is the connection to one core gRPC endpoint.
+type coreEndpoint struct {
+	addr   string
+	conn   *grpc.ClientConn
+	client coregrpc.BlockAPIClient
+	// signal queries the app's signal module, where the endpoint serves
+	// the app's gRPC services besides core's.
+	signal signaltypes.QueryClient
+}
+
+// DefaultPartTimeout is how long a CoreAccessor waits for each part of a
+// streamed block unless SetPartTimeout says otherwise.
+const DefaultPartTimeout = 10 * time.Second
+
+// DefaultMaxRecvMsgSize is the largest message in bytes a CoreAccessor
+// accepts from core unless WithMaxRecvMsgSize says otherwise. It is well
+// above gRPC's own 4 MiB default, which large blocks can exceed.
+const DefaultMaxRecvMsgSize = 64 << 20
+
+// WithMaxRecvMsgSize returns a dial option raising or lowering the largest
+// message in bytes accepted from core, for NewCoreAccessor.
+func WithMaxRecvMsgSize(size int) grpc.DialOption {
+	return grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(size))
+}
+
+// Default keepalive parameters for WithKeepalive. They stay within what
+// gRPC servers accept by default, which close connections that ping more
+// often than every 5 minutes, or at all without an active stream.
+const (
+	DefaultKeepaliveTime    = 5 * time.Minute
+	DefaultKeepaliveTimeout = 20 * time.Second
+)
+
+// WithKeepalive returns a dial option that pings core after interval
+// without activity and drops the connection if the ping isn't answered
+// within timeout, so that a connection dropped by a load balancer is
+// noticed before the next fetch. Without permitWithoutStream no pings are
+// sent while no fetch is in progress.
+func WithKeepalive(interval, timeout time.Duration, permitWithoutStream bool) grpc.DialOption {
+	return grpc.WithKeepaliveParams(keepalive.ClientParameters{
+		Time:                interval,
+		Timeout:             timeout,
+		PermitWithoutStream: permitWithoutStream,
+	})
+}
+
+// NewCoreAccessor connects to the core gRPC endpoint at ip, or to each of
+// a comma-separated list of endpoints, which are then tried in order. The
+// connections are insecure and accept messages of up to
+// DefaultMaxRecvMsgSize bytes unless extraOpts say otherwise.
+func NewCoreAccessor(ip string, extraOpts ...grpc.DialOption) (*CoreAccessor, error) {
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		WithMaxRecvMsgSize(DefaultMaxRecvMsgSize),
+	}
+	opts = append(opts, extraOpts...)
+	c := &CoreAccessor{partTimeout: DefaultPartTimeout}
+	for _, addr := range strings.Split(ip, ",") {
+		addr = strings.TrimSpace(addr)
+		if addr == "" {
+			continue
+		}
+		conn, err := grpc.NewClient(addr, opts...)
+		if err != nil {
+			c.Close()
+			return nil, fmt.Errorf("core endpoint %s: %w", addr, err)
+		}
+		c.endpoints = append(c.endpoints, coreEndpoint{addr, conn, coregrpc.NewBlockAPIClient(conn), signaltypes.NewQueryClient(conn)})
+	}
+	if len(c.endpoints) == 0 {
+		return nil, errors.New("no core endpoint given")
+	}
+	return c, nil
+}
+
+// SetPartTimeout sets how long to wait for each part of a streamed block
+// before giving up on the fetch. Zero waits for as long as the fetch's
+// context allows.
+func (c *CoreAccessor) SetPartTimeout(d time.Duration) {
+	c.partTimeout = d
+}
+
+// SetSequentialReassembly makes the accessor receive every part of a
+// streamed block before reassembling it, instead of reassembling parts as
+// they arrive. It is a fallback should the pipelined reassembly misbehave.
+func (c *CoreAccessor) SetSequentialReassembly(sequential bool) {
+	c.sequential = sequential
+}
+
+// Close closes the connections to the core endpoints.
+func (c *CoreAccessor) Close() error {
+	var errs []error
+	for _, ep := range c.endpoints {
+		if err := ep.conn.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", ep.addr, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// withEndpoints calls fn with the client of each endpoint in turn,
+// starting with the preferred one, until a call doesn't fail with
+// codes.Unavailable. The endpoint that served the call becomes the
+// preferred one. If every endpoint is unavailable, the error lists the
+// failure of each.
+func (c *CoreAccessor) withEndpoints(fn func(client coregrpc.BlockAPIClient) error) error {
+	return c.withEndpoint(func(ep *coreEndpoint) error {
+		return fn(ep.client)
+	})
+}
+
+// withEndpoint is withEndpoints for calls other than to core's BlockAPI.
+// Failures that may succeed when retried are wrapped in a RetriableError.
+func (c *CoreAccessor) withEndpoint(fn func(ep *coreEndpoint) error) error {
+	return retriable(c.tryEndpoints(fn))
+}
+
+// tryEndpoints does the work of withEndpoint.
+func (c *CoreAccessor) tryEndpoints(fn func(ep *coreEndpoint) error) error {
+	first := int(c.preferred.Load())
+	errs := make([]error, 0, len(c.endpoints))
+	for i := range c.endpoints {
+		idx := (first + i) % len(c.endpoints)
+		ep := &c.endpoints[idx]
+		err := fn(ep)
+		if status.Code(err) != codes.Unavailable {
+			if err == nil {
+				c.preferred.Store(int32(idx))
+			}
+			return err
+		}
+		if len(c.endpoints) == 1 {
+			return err
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", ep.addr, err))
+		if i < len(c.endpoints)-1 {
+			slog.Info("core endpoint unavailable, trying the next", "address", ep.addr, "err", err)
+		}
+	}
+	return fmt.Errorf("all %d core endpoints are unavailable: %w", len(errs), endpointErrors(errs))
+}
+
+// retriable wraps err in a RetriableError if the call that failed may
+// succeed when retried: it ran out of time, as a request past its deadline
+// or a stream stalled past the part timeout does, or core was unavailable.
+func retriable(err error) error {
+	if err == nil || IsRetriable(err) {
+		return err
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.As(err, new(endpointErrors)) {
+		return &RetriableError{Err: err}
+	}
+	switch status.Code(err) {
+	case codes.DeadlineExceeded, codes.Unavailable:
+		return &RetriableError{Err: err}
+	default:
+		return err
+	}
+}
+
+// endpointErrors are the failures of several endpoints, listed on one line.
+type endpointErrors []error
+
+func (e endpointErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e endpointErrors) Unwrap() []error {
+	return e
+}
+
+// isNotFound reports whether err is core saying it has no such block. Core
+// answers requests for heights it doesn't have with an unclassified "nil
+// block meta" error rather than NotFound.
+func isNotFound(err error) bool {
+	if err == nil {
+		return false
+	}
+	st := status.Convert(err)
+	return st.Code() == codes.NotFound ||
+		st.Code() == codes.Unknown && strings.Contains(st.Message(), "nil block meta")
+}
+
+// latestHeight is the height argument that selects the chain tip.
+const latestHeight = "latest"
+
+// NodeStatus is the state of a core node as reported by its status API.
+type NodeStatus struct {
+	ChainID         string    `json:"chain_id"`
+	LatestHeight    int64     `json:"latest_height"`
+	LatestBlockTime time.Time `json:"latest_block_time"`
+	// EarliestHeight is the oldest block the node still has, above 1 once
+	// it pruned older ones.
+	EarliestHeight int64 `json:"earliest_height"`
+	CatchingUp     bool  `json:"catching_up"`
+}
+
+// Status queries the status of the core node.
+func (c *CoreAccessor) Status(ctx context.Context) (*NodeStatus, error) {
+	var status *coregrpc.StatusResponse
+	err := c.withEndpoints(func(client coregrpc.BlockAPIClient) error {
+		var err error
+		status, err = client.Status(ctx, &coregrpc.StatusRequest{})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if status.SyncInfo == nil {
+		return nil, errors.New("core status has no sync info")
+	}
+	return &NodeStatus{
+		ChainID:         status.GetNodeInfo().GetNetwork(),
+		LatestHeight:    status.SyncInfo.LatestBlockHeight,
+		LatestBlockTime: status.SyncInfo.LatestBlockTime,
+		EarliestHeight:  status.SyncInfo.EarliestBlockHeight,
+		CatchingUp:      status.SyncInfo.CatchingUp,
+	}, nil
+}
+
+// LatestHeight returns the height of the newest block known to the core
+// node. It fails if the node reports that it is still catching up, since
+// its tip is then not the chain's.
+func (c *CoreAccessor) LatestHeight(ctx context.Context) (int64, error) {
+	status, err := c.Status(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if status.CatchingUp {
+		return 0, fmt.Errorf("core node is still syncing, at height %d", status.LatestHeight)
+	}
+	return status.LatestHeight, nil
+}
+
+// EarliestHeight returns the height of the oldest block the core node
+// still has.
+func (c *CoreAccessor) EarliestHeight(ctx context.Context) (int64, error) {
+	status, err := c.Status(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return status.EarliestHeight, nil
+}
+
+// Upgrade is an app version upgrade scheduled by the chain.
+type Upgrade struct {
+	AppVersion uint64 `json:"app_version"`
+	Height     int64  `json:"height"`
+}
+
+// ScheduledUpgrade returns the app version upgrade the chain has scheduled,
+// as the app's signal module reports it now, or nil if none is. Only
+// endpoints that also serve the app's gRPC services can answer; others
+// fail with codes.Unimplemented, as do apps older than version 2, which
+// have no signal module.
+func (c *CoreAccessor) ScheduledUpgrade(ctx context.Context) (*Upgrade, error) {
+	var resp *signaltypes.QueryGetUpgradeResponse
+	err := c.withEndpoint(func(ep *coreEndpoint) error {
+		var err error
+		resp, err = ep.signal.GetUpgrade(ctx, &signaltypes.QueryGetUpgradeRequest{})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Upgrade == nil {
+		return nil, nil
+	}
+	return &Upgrade{AppVersion: resp.Upgrade.AppVersion, Height: resp.Upgrade.UpgradeHeight}, nil
+}
+
+// BlockHash returns the hash of the block core has at the given height, as
+// recorded by the block's commit. It is much cheaper than fetching the
+// block.
+func (c *CoreAccessor) BlockHash(ctx context.Context, height int64) ([]byte, error) {
+	var resp *coregrpc.CommitResponse
+	err := c.withEndpoints(func(client coregrpc.BlockAPIClient) error {
+		var err error
+		resp, err = client.Commit(ctx, &coregrpc.CommitRequest{Height: height})
+		return err
+	})
+	if isNotFound(err) {
+		return nil, fmt.Errorf("%w at height %d: %w", ErrBlockNotFound, height, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if resp.Commit == nil {
+		return nil, fmt.Errorf("core returned no commit for height %d", height)
+	}
+	return resp.Commit.BlockID.Hash, nil
+}
+
+// GetSignedBlock fetches the block at height h, given in decimal or as
+// "latest" for the chain tip. The fetch, including the streaming of every
+// block part, is aborted once ctx is done.
+func (c *CoreAccessor) GetSignedBlock(ctx context.Context, h string) (*SignedBlock, error) {
+	height, err := c.resolveHeight(ctx, h)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	block, err := c.fetchBlock(ctx, c.openByHeight(height), c.blockReceiver())
+	if isNotFound(err) {
+		return nil, fmt.Errorf("%w at height %d: %w", ErrBlockNotFound, height, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	observeStage(StageFetch, start, 0)
+	slog.Debug("fetched block", "height", height, "duration", time.Since(start))
+	return block, nil
+}
+
+// GetSignedHeader fetches the header, commit and validator set of the
+// block at height h, like GetSignedBlock, leaving the returned block's
+// Data nil. core has no API for the header alone, so the block is streamed
+// as usual, but only until its header has arrived: the header is encoded
+// first, so usually only the first part is received, and the block data
+// is never reassembled.
+func (c *CoreAccessor) GetSignedHeader(ctx context.Context, h string) (*SignedBlock, error) {
+	height, err := c.resolveHeight(ctx, h)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	block, err := c.fetchBlock(ctx, c.openByHeight(height), receiveHeader)
+	if isNotFound(err) {
+		return nil, fmt.Errorf("%w at height %d: %w", ErrBlockNotFound, height, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	slog.Debug("fetched header", "height", height, "duration", time.Since(start))
+	return block, nil
+}
+
+// resolveHeight parses the height h, given in decimal or as "latest" for
+// the chain tip.
+func (c *CoreAccessor) resolveHeight(ctx context.Context, h string) (int64, error) {
+	if h == latestHeight {
+		return c.LatestHeight(ctx)
+	}
+	height, err := strconv.Atoi(h)
+	if err != nil {
+		return 0, err
+	}
+	return int64(height), nil
+}
+
+// openByHeight opens the stream of the block at height.
+func (c *CoreAccessor) openByHeight(height int64) func(context.Context, coregrpc.BlockAPIClient) (blockStream, error) {
+	return func(ctx context.Context, client coregrpc.BlockAPIClient) (blockStream, error) {
+		stream, err := client.BlockByHeight(ctx, &coregrpc.BlockByHeightRequest{Height: height})
+		if err != nil {
+			return nil, err
+		}
+		return func() (streamedBlockPart, error) { return stream.Recv() }, nil
+	}
+}
+
+// GetSignedBlockByHash fetches the block with the given hex-encoded hash.
+func (c *CoreAccessor) GetSignedBlockByHash(ctx context.Context, h string) (*SignedBlock, error) {
+	hash, err := hex.DecodeString(h)
+	if err != nil {
+		return nil, fmt.Errorf("invalid block hash: %w", err)
+	}
+	if len(hash) != tmhash.Size {
+		return nil, fmt.Errorf("invalid block hash: got %d bytes, expected %d", len(hash), tmhash.Size)
+	}
+
+	start := time.Now()
+	block, err := c.fetchBlock(ctx, func(ctx context.Context, client coregrpc.BlockAPIClient) (blockStream, error) {
+		stream, err := client.BlockByHash(ctx, &coregrpc.BlockByHashRequest{Hash: hash})
+		if err != nil {
+			return nil, err
+		}
+		return func() (streamedBlockPart, error) { return stream.Recv() }, nil
+	}, c.blockReceiver())
+	if isNotFound(err) {
+		return nil, fmt.Errorf("%w with hash %X: %w", ErrBlockNotFound, hash, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if got := block.Header.Hash(); !bytes.Equal(got, hash) {
+		return nil, fmt.Errorf("core returned block %X for hash %X", got, hash)
+	}
+	observeStage(StageFetch, start, 0)
+	slog.Debug("fetched block", "hash", h, "height", block.Header.Height, "duration", time.Since(start))
+	return block, nil
+}
+
+// blockStream receives the next part of a streamed block.
+type blockStream func() (streamedBlockPart, error)
+
+// receiveFunc receives a block, or what is needed of it, from the parts
+// returned by recv, calling cancel to abort the stream should a part take
+// longer than partTimeout to arrive.
+type receiveFunc func(
+	ctx context.Context,
+	cancel context.CancelFunc,
+	partTimeout time.Duration,
+	recv func() (streamedBlockPart, error),
+) (*SignedBlock, error)
+
+// blockReceiver returns the receiveFunc reassembling whole blocks.
+func (c *CoreAccessor) blockReceiver() receiveFunc {
+	if c.sequential {
+		return receiveBlock
+	}
+	return receiveBlockPipelined
+}
+
+// fetchBlock opens a block stream to an endpoint with open and receives
+// the block it streams with receive, failing over to the next endpoint if
+// the stream can't be opened or breaks off because the endpoint became
+// unavailable. The stream is closed once receive returns.
+func (c *CoreAccessor) fetchBlock(
+	ctx context.Context,
+	open func(ctx context.Context, client coregrpc.BlockAPIClient) (blockStream, error),
+	receive receiveFunc,
+) (*SignedBlock, error) {
+	var block *SignedBlock
+	err := c.withEndpoints(func(client coregrpc.BlockAPIClient) error {
+		// Cancelling closes the stream should receiving fail part way
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		recv, err := open(ctx, client)
+		if err != nil {
+			return err
+		}
+		block, err = receive(ctx, cancel, c.partTimeout, recv)
+		return err
+	})
+	return block, err
+}
+
+// streamedBlockPart is a part of a block streamed by height or by hash.
+type streamedBlockPart interface {
+	GetBlockPart() *tmproto.Part
+	GetCommit() *tmproto.Commit
+	GetValidatorSet() *tmproto.ValidatorSet
+	GetIsLast() bool
+}
+
+// receiveBlock reassembles a block from the parts returned by recv and
+// checks that the streamed commit is for its header, and that the parts
+// are all those of the part set the commit names. If a part takes
+// longer than partTimeout to arrive, cancel is called to abort the stream
+// that recv receives from.
+func receiveBlock(
+	ctx context.Context,
+	cancel context.CancelFunc,
+	partTimeout time.Duration,
+	recv func() (streamedBlockPart, error),
+) (*SignedBlock, error) {
+	parts := make([]*tmproto.Part, 0)
+	if partTimeout > 0 {
+		recv = recvWithin(partTimeout, cancel, func() int { return len(parts) }, recv)
+	}
+
+	firstPart, commit, validatorSet, err := receiveFirstPart(recv)
+	if err != nil {
+		return nil, err
+	}
+	parts = append(parts, firstPart.GetBlockPart())
+
+	// receive the rest of the block
+	isLast := firstPart.GetIsLast()
+	for !isLast {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		resp, err := recv()
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, resp.GetBlockPart())
+		isLast = resp.GetIsLast()
+	}
+	slog.Debug("received block parts", "parts", len(parts))
+	start := time.Now()
+	block, err := partsToBlock(parts, commit.BlockID.PartSetHeader)
+	if err != nil {
+		return nil, err
+	}
+	observeStage(StageReassemble, start, 0)
+	return signedBlock(block, commit, validatorSet)
+}
+
+// receiveFirstPart receives the first part of a streamed block, which
+// alone carries the block's commit and validator set.
+func receiveFirstPart(recv func() (streamedBlockPart, error)) (streamedBlockPart, *types.Commit, *types.ValidatorSet, error) {
+	firstPart, err := recv()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	commit, err := types.CommitFromProto(firstPart.GetCommit())
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	validatorSet, err := types.ValidatorSetFromProto(firstPart.GetValidatorSet())
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return firstPart, commit, validatorSet, nil
+}
+
+// signedBlock returns block with its streamed commit and validator set,
+// checking that the commit is for the block's header.
+func signedBlock(block *types.Block, commit *types.Commit, validatorSet *types.ValidatorSet) (*SignedBlock, error) {
+	// Catch a commit spliced onto another header before anything is
+	// derived from the block
+	if !bytes.Equal(commit.BlockID.Hash, block.Header.Hash()) {
+		return nil, fmt.Errorf("%w: commit is for block %X, but the header of block %d hashes to %X",
+			ErrCommitVerification, commit.BlockID.Hash, block.Header.Height, block.Header.Hash())
+	}
+	return &SignedBlock{
+		Header:       &block.Header,
+		Commit:       commit,
+		Data:         &block.Data,
+		ValidatorSet: validatorSet,
+	}, nil
+}
+
+// receiveHeader receives the parts returned by recv until the block's
+// header has arrived, and returns it with the commit and validator set
+// streamed with the first part, checking the commit is for the header.
+func receiveHeader(
+	ctx context.Context,
+	cancel context.CancelFunc,
+	partTimeout time.Duration,
+	recv func() (streamedBlockPart, error),
+) (*SignedBlock, error) {
+	parts := 0
+	if partTimeout > 0 {
+		recv = recvWithin(partTimeout, cancel, func() int { return parts }, recv)
+	}
+
+	firstPart, commit, validatorSet, err := receiveFirstPart(recv)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		buf    []byte
+		header *types.Header
+	)
+	for part := firstPart; ; {
+		if index := part.GetBlockPart().GetIndex(); int(index) != parts {
+			return nil, fmt.Errorf("%w: block part %d streamed out of order, expected part %d", ErrInvalidBlockParts, index, parts)
+		}
+		parts++
+		buf = append(buf, part.GetBlockPart().GetBytes()...)
+		if header, err = headerFromPrefix(buf); err != nil {
+			return nil, err
+		} else if header != nil {
+			break
+		}
+		if part.GetIsLast() {
+			return nil, fmt.Errorf("%w: block of %d parts ends before its header", ErrInvalidBlockParts, parts)
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if part, err = recv(); err != nil {
+			return nil, err
+		}
+	}
+	slog.Debug("received block header", "parts", parts)
+	if !bytes.Equal(commit.BlockID.Hash, header.Hash()) {
+		return nil, fmt.Errorf("%w: commit is for block %X, but the header of block %d hashes to %X",
+			ErrCommitVerification, commit.BlockID.Hash, header.Height, header.Hash())
+	}
+	return &SignedBlock{
+		Header:       header,
+		Commit:       commit,
+		ValidatorSet: validatorSet,
+	}, nil
+}
+
+// headerFromPrefix decodes the header of a block from bz, the start of
+// the block's protobuf encoding, whose first field is the header. It
+// returns nil without error if bz ends before the header does.
+func headerFromPrefix(bz []byte) (*types.Header, error) {
+	if len(bz) == 0 {
+		return nil, nil
+	}
+	// The tag of field 1, length-delimited
+	if bz[0] != 1<<3|2 {
+		return nil, errors.New("block does not start with its header")
+	}
+	size, n := binary.Uvarint(bz[1:])
+	switch {
+	case n == 0:
+		return nil, nil
+	case n < 0:
+		return nil, errors.New("block header length overflows")
+	}
+	start := 1 + n
+	if uint64(len(bz)-start) < size {
+		return nil, nil
+	}
+	pbh := new(tmproto.Header)
+	if err := proto.Unmarshal(bz[start:start+int(size)], pbh); err != nil {
+		return nil, err
+	}
+	header, err := types.HeaderFromProto(pbh)
+	if err != nil {
+		return nil, err
+	}
+	return &header, nil
+}
+
+// recvWithin wraps recv so that a call cancels the stream, through cancel,
+// once no part has arrived for timeout. The resulting error reports how
+// many parts, as counted by received, made it before the stall.
+func recvWithin(
+	timeout time.Duration,
+	cancel context.CancelFunc,
+	received func() int,
+	recv func() (streamedBlockPart, error),
+) func() (streamedBlockPart, error) {
+	var stalled atomic.Bool
+	return func() (streamedBlockPart, error) {
+		timer := time.AfterFunc(timeout, func() {
+			stalled.Store(true)
+			cancel()
+		})
+		defer timer.Stop()
+		part, err := recv()
+		if err != nil && stalled.Load() {
+			return nil, fmt.Errorf("no block part received for %s, %d received before the stall: %w",
+				timeout, received(), context.DeadlineExceeded)
+		}
+		return part, err
+	}
+}
+
+// blockBufs holds the buffers partsToBlock reassembles blocks into, reused
+// across blocks so that fetching many does not allocate a block-sized
+// buffer for each.
+var blockBufs = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+
+// partsToBlock takes a slice of parts and generates the corresponding block.
+// The parts must be all of those of the part set described by header, so
+// that a stream ending early isn't taken for a smaller block. It empties
+// the slice to optimize the memory usage.
+func partsToBlock(parts []*tmproto.Part, header types.PartSetHeader) (*types.Block, error) {
+	if got := uint32(len(parts)); got != header.Total {
+		return nil, fmt.Errorf("%w: stream ended after %d block parts, the commit's part set has %d", ErrInvalidBlockParts, got, header.Total)
+	}
+	if err := checkPartIndices(parts); err != nil {
+		return nil, err
+	}
+	ordered := make([][]byte, len(parts))
+	for _, part := range parts {
+		ordered[part.Index] = part.Bytes
+	}
+	if err := checkPartSetHash(ordered, header); err != nil {
+		return nil, err
+	}
+	partSet := types.NewPartSetFromHeader(header)
+	for i, part := range parts {
+		ok, err := partSet.AddPartWithoutProof(&types.Part{Index: part.Index, Bytes: part.Bytes})
+		if err != nil {
+			return nil, fmt.Errorf("adding block part %d of %d: %w", part.Index, len(parts), err)
+		}
+		if !ok {
+			return nil, fmt.Errorf("block part %d of %d was not added", part.Index, len(parts))
+		}
+		parts[i] = nil
+	}
+	buf := blockBufs.Get().(*bytes.Buffer)
+	defer blockBufs.Put(buf)
+	buf.Reset()
+	buf.Grow(int(partSet.ByteSize()))
+	if _, err := buf.ReadFrom(partSet.GetReader()); err != nil {
+		return nil, err
+	}
+	return decodeBlock(buf.Bytes())
+}
+
+// checkPartSetHash checks that parts, the bytes of a block's parts in index
+// order, hash to the part set header the block's commit names.
+func checkPartSetHash(parts [][]byte, header types.PartSetHeader) error {
+	return checkPartSetRoot(merkle.HashFromByteSlices(parts), header)
+}
+
+// checkPartSetRoot checks that got, the merkle root of a block's parts, is
+// the part set hash the block's commit names.
+func checkPartSetRoot(got []byte, header types.PartSetHeader) error {
+	if !bytes.Equal(got, header.Hash) {
+		return fmt.Errorf("%w: block parts hash to %X, but the commit's part set hash is %X", ErrInvalidBlockParts, got, header.Hash)
+	}
+	return nil
+}
+
+// checkPartIndices checks that the streamed parts are the parts 0 to
+// len(parts)-1 of a block, each once, in any order. The error says which
+// part is nil, out of range, streamed twice or missing.
+func checkPartIndices(parts []*tmproto.Part) error {
+	total := uint32(len(parts))
+	seen := make([]bool, total)
+	var dup []uint32
+	for i, part := range parts {
+		switch {
+		case part == nil:
+			return fmt.Errorf("%w: block part %d of the stream is empty", ErrInvalidBlockParts, i)
+		case part.Index >= total:
+			return fmt.Errorf("%w: block part index %d out of range, the stream has %d parts", ErrInvalidBlockParts, part.Index, total)
+		case seen[part.Index]:
+			dup = append(dup, part.Index)
+		}
+		seen[part.Index] = true
+	}
+	if len(dup) == 0 {
+		return nil
+	}
+	// As many parts as the stream has are missing as were streamed twice
+	var missing []uint32
+	for idx, ok := range seen {
+		if !ok {
+			missing = append(missing, uint32(idx))
+		}
+	}
+	return fmt.Errorf("%w: block parts %v streamed twice and parts %v missing from the stream of %d parts", ErrInvalidBlockParts, dup, missing, total)
+}
+
+// decodeBlock decodes a block from its protobuf encoding. The block keeps
+// no reference to bz, which can be reused afterwards.
+func decodeBlock(bz []byte) (*types.Block, error) {
+	pbb := new(tmproto.Block)
+	if err := proto.Unmarshal(bz, pbb); err != nil {
+		return nil, err
+	}
+	return types.BlockFromProto(pbb)
+}
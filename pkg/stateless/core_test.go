@@ -0,0 +1,197 @@
+package stateless
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+// testBlock returns an unsigned block of txs that passes validation.
+func testBlock(txs ...types.Tx) *types.Block {
+	block := types.MakeBlock(1, types.Data{Txs: txs}, &types.Commit{}, nil)
+	block.ChainID = "test"
+	// A header without a validators hash hashes to nil
+	block.ValidatorsHash = make([]byte, 32)
+	block.ProposerAddress = make([]byte, 20)
+	return block
+}
+
+// signedTestBlock returns a block of txs at height 1 with a commit its
+// validator set signed, as core streams them.
+func signedTestBlock(t testing.TB, txs ...types.Tx) (*types.Block, *types.Commit, *types.ValidatorSet) {
+	t.Helper()
+	validators, privValidators := types.RandValidatorSet(4, 10)
+	block := types.MakeBlock(1, types.Data{Txs: txs}, &types.Commit{}, nil)
+	block.ChainID = "test"
+	block.ValidatorsHash = validators.Hash()
+	block.ProposerAddress = validators.Validators[0].Address
+	block.Time = time.Now()
+	blockID := types.BlockID{Hash: block.Hash(), PartSetHeader: block.MakePartSet(types.BlockPartSizeBytes).Header()}
+	votes := types.NewVoteSet(block.ChainID, block.Height, 0, tmproto.PrecommitType, validators)
+	commit, err := types.MakeCommit(blockID, block.Height, 0, votes, privValidators, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return block, commit, validators
+}
+
+// blockParts splits block into parts of partSize bytes as core streams
+// them, returning them with the part set header its commit names.
+func blockParts(t testing.TB, block *types.Block, partSize uint32) ([]*tmproto.Part, types.PartSetHeader) {
+	t.Helper()
+	partSet := block.MakePartSet(partSize)
+	parts := make([]*tmproto.Part, partSet.Total())
+	for i := range parts {
+		part, err := partSet.GetPart(i).ToProto()
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts[i] = part
+	}
+	return parts, partSet.Header()
+}
+
+func TestPartsToBlock(t *testing.T) {
+	block := testBlock(types.Tx("a"), types.Tx("b"))
+
+	for _, tc := range []struct {
+		name string
+		// mangle changes the parts and header before reassembly.
+		mangle func(parts []*tmproto.Part, header *types.PartSetHeader) []*tmproto.Part
+		// wantErr is the sentinel the error wraps, nil if there is none.
+		wantErr error
+	}{
+		{"complete", func(parts []*tmproto.Part, _ *types.PartSetHeader) []*tmproto.Part { return parts }, nil},
+		{"reversed", func(parts []*tmproto.Part, _ *types.PartSetHeader) []*tmproto.Part {
+			for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+				parts[i], parts[j] = parts[j], parts[i]
+			}
+			return parts
+		}, nil},
+		{"missing part", func(parts []*tmproto.Part, _ *types.PartSetHeader) []*tmproto.Part {
+			return parts[:len(parts)-1]
+		}, ErrInvalidBlockParts},
+		{"repeated part", func(parts []*tmproto.Part, _ *types.PartSetHeader) []*tmproto.Part {
+			parts[1] = parts[0]
+			return parts
+		}, ErrInvalidBlockParts},
+		{"nil part", func(parts []*tmproto.Part, _ *types.PartSetHeader) []*tmproto.Part {
+			parts[0] = nil
+			return parts
+		}, ErrInvalidBlockParts},
+		{"part set hash mismatch", func(parts []*tmproto.Part, header *types.PartSetHeader) []*tmproto.Part {
+			header.Hash = make([]byte, len(header.Hash))
+			return parts
+		}, ErrInvalidBlockParts},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			parts, header := blockParts(t, block, 64)
+			if len(parts) < 2 {
+				t.Fatalf("block splits into %d parts, want several", len(parts))
+			}
+			parts = tc.mangle(parts, &header)
+			got, err := partsToBlock(parts, header)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("got error %v, want %v", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got.Header.Hash(), block.Header.Hash()) {
+				t.Errorf("reassembled block %X, want %X", got.Header.Hash(), block.Header.Hash())
+			}
+		})
+	}
+}
+
+func TestSignedBlockRejectsSplicedCommit(t *testing.T) {
+	block := testBlock(types.Tx("a"))
+	commit := &types.Commit{Height: 1, BlockID: types.BlockID{Hash: block.Header.Hash()}}
+	if _, err := signedBlock(block, commit, nil); err != nil {
+		t.Fatalf("commit for the block: %v", err)
+	}
+	commit.BlockID.Hash = make([]byte, 32)
+	if _, err := signedBlock(block, commit, nil); !errors.Is(err, ErrCommitVerification) {
+		t.Fatalf("got error %v, want %v", err, ErrCommitVerification)
+	}
+}
+
+func TestExtendSharesTooLarge(t *testing.T) {
+	extender := NewExtender(1)
+	width := 2 * extender.SquareSizeUpperBound()
+	shares := make([][]byte, width*width)
+	if _, err := extender.ExtendShares(shares); !errors.Is(err, ErrSquareTooLarge) {
+		t.Fatalf("got error %v, want %v", err, ErrSquareTooLarge)
+	}
+}
+
+// BenchmarkPartsToBlock reassembles a 4 MiB block from its parts into a
+// pooled buffer, as partsToBlock does, and into a fresh one, as reading
+// the part set with io.ReadAll would. Compare B/op and allocs/op.
+func BenchmarkPartsToBlock(b *testing.B) {
+	txs := make([]types.Tx, 64)
+	for i := range txs {
+		txs[i] = bytes.Repeat([]byte{byte(i)}, 64<<10)
+	}
+	block := testBlock(txs...)
+	partSet := block.MakePartSet(types.BlockPartSizeBytes)
+	parts := make([]*tmproto.Part, partSet.Total())
+	for i := range parts {
+		part, err := partSet.GetPart(i).ToProto()
+		if err != nil {
+			b.Fatal(err)
+		}
+		parts[i] = part
+	}
+	header := partSet.Header()
+	b.Logf("%d parts of %d bytes", len(parts), types.BlockPartSizeBytes)
+
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		streamed := make([]*tmproto.Part, len(parts))
+		for range b.N {
+			// partsToBlock releases the parts it is given
+			copy(streamed, parts)
+			if _, err := partsToBlock(streamed, header); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("unpooled", func(b *testing.B) {
+		b.ReportAllocs()
+		ordered := make([][]byte, len(parts))
+		for i, part := range parts {
+			ordered[i] = part.Bytes
+		}
+		for range b.N {
+			// The same checks as partsToBlock, for only the buffer to differ
+			if err := checkPartIndices(parts); err != nil {
+				b.Fatal(err)
+			}
+			if err := checkPartSetHash(ordered, header); err != nil {
+				b.Fatal(err)
+			}
+			partSet := types.NewPartSetFromHeader(header)
+			for _, part := range parts {
+				if _, err := partSet.AddPartWithoutProof(&types.Part{Index: part.Index, Bytes: part.Bytes}); err != nil {
+					b.Fatal(err)
+				}
+			}
+			bz, err := io.ReadAll(partSet.GetReader())
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, err := decodeBlock(bz); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
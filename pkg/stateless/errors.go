@@ -0,0 +1,59 @@
+package stateless
+
+import "errors"
+
+// Errors returned by the package wrap one of these, for callers to tell
+// failures apart with errors.Is.
+var (
+	// ErrBlockNotFound is returned when core has no block at the requested
+	// height or with the requested hash, as for heights it pruned or
+	// hasn't reached.
+	ErrBlockNotFound = errors.New("block not found")
+	// ErrDAHMismatch is returned when a DAH doesn't hash to the data root
+	// committed in a header.
+	ErrDAHMismatch = errors.New("DAH does not match header data hash")
+	// ErrCommitVerification is returned when a commit isn't for its
+	// header or isn't signed by more than 2/3 of its validator set.
+	ErrCommitVerification = errors.New("commit verification failed")
+	// ErrInvalidValidatorSet is returned when a validator set is
+	// malformed or isn't the set a header names.
+	ErrInvalidValidatorSet = errors.New("invalid validator set")
+	// ErrNotPowerOfTwo is returned when shares or a square size don't
+	// make up a square of power-of-2 width.
+	ErrNotPowerOfTwo = errors.New("not a square of power-of-2 width")
+	// ErrSquareTooLarge is returned when shares make up a square wider
+	// than the app version they are extended under allows.
+	ErrSquareTooLarge = errors.New("square exceeds the size upper bound")
+	// ErrInvalidBlockParts is returned when the parts core streams don't
+	// make up the block its commit names: parts are missing, repeated or
+	// out of range, or don't hash to the commit's part set header.
+	ErrInvalidBlockParts = errors.New("invalid block parts")
+)
+
+// RetriableError wraps a failure to fetch from core that may succeed when
+// retried: the request or the stream ran out of time, or no endpoint was
+// available.
+type RetriableError struct {
+	Err error
+}
+
+func (e *RetriableError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RetriableError) Unwrap() error {
+	return e.Err
+}
+
+// IsRetriable reports whether retrying the call that failed may succeed,
+// which it always may.
+func (e *RetriableError) IsRetriable() bool {
+	return true
+}
+
+// IsRetriable reports whether err, or an error it wraps, says retrying
+// the call that failed may succeed.
+func IsRetriable(err error) bool {
+	var retriable interface{ IsRetriable() bool }
+	return errors.As(err, &retriable) && retriable.IsRetriable()
+}
@@ -0,0 +1,80 @@
+package stateless
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	coregrpc "github.com/tendermint/tendermint/rpc/grpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// stallingCore is a core BlockAPI whose block streams send nothing until
+// the client gives up, or fail at once with err if it is set.
+type stallingCore struct {
+	coregrpc.UnimplementedBlockAPIServer
+	err error
+}
+
+func (c *stallingCore) BlockByHeight(_ *coregrpc.BlockByHeightRequest, srv coregrpc.BlockAPI_BlockByHeightServer) error {
+	if c.err != nil {
+		return c.err
+	}
+	<-srv.Context().Done()
+	return srv.Context().Err()
+}
+
+// startCore serves api on a local port and returns an accessor to it.
+func startCore(t *testing.T, api coregrpc.BlockAPIServer) *CoreAccessor {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	coregrpc.RegisterBlockAPIServer(srv, api)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	accessor, err := NewCoreAccessor(lis.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { accessor.Close() })
+	return accessor
+}
+
+func TestSlowStreamIsRetriable(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		core        *stallingCore
+		timeout     time.Duration
+		partTimeout time.Duration
+		retriable   bool
+	}{
+		{"request deadline", &stallingCore{}, 100 * time.Millisecond, 0, true},
+		{"part timeout", &stallingCore{}, 0, 100 * time.Millisecond, true},
+		{"unavailable", &stallingCore{err: status.Error(codes.Unavailable, "overloaded")}, 0, 0, true},
+		{"invalid request", &stallingCore{err: status.Error(codes.InvalidArgument, "bad height")}, 0, 0, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			accessor := startCore(t, tc.core)
+			accessor.SetPartTimeout(tc.partTimeout)
+			ctx := context.Background()
+			if tc.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tc.timeout)
+				defer cancel()
+			}
+			_, err := accessor.GetSignedBlock(ctx, "5")
+			if err == nil {
+				t.Fatal("fetch succeeded")
+			}
+			if got := IsRetriable(err); got != tc.retriable {
+				t.Errorf("IsRetriable(%q) = %t, want %t", err, got, tc.retriable)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package stateless
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/celestiaorg/celestia-app/v3/app"
+	"github.com/celestiaorg/celestia-app/v3/pkg/appconsts"
+	"github.com/celestiaorg/celestia-node/share"
+	libsquare "github.com/celestiaorg/go-square/v2"
+	libshare "github.com/celestiaorg/go-square/v2/share"
+	"github.com/celestiaorg/nmt"
+	"github.com/celestiaorg/rsmt2d"
+	"github.com/tendermint/tendermint/types"
+)
+
+// Extender extends block data and shares under one app version, NMT
+// configuration and codec, for callers extending many blocks alike to
+// configure it once. The zero options are celestia-app's trees. The app version's
+// square constants are looked up once, and the tree constructor of each
+// square size is built the first time a square of that size is extended,
+// so reusing an Extender across blocks saves redoing both for every block.
+// It is safe for concurrent use.
+type Extender struct {
+	appVersion uint64
+	options    []nmt.Option
+	codec      rsmt2d.Codec
+	constants  squareConstants
+	// treeSlots, if set, bounds how many row and column trees are hashed at
+	// once to its capacity.
+	treeSlots chan struct{}
+	// trees holds the rsmt2d.TreeConstructorFn of each square size.
+	trees sync.Map
+}
+
+// NewExtender returns an Extender for blocks of the given app version.
+// Options override the NMT configuration celestia-app builds the trees
+// with, which changes the roots. It erasure codes with celestia-app's
+// codec.
+func NewExtender(appVersion uint64, options ...nmt.Option) *Extender {
+	return NewExtenderWithCodec(appVersion, appconsts.DefaultCodec(), options...)
+}
+
+// NewExtenderWithCodec returns an Extender like NewExtender that erasure
+// codes with codec, such as one CodecByName returns.
+func NewExtenderWithCodec(appVersion uint64, codec rsmt2d.Codec, options ...nmt.Option) *Extender {
+	return &Extender{appVersion: appVersion, options: options, codec: codec, constants: constantsFor(appVersion)}
+}
+
+// SetConcurrency bounds how many row and column trees of a square are
+// hashed at once when its roots are computed, e.g. by
+// da.NewDataAvailabilityHeader, to n. Zero, the default, leaves rsmt2d to
+// hash them all at once. It must be called before e extends any square.
+func (e *Extender) SetConcurrency(n int) {
+	e.treeSlots = nil
+	if n > 0 {
+		e.treeSlots = make(chan struct{}, n)
+	}
+}
+
+// AppVersion returns the app version e extends under.
+func (e *Extender) AppVersion() uint64 {
+	return e.appVersion
+}
+
+// Codec returns the codec e erasure codes with.
+func (e *Extender) Codec() rsmt2d.Codec {
+	return e.codec
+}
+
+// SquareSizeUpperBound returns SquareSizeUpperBound of e's app version.
+func (e *Extender) SquareSizeUpperBound() int {
+	return e.constants.squareSizeUpperBound
+}
+
+// SubtreeRootThreshold returns SubtreeRootThreshold of e's app version.
+func (e *Extender) SubtreeRootThreshold() int {
+	return e.constants.subtreeRootThreshold
+}
+
+// treeConstructor returns the tree constructor of squares of the given
+// original width under e's options.
+func (e *Extender) treeConstructor(squareSize uint64) rsmt2d.TreeConstructorFn {
+	if fn, ok := e.trees.Load(squareSize); ok {
+		return fn.(rsmt2d.TreeConstructorFn)
+	}
+	fn := treeConstructor(squareSize, e.options...)
+	if e.treeSlots != nil {
+		fn = boundedConstructor(fn, e.treeSlots)
+	}
+	stored, _ := e.trees.LoadOrStore(squareSize, fn)
+	return stored.(rsmt2d.TreeConstructorFn)
+}
+
+// Extend extends the given block data, returning the resulting
+// ExtendedDataSquare (EDS). The square of a block without transactions is
+// the minimal empty one.
+func (e *Extender) Extend(data *types.Data) (*rsmt2d.ExtendedDataSquare, error) {
+	start := time.Now()
+	eds, err := e.extend(data)
+	if err != nil {
+		return nil, err
+	}
+	observeStage(StageExtend, start, int(eds.Width()/2))
+	return eds, nil
+}
+
+func (e *Extender) extend(data *types.Data) (*rsmt2d.ExtendedDataSquare, error) {
+	if app.IsEmptyBlockRef(data, e.appVersion) {
+		return share.EmptyEDS(), nil
+	}
+
+	txs := data.Txs.ToSliceOfBytes()
+	if err := validateTxs(txs); err != nil {
+		return nil, fmt.Errorf("invalid block transactions: %w", err)
+	}
+
+	// Construct the data square from the block's transactions
+	square, err := libsquare.Construct(
+		txs,
+		e.SquareSizeUpperBound(),
+		e.SubtreeRootThreshold(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return e.ExtendShares(libshare.ToBytes(square))
+}
+
+// ExtendShares erasure codes the shares of an original data square, given
+// in row-major order, into an ExtendedDataSquare. The square may be no
+// larger than e's app version allows.
+func (e *Extender) ExtendShares(s [][]byte) (*rsmt2d.ExtendedDataSquare, error) {
+	// Check that the shares fill a square of power-of-2 width.
+	squareSize := libsquare.Size(len(s))
+	if len(s) == 0 || len(s) != squareSize*squareSize {
+		return nil, fmt.Errorf("%w: got %d shares", ErrNotPowerOfTwo, len(s))
+	}
+	if upperBound := e.SquareSizeUpperBound(); squareSize > upperBound {
+		return nil, fmt.Errorf("%w: square size %d exceeds the upper bound %d for app version %d",
+			ErrSquareTooLarge, squareSize, upperBound, e.appVersion)
+	}
+	// Every app version uses the same share size. rsmt2d only fails on a
+	// share of another length deep inside the codec, so catch it first.
+	for i, sh := range s {
+		if len(sh) != libshare.ShareSize {
+			return nil, fmt.Errorf("share %d is %d bytes, expected %d", i, len(sh), libshare.ShareSize)
+		}
+	}
+	// here we construct a tree
+	// Note: uses the nmt wrapper to construct the tree, see treeConstructor
+	// for how options are applied. The trees are only built once the roots
+	// are first asked for, e.g. by da.NewDataAvailabilityHeader, and rsmt2d
+	// then builds every row and column tree in its own goroutine, as many
+	// at once as SetConcurrency allows.
+	start := time.Now()
+	eds, err := rsmt2d.ComputeExtendedDataSquare(s,
+		e.codec,
+		e.treeConstructor(uint64(squareSize)))
+	if err != nil {
+		return nil, err
+	}
+	slog.Debug("extended square", "square_size", squareSize, "codec", e.codec.Name(), "duration", time.Since(start))
+	return eds, nil
+}
+
+// ExtendBlock extends the given block data, returning the resulting
+// ExtendedDataSquare (EDS). It is NewExtender(appVersion,
+// options...).Extend(data).
+func ExtendBlock(data *types.Data, appVersion uint64, options ...nmt.Option) (*rsmt2d.ExtendedDataSquare, error) {
+	return NewExtender(appVersion, options...).Extend(data)
+}
+
+// PadShares returns s followed by tail padding shares up to the share count
+// of the smallest square of power-of-2 width that holds s, which is what
+// ExtendShares requires. Padding changes the square, so the DAH of the
+// padded square differs from that of any block the shares came from,
+// whose square celestia-app pads itself.
+func PadShares(s [][]byte) [][]byte {
+	if len(s) == 0 {
+		return s
+	}
+	squareSize := libsquare.Size(len(s))
+	padding := libshare.ToBytes(libshare.TailPaddingShares(squareSize*squareSize - len(s)))
+	return append(s[:len(s):len(s)], padding...)
+}
+
+// ExtendShares erasure codes the shares of an original data square, given
+// in row-major order, into an ExtendedDataSquare. It is
+// NewExtender(appVersion, options...).ExtendShares(s).
+func ExtendShares(s [][]byte, appVersion uint64, options ...nmt.Option) (*rsmt2d.ExtendedDataSquare, error) {
+	return NewExtender(appVersion, options...).ExtendShares(s)
+}
+
+// SquareDimensions is the geometry of an ExtendedDataSquare.
+type SquareDimensions struct {
+	// OriginalWidth is the width of the original data square.
+	OriginalWidth int `json:"original_width"`
+	// ExtendedWidth is the width of the extended square, twice the
+	// original.
+	ExtendedWidth int `json:"extended_width"`
+	// Shares is the number of shares of the original data square.
+	Shares int `json:"shares"`
+}
+
+// EDSSize returns the dimensions of eds. The square of an empty block is
+// the minimal one, a single share extended to 2x2.
+func EDSSize(eds *rsmt2d.ExtendedDataSquare) SquareDimensions {
+	width := int(eds.Width())
+	return SquareDimensions{
+		OriginalWidth: width / 2,
+		ExtendedWidth: width,
+		Shares:        width * width / 4,
+	}
+}
+
+func (d SquareDimensions) String() string {
+	return fmt.Sprintf("original: %dx%d, extended: %dx%d, shares: %d",
+		d.OriginalWidth, d.OriginalWidth, d.ExtendedWidth, d.ExtendedWidth, d.Shares)
+}
@@ -0,0 +1,186 @@
+package stateless
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/celestiaorg/celestia-app/v3/app"
+	"github.com/celestiaorg/celestia-app/v3/pkg/da"
+	libshare "github.com/celestiaorg/go-square/v2/share"
+	"github.com/celestiaorg/go-square/v2/tx"
+	"github.com/celestiaorg/rsmt2d"
+	"github.com/tendermint/tendermint/types"
+)
+
+// randomTxs returns up to 20 normal transactions of random bytes followed
+// by up to 4 blob transactions, each carrying one blob under a random
+// namespace, as a valid block orders them.
+func randomTxs(t testing.TB, rng *rand.Rand) types.Txs {
+	t.Helper()
+	var txs types.Txs
+	for range rng.Intn(20) {
+		rawTx := make([]byte, 1+rng.Intn(2000))
+		rng.Read(rawTx)
+		txs = append(txs, rawTx)
+	}
+	for range rng.Intn(5) {
+		id := make([]byte, libshare.NamespaceVersionZeroIDSize)
+		rng.Read(id)
+		// A non-zero first byte keeps clear of the reserved namespaces
+		id[0] |= 1
+		ns, err := libshare.NewV0Namespace(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data := make([]byte, 1+rng.Intn(5000))
+		rng.Read(data)
+		blob, err := libshare.NewV0Blob(ns, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inner := make([]byte, 1+rng.Intn(300))
+		rng.Read(inner)
+		blobTx, err := tx.MarshalBlobTx(inner, blob)
+		if err != nil {
+			t.Fatal(err)
+		}
+		txs = append(txs, blobTx)
+	}
+	return txs
+}
+
+// dataRoot returns the hash of the DAH of eds.
+func dataRoot(t testing.TB, eds *rsmt2d.ExtendedDataSquare) []byte {
+	t.Helper()
+	dah, err := da.NewDataAvailabilityHeader(eds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dah.Hash()
+}
+
+// TestExtendBlockMatchesApp extends random valid blocks and checks that
+// their DAH hashes to the data root celestia-app computes for the same
+// transactions, at every app version.
+func TestExtendBlockMatchesApp(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := range 50 {
+		data := &types.Data{Txs: randomTxs(t, rng)}
+		for _, version := range []uint64{1, 2, 3} {
+			eds, err := ExtendBlock(data, version)
+			if err != nil {
+				t.Fatalf("block %d, app version %d: %v", i, version, err)
+			}
+			appEDS, err := app.ExtendBlock(*data, version)
+			if err != nil {
+				t.Fatalf("block %d, app version %d: celestia-app: %v", i, version, err)
+			}
+			if got, want := dataRoot(t, eds), dataRoot(t, appEDS); !bytes.Equal(got, want) {
+				t.Errorf("block %d of %d txs, app version %d: data root %X, celestia-app computes %X",
+					i, len(data.Txs), version, got, want)
+			}
+		}
+	}
+}
+
+// BenchmarkExtendRange extends a range of 20 blocks with one Extender, as
+// the CLI's range does, and with an Extender built for each block, as
+// ExtendBlock does, which looks the square constants up and builds the
+// tree constructor again for every block.
+func BenchmarkExtendRange(b *testing.B) {
+	rng := rand.New(rand.NewSource(4))
+	blocks := make([]*types.Data, 20)
+	for i := range blocks {
+		blocks[i] = &types.Data{Txs: randomTxs(b, rng)}
+	}
+	b.Run("shared", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			extender := NewExtender(3)
+			for _, data := range blocks {
+				if _, err := extender.Extend(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("per block", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			for _, data := range blocks {
+				if _, err := ExtendBlock(data, 3); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
+
+// orderedShares returns the shares of a square of the given width, each
+// of random data under a namespace above that of the share before it, as
+// the NMT requires.
+func orderedShares(width int) [][]byte {
+	rng := rand.New(rand.NewSource(5))
+	shares := make([][]byte, width*width)
+	for i := range shares {
+		id := make([]byte, libshare.NamespaceVersionZeroIDSize)
+		binary.BigEndian.PutUint32(id[len(id)-4:], uint32(i+1))
+		shares[i] = make([]byte, libshare.ShareSize)
+		copy(shares[i], libshare.MustNewV0Namespace(id).Bytes())
+		rng.Read(shares[i][libshare.NamespaceSize:])
+	}
+	return shares
+}
+
+// TestExtenderConcurrency checks that bounding how many trees are hashed
+// at once doesn't change the roots.
+func TestExtenderConcurrency(t *testing.T) {
+	shares := orderedShares(16)
+	eds, err := NewExtender(3).ExtendShares(shares)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := dataRoot(t, eds)
+	for _, n := range []int{1, 2, 7} {
+		extender := NewExtender(3)
+		extender.SetConcurrency(n)
+		eds, err := extender.ExtendShares(shares)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := dataRoot(t, eds); !bytes.Equal(got, want) {
+			t.Errorf("concurrency %d: data root %X, want %X", n, got, want)
+		}
+	}
+}
+
+// BenchmarkExtendConcurrency extends a 128x128 square and computes its
+// DAH with the row and column trees hashed one at a time, a bounded number
+// at once, and all at once as rsmt2d does by default. Run it on a machine
+// with several cores to see the speedup.
+func BenchmarkExtendConcurrency(b *testing.B) {
+	shares := orderedShares(128)
+	for _, n := range []int{1, 2, 4, 8, 0} {
+		name := fmt.Sprintf("concurrency=%d", n)
+		if n == 0 {
+			name = "unbounded"
+		}
+		b.Run(name, func(b *testing.B) {
+			extender := NewExtender(3)
+			extender.SetConcurrency(n)
+			b.ReportAllocs()
+			for range b.N {
+				eds, err := extender.ExtendShares(shares)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if _, err := da.NewDataAvailabilityHeader(eds); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
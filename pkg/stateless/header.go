@@ -0,0 +1,92 @@
+package stateless
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/celestiaorg/celestia-app/v3/pkg/da"
+	"github.com/celestiaorg/celestia-node/header"
+	"github.com/celestiaorg/rsmt2d"
+	"github.com/tendermint/tendermint/types"
+)
+
+// ExtendedHeader represents a wrapped "raw" header that includes
+// information necessary for Celestia Nodes to be notified of new
+// block headers and perform Data Availability Sampling.
+type ExtendedHeader struct {
+	types.Header `json:"header"`
+	Commit       *types.Commit              `json:"commit"`
+	ValidatorSet *types.ValidatorSet        `json:"validator_set"`
+	DAH          *da.DataAvailabilityHeader `json:"dah"`
+}
+
+// MakeExtendedHeader assembles new ExtendedHeader. It fails if the DAH of
+// eds does not hash to the header's DataHash, i.e. if eds is not the square
+// validators committed to.
+func MakeExtendedHeader(
+	h *types.Header,
+	comm *types.Commit,
+	vals *types.ValidatorSet,
+	eds *rsmt2d.ExtendedDataSquare,
+) (*ExtendedHeader, error) {
+	eh, err := AssembleExtendedHeader(h, comm, vals, eds)
+	if err != nil {
+		return nil, err
+	}
+	if err := VerifyDAH(h, eh.DAH); err != nil {
+		return nil, err
+	}
+	return eh, nil
+}
+
+// AssembleExtendedHeader assembles new ExtendedHeader like
+// MakeExtendedHeader without checking the DAH of eds against the header's
+// DataHash, for squares extended in a way validators didn't commit to.
+func AssembleExtendedHeader(
+	h *types.Header,
+	comm *types.Commit,
+	vals *types.ValidatorSet,
+	eds *rsmt2d.ExtendedDataSquare,
+) (*ExtendedHeader, error) {
+	var (
+		dah da.DataAvailabilityHeader
+		err error
+	)
+	switch eds {
+	case nil:
+		dah = da.MinDataAvailabilityHeader()
+	default:
+		dah, err = da.NewDataAvailabilityHeader(eds)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	eh := &ExtendedHeader{
+		Header:       *h,
+		DAH:          &dah,
+		Commit:       comm,
+		ValidatorSet: vals,
+	}
+	return eh, nil
+}
+
+// NodeHeader returns eh as celestia-node's header.ExtendedHeader, the type
+// a node stores and gossips, to encode it in the node's own JSON and
+// binary formats.
+func (eh *ExtendedHeader) NodeHeader() *header.ExtendedHeader {
+	return &header.ExtendedHeader{
+		RawHeader:    eh.Header,
+		Commit:       eh.Commit,
+		ValidatorSet: eh.ValidatorSet,
+		DAH:          eh.DAH,
+	}
+}
+
+// VerifyDAH checks that dah hashes to the data root committed in h.
+func VerifyDAH(h *types.Header, dah *da.DataAvailabilityHeader) error {
+	if !bytes.Equal(dah.Hash(), h.DataHash) {
+		return fmt.Errorf("%w: DAH hash %X, header data hash %X", ErrDAHMismatch, dah.Hash(), h.DataHash)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package stateless
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stage is a timed step of fetching and extending a block.
+type Stage string
+
+const (
+	// StageFetch is a GetSignedBlock or GetSignedBlockByHash call,
+	// streaming and reassembly included.
+	StageFetch Stage = "fetch"
+	// StageReassemble is the decoding of a block from its streamed parts.
+	StageReassemble Stage = "reassemble"
+	// StageExtend is an ExtendBlock call.
+	StageExtend Stage = "extend"
+)
+
+// StageObserver is called with the duration of every stage run. For
+// StageExtend, squareSize is the width of the original data square; it is
+// zero for the other stages.
+type StageObserver func(stage Stage, d time.Duration, squareSize int)
+
+var stageObserver atomic.Pointer[StageObserver]
+
+// SetStageObserver installs fn to be called for every stage run by this
+// package, replacing any previous observer. A nil fn removes it. fn may be
+// called concurrently.
+func SetStageObserver(fn StageObserver) {
+	if fn == nil {
+		stageObserver.Store(nil)
+		return
+	}
+	stageObserver.Store(&fn)
+}
+
+// observeStage reports a stage that began at start.
+func observeStage(stage Stage, start time.Time, squareSize int) {
+	if fn := stageObserver.Load(); fn != nil {
+		(*fn)(stage, time.Since(start), squareSize)
+	}
+}
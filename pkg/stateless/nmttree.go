@@ -0,0 +1,110 @@
+package stateless
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/celestiaorg/celestia-app/v3/pkg/appconsts"
+	"github.com/celestiaorg/celestia-app/v3/pkg/wrapper"
+	libshare "github.com/celestiaorg/go-square/v2/share"
+	"github.com/celestiaorg/nmt"
+	"github.com/celestiaorg/rsmt2d"
+)
+
+// treeConstructor returns the constructor of the row and column trees of a
+// square of the given original width. Without options it is celestia-app's
+// wrapper. The wrapper applies its own namespace size and IgnoreMaxNamespace
+// after the caller's options, so with options an equivalent tree is built
+// that applies them last instead, letting them override celestia-app's.
+func treeConstructor(squareSize uint64, options ...nmt.Option) rsmt2d.TreeConstructorFn {
+	if len(options) == 0 {
+		return wrapper.NewConstructor(squareSize)
+	}
+	return func(_ rsmt2d.Axis, axisIndex uint) rsmt2d.Tree {
+		opts := append([]nmt.Option{
+			nmt.InitialCapacity(int(squareSize) * 2),
+			nmt.NamespaceIDSize(libshare.NamespaceSize),
+			nmt.IgnoreMaxNamespace(true),
+		}, options...)
+		return &erasuredTree{
+			squareSize: squareSize,
+			axisIndex:  uint64(axisIndex),
+			tree:       nmt.New(appconsts.NewBaseHashFunc(), opts...),
+		}
+	}
+}
+
+// erasuredTree is wrapper.ErasuredNamespacedMerkleTree for a tree of any
+// namespace size: leaves outside the original quadrant are pushed under
+// the parity namespace, all others under the leading bytes of their share.
+type erasuredTree struct {
+	squareSize uint64
+	axisIndex  uint64
+	shareIndex uint64
+	tree       *nmt.NamespacedMerkleTree
+}
+
+func (t *erasuredTree) Push(data []byte) error {
+	if t.axisIndex+1 > 2*t.squareSize || t.shareIndex+1 > 2*t.squareSize {
+		return fmt.Errorf("pushed past predetermined square size: boundary at %d index at %d %d",
+			2*t.squareSize, t.axisIndex, t.shareIndex)
+	}
+	nsSize := int(t.tree.NamespaceSize())
+	if len(data) < nsSize {
+		return fmt.Errorf("data is too short to contain namespace ID")
+	}
+	leaf := make([]byte, nsSize+len(data))
+	copy(leaf[nsSize:], data)
+	if t.shareIndex < t.squareSize && t.axisIndex < t.squareSize {
+		copy(leaf, data[:nsSize])
+	} else {
+		// The parity namespace is all 0xFF, whatever its size
+		copy(leaf, bytes.Repeat([]byte{0xFF}, nsSize))
+	}
+	if err := t.tree.Push(leaf); err != nil {
+		return err
+	}
+	t.shareIndex++
+	return nil
+}
+
+func (t *erasuredTree) Root() ([]byte, error) {
+	return t.tree.Root()
+}
+
+// boundedConstructor wraps fn so that the trees it constructs compute
+// their roots only while holding a slot of sem, which bounds how many
+// trees are hashed at once to its capacity. rsmt2d starts a goroutine for
+// every row and column tree of a square, which is unbounded otherwise.
+func boundedConstructor(fn rsmt2d.TreeConstructorFn, sem chan struct{}) rsmt2d.TreeConstructorFn {
+	return func(axis rsmt2d.Axis, index uint) rsmt2d.Tree {
+		return &boundedTree{tree: fn(axis, index), sem: sem}
+	}
+}
+
+// boundedTree holds the leaves pushed to it until its root is asked for,
+// then pushes them to tree and computes the root once a slot of sem is
+// free. rsmt2d asks for the root of every tree right after pushing the
+// leaves.
+type boundedTree struct {
+	tree   rsmt2d.Tree
+	sem    chan struct{}
+	leaves [][]byte
+}
+
+func (t *boundedTree) Push(data []byte) error {
+	t.leaves = append(t.leaves, data)
+	return nil
+}
+
+func (t *boundedTree) Root() ([]byte, error) {
+	t.sem <- struct{}{}
+	defer func() { <-t.sem }()
+	for _, leaf := range t.leaves {
+		if err := t.tree.Push(leaf); err != nil {
+			return nil, err
+		}
+	}
+	t.leaves = nil
+	return t.tree.Root()
+}
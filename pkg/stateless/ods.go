@@ -0,0 +1,84 @@
+package stateless
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/celestiaorg/celestia-app/v3/pkg/appconsts"
+	libsquare "github.com/celestiaorg/go-square/v2"
+	libshare "github.com/celestiaorg/go-square/v2/share"
+	"github.com/celestiaorg/rsmt2d"
+)
+
+// odsMagic starts every file written by WriteODS, versioning its format.
+// No share starts with it, since its first byte is no namespace version.
+const odsMagic = "ODS1"
+
+// odsHeaderSize is the size of the header of an ODS file: the magic, then
+// the square size and the share size as big-endian uint32s.
+const odsHeaderSize = len(odsMagic) + 8
+
+// WriteODS writes the original data square of eds to w as a header
+// recording the square size and share size, followed by the shares of the
+// square concatenated in row-major order. The parity quadrants are left
+// out, since ExtendShares recomputes them from the shares ReadODS returns.
+func WriteODS(w io.Writer, eds *rsmt2d.ExtendedDataSquare) error {
+	ods := eds.FlattenedODS()
+	header := make([]byte, odsHeaderSize)
+	copy(header, odsMagic)
+	binary.BigEndian.PutUint32(header[len(odsMagic):], uint32(eds.Width()/2))
+	binary.BigEndian.PutUint32(header[len(odsMagic)+4:], libshare.ShareSize)
+	bw := bufio.NewWriter(w)
+	if _, err := bw.Write(header); err != nil {
+		return err
+	}
+	for _, s := range ods {
+		if _, err := bw.Write(s); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// IsODS reports whether bz starts like a file written by WriteODS.
+func IsODS(bz []byte) bool {
+	return len(bz) >= len(odsMagic) && string(bz[:len(odsMagic)]) == odsMagic
+}
+
+// ReadODS reads a file written by WriteODS and returns the shares of its
+// original data square in row-major order.
+func ReadODS(r io.Reader) ([][]byte, error) {
+	header := make([]byte, odsHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	if !IsODS(header) {
+		return nil, errors.New("not an ODS file: bad magic")
+	}
+	squareSize := binary.BigEndian.Uint32(header[len(odsMagic):])
+	shareSize := binary.BigEndian.Uint32(header[len(odsMagic)+4:])
+	if squareSize == 0 || !libsquare.IsPowerOfTwo(squareSize) {
+		return nil, fmt.Errorf("%w: square size %d", ErrNotPowerOfTwo, squareSize)
+	}
+	// Bounded before allocating the square
+	if upperBound := SquareSizeUpperBound(appconsts.LatestVersion); squareSize > uint32(upperBound) {
+		return nil, fmt.Errorf("square size %d exceeds the upper bound %d", squareSize, upperBound)
+	}
+	if shareSize != libshare.ShareSize {
+		return nil, fmt.Errorf("share size is %d, expected %d", shareSize, libshare.ShareSize)
+	}
+	shares := make([][]byte, squareSize*squareSize)
+	for i := range shares {
+		shares[i] = make([]byte, shareSize)
+		if _, err := io.ReadFull(r, shares[i]); err != nil {
+			return nil, fmt.Errorf("reading share %d of %d: %w", i, len(shares), err)
+		}
+	}
+	if n, _ := io.Copy(io.Discard, r); n > 0 {
+		return nil, fmt.Errorf("%d bytes after the last share", n)
+	}
+	return shares, nil
+}
@@ -0,0 +1,188 @@
+package stateless
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"math/bits"
+	"sync/atomic"
+	"time"
+
+	"github.com/tendermint/tendermint/crypto/tmhash"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+)
+
+// pipelineDepth is how many received block parts may wait for the
+// reassembler before receiving pauses.
+const pipelineDepth = 16
+
+// receiveBlockPipelined reassembles a block like receiveBlock, but
+// overlaps receiving the parts with reassembling them: one goroutine
+// receives parts from recv while the caller appends each part to the
+// block's encoding, and hashes it into the part set's merkle tree, as soon
+// as the parts before it have arrived. Parts are
+// placed by their index, so they may arrive in any order. Like
+// receiveBlock, it checks the parts against the commit's part set header.
+func receiveBlockPipelined(
+	ctx context.Context,
+	cancel context.CancelFunc,
+	partTimeout time.Duration,
+	recv func() (streamedBlockPart, error),
+) (*SignedBlock, error) {
+	var received atomic.Int64
+	if partTimeout > 0 {
+		recv = recvWithin(partTimeout, cancel, func() int { return int(received.Load()) }, recv)
+	}
+	firstPart, commit, validatorSet, err := receiveFirstPart(recv)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := make(chan *tmproto.Part, pipelineDepth)
+	recvErr := make(chan error, 1)
+	go func() {
+		defer close(parts)
+		for part := firstPart; ; {
+			select {
+			case parts <- part.GetBlockPart():
+			case <-ctx.Done():
+				recvErr <- ctx.Err()
+				return
+			}
+			received.Add(1)
+			if part.GetIsLast() {
+				recvErr <- nil
+				return
+			}
+			var err error
+			if part, err = recv(); err != nil {
+				recvErr <- err
+				return
+			}
+		}
+	}()
+
+	buf := blockBufs.Get().(*bytes.Buffer)
+	defer blockBufs.Put(buf)
+	buf.Reset()
+	partSet := commit.BlockID.PartSetHeader
+	asm := &partAssembler{buf: buf, total: partSet.Total, pending: make(map[uint32][]byte)}
+	var asmErr error
+	for part := range parts {
+		if asmErr != nil {
+			continue
+		}
+		if asmErr = asm.add(part); asmErr != nil {
+			// Stop the receiver, the parts it already sent are drained
+			cancel()
+		}
+	}
+	if err := <-recvErr; asmErr == nil {
+		asmErr = err
+	}
+	if asmErr != nil {
+		return nil, asmErr
+	}
+	if err := asm.complete(); err != nil {
+		return nil, err
+	}
+	slog.Debug("received block parts", "parts", asm.next)
+	if err := checkPartSetRoot(rootFromLeafHashes(asm.leaves), partSet); err != nil {
+		return nil, err
+	}
+
+	// Only decoding is left once the last part is in, so that is what the
+	// stage measures
+	start := time.Now()
+	block, err := decodeBlock(buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	observeStage(StageReassemble, start, 0)
+	return signedBlock(block, commit, validatorSet)
+}
+
+// partAssembler appends the parts of a block to buf in index order,
+// holding parts that arrive early until the ones before them are in.
+type partAssembler struct {
+	buf *bytes.Buffer
+	// total is the number of parts of the block.
+	total uint32
+	// next is the index of the next part to append.
+	next    uint32
+	pending map[uint32][]byte
+	// leaves are the leaf hashes of the appended parts in the part set's
+	// merkle tree, hashed as the parts arrive so that only the inner
+	// nodes are left to hash once the last one is in.
+	leaves [][]byte
+}
+
+// add places part, appending it and every pending part it unblocks.
+func (a *partAssembler) add(part *tmproto.Part) error {
+	if part == nil {
+		return fmt.Errorf("%w: block part missing from the stream after part %d", ErrInvalidBlockParts, a.next)
+	}
+	idx := part.Index
+	if idx >= a.total {
+		return fmt.Errorf("%w: block part index %d out of range, the commit's part set has %d parts", ErrInvalidBlockParts, idx, a.total)
+	}
+	if _, ok := a.pending[idx]; ok || idx < a.next {
+		return fmt.Errorf("%w: block part %d streamed twice", ErrInvalidBlockParts, idx)
+	}
+	if idx != a.next {
+		a.pending[idx] = part.Bytes
+		return nil
+	}
+	a.append(part.Bytes)
+	for {
+		bz, ok := a.pending[a.next]
+		if !ok {
+			return nil
+		}
+		delete(a.pending, a.next)
+		a.append(bz)
+	}
+}
+
+func (a *partAssembler) append(bz []byte) {
+	a.buf.Write(bz)
+	a.leaves = append(a.leaves, partLeafHash(bz))
+	a.next++
+}
+
+// complete checks that no part is missing once the stream ended.
+func (a *partAssembler) complete() error {
+	if len(a.pending) > 0 {
+		return fmt.Errorf("%w: block part %d was not streamed, %d parts after it were", ErrInvalidBlockParts, a.next, len(a.pending))
+	}
+	if a.next != a.total {
+		return fmt.Errorf("%w: stream ended after %d block parts, the commit's part set has %d", ErrInvalidBlockParts, a.next, a.total)
+	}
+	return nil
+}
+
+// partLeafHash returns the hash of part as a leaf of its part set's merkle
+// tree, as merkle.HashFromByteSlices hashes leaves.
+func partLeafHash(part []byte) []byte {
+	h := tmhash.New()
+	h.Write([]byte{0})
+	h.Write(part)
+	return h.Sum(nil)
+}
+
+// rootFromLeafHashes returns the root merkle.HashFromByteSlices computes
+// over the leaves whose hashes are given.
+func rootFromLeafHashes(leaves [][]byte) []byte {
+	switch len(leaves) {
+	case 0:
+		return tmhash.Sum(nil)
+	case 1:
+		return leaves[0]
+	}
+	// The left subtree holds the largest power of 2 of leaves that is
+	// less than all of them
+	split := 1 << (bits.Len(uint(len(leaves)-1)) - 1)
+	left, right := rootFromLeafHashes(leaves[:split]), rootFromLeafHashes(leaves[split:])
+	return tmhash.Sum(append(append([]byte{1}, left...), right...))
+}
@@ -0,0 +1,116 @@
+package stateless
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/tendermint/tendermint/crypto/merkle"
+	coregrpc "github.com/tendermint/tendermint/rpc/grpc"
+	"github.com/tendermint/tendermint/types"
+)
+
+// bigTestBlock returns a signed block of txs totalling size bytes.
+func bigTestBlock(t testing.TB, size int) (*types.Block, *types.Commit, *types.ValidatorSet) {
+	t.Helper()
+	txs := make([]types.Tx, size/(64<<10))
+	for i := range txs {
+		txs[i] = bytes.Repeat([]byte{byte(i)}, 64<<10)
+	}
+	return signedTestBlock(t, txs...)
+}
+
+// streamOf returns a recv function streaming block's parts as core does,
+// with the commit and validator set on the first, each part taking
+// latency to arrive.
+func streamOf(t testing.TB, block *types.Block, commit *types.Commit, validators *types.ValidatorSet, latency time.Duration) func() (streamedBlockPart, error) {
+	t.Helper()
+	parts, _ := blockParts(t, block, types.BlockPartSizeBytes)
+	validatorsProto, err := validators.ToProto()
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := 0
+	return func() (streamedBlockPart, error) {
+		if next == len(parts) {
+			return nil, io.EOF
+		}
+		// Spin rather than sleep, which would wait at least a scheduler
+		// tick, far longer than a part takes on a fast link
+		for start := time.Now(); time.Since(start) < latency; {
+		}
+		resp := &coregrpc.StreamedBlockByHeightResponse{BlockPart: parts[next], IsLast: next == len(parts)-1}
+		if next == 0 {
+			resp.Commit, resp.ValidatorSet = commit.ToProto(), validatorsProto
+		}
+		next++
+		return resp, nil
+	}
+}
+
+func TestRootFromLeafHashes(t *testing.T) {
+	for n := range 40 {
+		parts := make([][]byte, n)
+		leaves := make([][]byte, n)
+		for i := range parts {
+			parts[i] = []byte{byte(i), byte(n)}
+			leaves[i] = partLeafHash(parts[i])
+		}
+		if got, want := rootFromLeafHashes(leaves), merkle.HashFromByteSlices(parts); !bytes.Equal(got, want) {
+			t.Errorf("%d parts: root %X, want %X", n, got, want)
+		}
+	}
+}
+
+func TestReceivers(t *testing.T) {
+	block, commit, validators := bigTestBlock(t, 1<<20)
+	for name, receive := range map[string]receiveFunc{
+		"sequential": receiveBlock,
+		"pipelined":  receiveBlockPipelined,
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			got, err := receive(ctx, cancel, 0, streamOf(t, block, commit, validators, 0))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got.Header.Hash(), block.Hash()) {
+				t.Errorf("received block %X, want %X", got.Header.Hash(), block.Hash())
+			}
+			if len(got.Data.Txs) != len(block.Txs) {
+				t.Errorf("received %d txs, want %d", len(got.Data.Txs), len(block.Txs))
+			}
+		})
+	}
+}
+
+// BenchmarkReassembly receives an 8 MiB block whose parts each take 100µs
+// to arrive, reassembling it after the last part, as receiveBlock does,
+// or while the parts arrive, as receiveBlockPipelined does.
+func BenchmarkReassembly(b *testing.B) {
+	block, commit, validators := bigTestBlock(b, 8<<20)
+	for _, bc := range []struct {
+		name    string
+		receive receiveFunc
+	}{
+		{"sequential", receiveBlock},
+		{"pipelined", receiveBlockPipelined},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				b.StopTimer()
+				recv := streamOf(b, block, commit, validators, 100*time.Microsecond)
+				ctx, cancel := context.WithCancel(context.Background())
+				b.StartTimer()
+				if _, err := bc.receive(ctx, cancel, 0, recv); err != nil {
+					b.Fatal(err)
+				}
+				cancel()
+			}
+		})
+	}
+}
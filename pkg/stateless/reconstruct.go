@@ -0,0 +1,153 @@
+package stateless
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/celestiaorg/celestia-app/v3/pkg/appconsts"
+	"github.com/celestiaorg/celestia-app/v3/pkg/da"
+	"github.com/celestiaorg/celestia-node/share"
+	libsquare "github.com/celestiaorg/go-square/v2"
+	libshare "github.com/celestiaorg/go-square/v2/share"
+	"github.com/celestiaorg/nmt"
+	"github.com/celestiaorg/rsmt2d"
+)
+
+// Reconstruct rebuilds the ExtendedDataSquare committed to by dah from
+// shares, the cells of the square in row-major order with nil for every
+// missing share. The square is repaired with Reed-Solomon against the DAH
+// roots, which fails with rsmt2d.ErrUnrepairableDataSquare if too few
+// shares are available. It repairs with celestia-app's codec and trees,
+// as NewExtender(appconsts.LatestVersion).Reconstruct(shares, dah).
+func Reconstruct(shares [][]byte, dah *da.DataAvailabilityHeader) (*rsmt2d.ExtendedDataSquare, error) {
+	return NewExtender(appconsts.LatestVersion).Reconstruct(shares, dah)
+}
+
+// Reconstruct is like the package's Reconstruct, but repairs the square
+// with e's codec and builds its trees under e's NMT options, so that a
+// DAH e computed is matched. The app version doesn't matter.
+func (e *Extender) Reconstruct(shares [][]byte, dah *da.DataAvailabilityHeader) (*rsmt2d.ExtendedDataSquare, error) {
+	width := len(dah.RowRoots)
+	if len(shares) != width*width {
+		return nil, fmt.Errorf("got %d shares, expected %d for a %d-wide square", len(shares), width*width, width)
+	}
+	if !libsquare.IsPowerOfTwo(width) || width < 2 {
+		return nil, fmt.Errorf("invalid square width %d", width)
+	}
+	available := false
+	for _, sh := range shares {
+		if sh != nil {
+			available = true
+			break
+		}
+	}
+	if !available {
+		return nil, rsmt2d.ErrUnrepairableDataSquare
+	}
+
+	eds, err := rsmt2d.ImportExtendedDataSquare(shares,
+		e.codec,
+		e.treeConstructor(uint64(width/2)))
+	if err != nil {
+		return nil, err
+	}
+	if err := eds.Repair(dah.RowRoots, dah.ColumnRoots); err != nil {
+		var byzantine *rsmt2d.ErrByzantineData
+		if errors.As(err, &byzantine) {
+			return nil, fmt.Errorf("shares do not match the DAH: %w", err)
+		}
+		return nil, err
+	}
+
+	repaired, err := da.NewDataAvailabilityHeader(eds)
+	if err != nil {
+		return nil, err
+	}
+	if !repaired.Equals(dah) {
+		return nil, fmt.Errorf("reconstructed data root %X does not match expected data root %X", repaired.Hash(), dah.Hash())
+	}
+	return eds, nil
+}
+
+// ProvenShare is the share at (Row, Col) of an extended square with an NMT
+// inclusion proof against the root of its row, or of its column if Axis
+// is rsmt2d.Col.
+type ProvenShare struct {
+	Row   uint
+	Col   uint
+	Axis  rsmt2d.Axis
+	Share []byte
+	Proof *nmt.Proof
+}
+
+// verify checks the share's proof against the root of its row or column in
+// dah. Shares of the original data square are proven under their own
+// namespace, all others under the parity namespace.
+func (s *ProvenShare) verify(dah *da.DataAvailabilityHeader) error {
+	width := uint(len(dah.RowRoots))
+	if s.Row >= width || s.Col >= width {
+		return fmt.Errorf("cell (%d, %d) out of range for %d-wide square", s.Row, s.Col, width)
+	}
+	if len(s.Share) != libshare.ShareSize {
+		return fmt.Errorf("share is %d bytes, expected %d", len(s.Share), libshare.ShareSize)
+	}
+	if s.Proof == nil {
+		return errors.New("share has no proof")
+	}
+	ns := libshare.ParitySharesNamespace.Bytes()
+	if s.Row < width/2 && s.Col < width/2 {
+		ns = s.Share[:libshare.NamespaceSize]
+	}
+	root, index := dah.RowRoots[s.Row], s.Col
+	if s.Axis == rsmt2d.Col {
+		root, index = dah.ColumnRoots[s.Col], s.Row
+	}
+	if s.Proof.Start() != int(index) || s.Proof.End() != int(index)+1 {
+		return fmt.Errorf("proof covers shares %d to %d, expected share %d", s.Proof.Start(), s.Proof.End(), index)
+	}
+	if !s.Proof.VerifyInclusion(share.NewSHA256Hasher(), ns, [][]byte{s.Share}, root) {
+		return fmt.Errorf("NMT proof does not verify against %s root %X", s.Axis, root)
+	}
+	return nil
+}
+
+// ReconstructFromProofs rebuilds the ExtendedDataSquare committed to by dah
+// from shares proven against its roots, as a light client sampling the
+// square would collect them. Every proof is verified before its share is
+// placed in the square, and the square is then repaired like Reconstruct
+// does. It returns the square and the number of distinct verified shares
+// it was rebuilt from. With fewer shares than the original data square
+// holds, reconstruction is impossible and rsmt2d.ErrUnrepairableDataSquare
+// is returned without trying. It is
+// NewExtender(appconsts.LatestVersion).ReconstructFromProofs(shares, dah).
+func ReconstructFromProofs(shares []ProvenShare, dah *da.DataAvailabilityHeader) (*rsmt2d.ExtendedDataSquare, int, error) {
+	return NewExtender(appconsts.LatestVersion).ReconstructFromProofs(shares, dah)
+}
+
+// ReconstructFromProofs is like the package's ReconstructFromProofs, but
+// repairs the square like e.Reconstruct. The proofs are still verified
+// against celestia-app's trees.
+func (e *Extender) ReconstructFromProofs(shares []ProvenShare, dah *da.DataAvailabilityHeader) (*rsmt2d.ExtendedDataSquare, int, error) {
+	width := len(dah.RowRoots)
+	cells := make([][]byte, width*width)
+	verified := 0
+	for i := range shares {
+		s := &shares[i]
+		if err := s.verify(dah); err != nil {
+			return nil, 0, fmt.Errorf("share at (%d, %d): %w", s.Row, s.Col, err)
+		}
+		if cell := &cells[int(s.Row)*width+int(s.Col)]; *cell == nil {
+			*cell = s.Share
+			verified++
+		}
+	}
+	if needed := width * width / 4; verified < needed {
+		return nil, verified, fmt.Errorf("%w: %d verified shares, at least %d are needed",
+			rsmt2d.ErrUnrepairableDataSquare, verified, needed)
+	}
+	eds, err := e.Reconstruct(cells, dah)
+	if err != nil {
+		return nil, verified, err
+	}
+	return eds, verified, nil
+}
@@ -0,0 +1,54 @@
+package stateless
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/celestiaorg/celestia-app/v3/pkg/da"
+	"github.com/celestiaorg/nmt"
+	"github.com/celestiaorg/rsmt2d"
+	"github.com/tendermint/tendermint/types"
+)
+
+// firstQuadrant returns the cells of eds in row-major order with only
+// those of the original data square kept, nil for all others.
+func firstQuadrant(eds *rsmt2d.ExtendedDataSquare) [][]byte {
+	width := eds.Width()
+	shares := make([][]byte, width*width)
+	for row := range width / 2 {
+		for col := range width / 2 {
+			shares[row*width+col] = eds.GetCell(row, col)
+		}
+	}
+	return shares
+}
+
+// TestExtenderReconstruct checks that an Extender repairs the squares it
+// extends from their first quadrant, under any NMT options.
+func TestExtenderReconstruct(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	data := &types.Data{Txs: randomTxs(t, rng)}
+	for _, tc := range []struct {
+		name    string
+		options []nmt.Option
+	}{
+		{"default trees", nil},
+		{"max namespace kept", []nmt.Option{nmt.IgnoreMaxNamespace(false)}},
+		{"8-byte namespaces", []nmt.Option{nmt.NamespaceIDSize(8)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			extender := NewExtender(3, tc.options...)
+			eds, err := extender.Extend(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			dah, err := da.NewDataAvailabilityHeader(eds)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := extender.Reconstruct(firstQuadrant(eds), &dah); err != nil {
+				t.Fatalf("reconstructing from the first quadrant: %v", err)
+			}
+		})
+	}
+}
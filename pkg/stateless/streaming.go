@@ -0,0 +1,115 @@
+package stateless
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/celestiaorg/celestia-node/share"
+	libsquare "github.com/celestiaorg/go-square/v2"
+	libshare "github.com/celestiaorg/go-square/v2/share"
+	"github.com/celestiaorg/go-square/v2/tx"
+	"github.com/celestiaorg/nmt"
+	"github.com/celestiaorg/rsmt2d"
+)
+
+// StreamingExtender builds a block's data square as its transactions
+// arrive, instead of from the full transaction list at once. Each
+// transaction is placed in the square when it is added, so one that
+// ExtendBlock would reject is reported by AddTx.
+//
+// The square returned by Finalize is the one ExtendBlock returns for the
+// same transactions in the same order.
+type StreamingExtender struct {
+	builder    *libsquare.Builder
+	appVersion uint64
+	options    []nmt.Option
+	// numTxs and firstBlob track what has been added so far, firstBlob
+	// being the index of the first blob transaction or -1.
+	numTxs    int
+	firstBlob int
+	finalized bool
+}
+
+// NewStreamingExtender returns a StreamingExtender for a block of the given
+// app version.
+func NewStreamingExtender(appVersion uint64, options ...nmt.Option) (*StreamingExtender, error) {
+	builder, err := libsquare.NewBuilder(
+		SquareSizeUpperBound(appVersion),
+		SubtreeRootThreshold(appVersion),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &StreamingExtender{builder: builder, appVersion: appVersion, options: options, firstBlob: -1}, nil
+}
+
+// AddTx places the next transaction of the block in the square.
+func (s *StreamingExtender) AddTx(rawTx []byte) error {
+	if s.finalized {
+		return errors.New("streaming extender is finalized")
+	}
+	idx := s.numTxs
+	if len(rawTx) == 0 {
+		return fmt.Errorf("tx %d: empty transaction", idx)
+	}
+	blobTx, isBlobTx, err := tx.UnmarshalBlobTx(rawTx)
+	switch {
+	case isBlobTx && err != nil:
+		return fmt.Errorf("tx %d: malformed blob transaction: %w", idx, err)
+	case isBlobTx && len(blobTx.Tx) == 0:
+		return fmt.Errorf("tx %d: blob transaction has an empty inner transaction", idx)
+	case isBlobTx:
+		if !s.builder.AppendBlobTx(blobTx) {
+			return fmt.Errorf("tx %d: not enough space to append blob tx", idx)
+		}
+		if s.firstBlob == -1 {
+			s.firstBlob = idx
+		}
+	case s.firstBlob != -1:
+		return fmt.Errorf("tx %d: normal transaction after blob transaction %d", idx, s.firstBlob)
+	default:
+		if !s.builder.AppendTx(rawTx) {
+			return fmt.Errorf("tx %d: not enough space to append tx", idx)
+		}
+	}
+	s.numTxs++
+	return nil
+}
+
+// Extend builds and extends the square from the transactions added so far.
+// It may be called any number of times before Finalize.
+func (s *StreamingExtender) Extend() (*rsmt2d.ExtendedDataSquare, error) {
+	if s.numTxs == 0 {
+		return share.EmptyEDS(), nil
+	}
+	square, err := s.builder.Export()
+	if err != nil {
+		return nil, err
+	}
+	return ExtendShares(libshare.ToBytes(square), s.appVersion, s.options...)
+}
+
+// Finalize extends the square from all added transactions. No
+// transactions can be added afterwards.
+func (s *StreamingExtender) Finalize() (*rsmt2d.ExtendedDataSquare, error) {
+	s.finalized = true
+	return s.Extend()
+}
+
+// ExtendStream feeds every transaction received on txs to a
+// StreamingExtender and finalizes it once txs is closed.
+func ExtendStream(txs <-chan []byte, appVersion uint64, options ...nmt.Option) (*rsmt2d.ExtendedDataSquare, error) {
+	s, err := NewStreamingExtender(appVersion, options...)
+	if err != nil {
+		return nil, err
+	}
+	for rawTx := range txs {
+		if err := s.AddTx(rawTx); err != nil {
+			// drain so the sender is not left blocked
+			for range txs {
+			}
+			return nil, err
+		}
+	}
+	return s.Finalize()
+}
@@ -0,0 +1,84 @@
+package stateless
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+// TestStreamingExtenderMatchesExtendBlock checks that the square a
+// StreamingExtender finalizes, with or without intermediate Extend calls,
+// and the one ExtendStream returns are ExtendBlock's for the same
+// transactions.
+func TestStreamingExtenderMatchesExtendBlock(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	for i := range 50 {
+		txs := randomTxs(t, rng)
+		for _, version := range []uint64{1, 2, 3} {
+			eds, err := ExtendBlock(&types.Data{Txs: txs}, version)
+			if err != nil {
+				t.Fatalf("block %d, app version %d: %v", i, version, err)
+			}
+			want := dataRoot(t, eds)
+
+			s, err := NewStreamingExtender(version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for j, rawTx := range txs {
+				if err := s.AddTx(rawTx); err != nil {
+					t.Fatalf("block %d, app version %d: %v", i, version, err)
+				}
+				if j%3 == 0 {
+					if _, err := s.Extend(); err != nil {
+						t.Fatalf("block %d, app version %d: extending after tx %d: %v", i, version, j, err)
+					}
+				}
+			}
+			finalized, err := s.Finalize()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := dataRoot(t, finalized); !bytes.Equal(got, want) {
+				t.Errorf("block %d, app version %d: Finalize data root %X, ExtendBlock %X", i, version, got, want)
+			}
+
+			ch := make(chan []byte)
+			go func() {
+				for _, rawTx := range txs {
+					ch <- rawTx
+				}
+				close(ch)
+			}()
+			streamed, err := ExtendStream(ch, version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := dataRoot(t, streamed); !bytes.Equal(got, want) {
+				t.Errorf("block %d, app version %d: ExtendStream data root %X, ExtendBlock %X", i, version, got, want)
+			}
+		}
+	}
+}
+
+func TestStreamingExtenderRejects(t *testing.T) {
+	s, err := NewStreamingExtender(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddTx([]byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddTx(nil); err == nil || !strings.Contains(err.Error(), "tx 1: empty transaction") {
+		t.Errorf("got error %v, want one naming the empty tx 1", err)
+	}
+	if _, err := s.Finalize(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddTx([]byte("b")); err == nil {
+		t.Error("AddTx after Finalize succeeded")
+	}
+}
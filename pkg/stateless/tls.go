@@ -0,0 +1,37 @@
+package stateless
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// WithTLS returns a dial option for NewCoreAccessor that connects over TLS
+// instead of the default insecure transport. The server certificate is
+// verified against the PEM-encoded CA certificates in caCertFile, or the
+// system pool if caCertFile is empty. skipVerify disables verification
+// altogether and is only meant for development nodes with self-signed
+// certificates.
+func WithTLS(caCertFile string, skipVerify bool) (grpc.DialOption, error) {
+	cfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: skipVerify,
+	}
+	if caCertFile != "" {
+		pem, err := os.ReadFile(caCertFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("no PEM certificates found in " + caCertFile)
+		}
+		cfg.RootCAs = pool
+	}
+	return grpc.WithTransportCredentials(credentials.NewTLS(cfg)), nil
+}
@@ -0,0 +1,40 @@
+package stateless
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/celestiaorg/go-square/v2/tx"
+)
+
+// validateTxs scans block transactions for entries that square
+// construction would reject with an unclear error: empty transactions,
+// blob transactions that fail to decode or carry no inner transaction, and
+// blob transactions placed before a normal transaction. Every problem
+// found is reported with the index of the offending transaction.
+func validateTxs(txs [][]byte) error {
+	var (
+		errs      []error
+		firstBlob = -1
+	)
+	for i, rawTx := range txs {
+		if len(rawTx) == 0 {
+			errs = append(errs, fmt.Errorf("tx %d: empty transaction", i))
+			continue
+		}
+		blobTx, isBlobTx, err := tx.UnmarshalBlobTx(rawTx)
+		switch {
+		case isBlobTx && err != nil:
+			errs = append(errs, fmt.Errorf("tx %d: malformed blob transaction: %w", i, err))
+		case isBlobTx && len(blobTx.Tx) == 0:
+			errs = append(errs, fmt.Errorf("tx %d: blob transaction has an empty inner transaction", i))
+		case isBlobTx:
+			if firstBlob == -1 {
+				firstBlob = i
+			}
+		case firstBlob != -1:
+			errs = append(errs, fmt.Errorf("tx %d: normal transaction after blob transaction %d", i, firstBlob))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,81 @@
+package stateless
+
+import (
+	"strings"
+	"testing"
+
+	blobv1 "github.com/celestiaorg/go-square/v2/proto/blob/v1"
+	libshare "github.com/celestiaorg/go-square/v2/share"
+	"github.com/celestiaorg/go-square/v2/tx"
+	"github.com/tendermint/tendermint/types"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestValidateTxs(t *testing.T) {
+	blob, err := libshare.NewV0Blob(libshare.MustNewV0Namespace([]byte("validate")), []byte("data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	blobTx, err := tx.MarshalBlobTx([]byte("inner"), blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	emptyInner, err := tx.MarshalBlobTx(nil, blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A blob transaction without blobs
+	malformed, err := proto.Marshal(&blobv1.BlobTx{Tx: []byte("inner"), TypeId: tx.ProtoBlobTxTypeID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		txs  [][]byte
+		// want are substrings of the error, none if it is nil.
+		want []string
+	}{
+		{"no txs", nil, nil},
+		{"valid", [][]byte{[]byte("a"), []byte("b"), blobTx}, nil},
+		{"empty tx", [][]byte{[]byte("a"), {}, blobTx}, []string{"tx 1: empty transaction"}},
+		{"empty first tx", [][]byte{{}}, []string{"tx 0: empty transaction"}},
+		{"empty inner tx", [][]byte{emptyInner}, []string{"tx 0: blob transaction has an empty inner transaction"}},
+		{"malformed blob tx", [][]byte{malformed}, []string{"tx 0: malformed blob transaction"}},
+		{"normal after blob", [][]byte{blobTx, []byte("a")}, []string{"tx 1: normal transaction after blob transaction 0"}},
+		{"every problem", [][]byte{{}, blobTx, {}, []byte("a")}, []string{
+			"tx 0: empty transaction",
+			"tx 2: empty transaction",
+			"tx 3: normal transaction after blob transaction 1",
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateTxs(tc.txs)
+			if len(tc.want) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("no error, want %q", tc.want)
+			}
+			for _, want := range tc.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+// TestExtendEmptyTx checks that a block with an empty transaction fails to
+// extend with the transaction's index rather than a square construction
+// error.
+func TestExtendEmptyTx(t *testing.T) {
+	data := &types.Data{Txs: types.Txs{types.Tx("a"), types.Tx{}}}
+	_, err := ExtendBlock(data, 3)
+	if err == nil || !strings.Contains(err.Error(), "tx 1: empty transaction") {
+		t.Fatalf("got error %v, want one naming the empty tx 1", err)
+	}
+}
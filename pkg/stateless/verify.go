@@ -0,0 +1,176 @@
+package stateless
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/celestiaorg/celestia-app/v3/pkg/da"
+	tmmath "github.com/tendermint/tendermint/libs/math"
+	"github.com/tendermint/tendermint/types"
+)
+
+// DefaultTrustLevel is the fraction of a validator set's voting power that
+// VerifyCommit requires more than to have signed a commit: 2/3, as in
+// consensus.
+var DefaultTrustLevel = tmmath.Fraction{Numerator: 2, Denominator: 3}
+
+// CommitPower is the voting power of a validator set that signed a commit
+// for its block.
+type CommitPower struct {
+	Signed int64 `json:"signed"`
+	Total  int64 `json:"total"`
+}
+
+func (p *CommitPower) String() string {
+	return fmt.Sprintf("signed voting power %d/%d (%.2f%%)", p.Signed, p.Total, 100*float64(p.Signed)/float64(p.Total))
+}
+
+// VerifyCommit checks that block.Commit commits to block.Header and is
+// signed by more than 2/3 of the voting power of block.ValidatorSet, which
+// must pass VerifyValidatorSet first. Every signature present in the commit
+// is checked, so that the returned error can list each validator whose
+// signature is invalid along with the voting power that did sign.
+func VerifyCommit(block *SignedBlock) error {
+	_, err := VerifyCommitTrustLevel(block, DefaultTrustLevel)
+	return err
+}
+
+// VerifyCommitTrustLevel checks block.Commit like VerifyCommit, but
+// requires signatures from more than trustLevel of the voting power rather
+// than 2/3, e.g. 1/3 as a light client skipping headers does. trustLevel
+// must be in (0, 1]. The voting power that signed is returned whether or not
+// the commit passes, once the signatures have been checked.
+func VerifyCommitTrustLevel(block *SignedBlock, trustLevel tmmath.Fraction) (*CommitPower, error) {
+	if trustLevel.Numerator == 0 || trustLevel.Numerator > trustLevel.Denominator {
+		return nil, fmt.Errorf("trust level %s must be in (0, 1]", trustLevel)
+	}
+	h, commit, vals := block.Header, block.Commit, block.ValidatorSet
+	if h == nil || commit == nil || vals == nil {
+		return nil, fmt.Errorf("%w: block is missing its header, commit or validator set", ErrCommitVerification)
+	}
+	if err := VerifyValidatorSet(vals, h); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCommitVerification, err)
+	}
+	if commit.Height != h.Height {
+		return nil, fmt.Errorf("%w: commit is for height %d, header is at height %d", ErrCommitVerification, commit.Height, h.Height)
+	}
+	if !bytes.Equal(commit.BlockID.Hash, h.Hash()) {
+		return nil, fmt.Errorf("%w: commit is for block %X, header hashes to %X", ErrCommitVerification, commit.BlockID.Hash, h.Hash())
+	}
+	if len(commit.Signatures) != vals.Size() {
+		return nil, fmt.Errorf("%w: commit has %d signatures for %d validators", ErrCommitVerification, len(commit.Signatures), vals.Size())
+	}
+
+	var (
+		signed int64
+		failed []string
+	)
+	for idx, sig := range commit.Signatures {
+		if sig.Absent() {
+			continue
+		}
+		val := vals.Validators[idx]
+		if !bytes.Equal(sig.ValidatorAddress, val.Address) {
+			failed = append(failed, fmt.Sprintf("%X (signature %d is from %X)", val.Address, idx, sig.ValidatorAddress))
+			continue
+		}
+		if !val.PubKey.VerifySignature(commit.VoteSignBytes(h.ChainID, int32(idx)), sig.Signature) {
+			failed = append(failed, fmt.Sprintf("%X (invalid signature)", val.Address))
+			continue
+		}
+		// Only votes for the block count, nil votes are merely well-formed
+		if sig.ForBlock() {
+			signed += val.VotingPower
+		}
+	}
+
+	power := &CommitPower{Signed: signed, Total: vals.TotalVotingPower()}
+	needed := powerNeeded(power.Total, trustLevel)
+	if len(failed) > 0 || signed <= needed {
+		msg := fmt.Sprintf("signed voting power %d of %d, need more than %d", signed, power.Total, needed)
+		if len(failed) > 0 {
+			msg += "; failed validators: " + strings.Join(failed, ", ")
+		}
+		return power, fmt.Errorf("%w: %s", ErrCommitVerification, msg)
+	}
+	return power, nil
+}
+
+// powerNeeded returns trustLevel of total, rounded down. The product of
+// total and the numerator may not fit in an int64.
+func powerNeeded(total int64, trustLevel tmmath.Fraction) int64 {
+	needed := new(big.Int).Mul(big.NewInt(total), new(big.Int).SetUint64(trustLevel.Numerator))
+	return needed.Quo(needed, new(big.Int).SetUint64(trustLevel.Denominator)).Int64()
+}
+
+// VerifyValidatorSet checks that vals is well formed and the set h names:
+// it has validators, none of them twice or with negative voting power,
+// their total voting power is positive and within the bound Tendermint
+// allows, and the set hashes to h.ValidatorsHash. A malformed set is
+// rejected here rather than failing, or panicking, in commit verification.
+func VerifyValidatorSet(vals *types.ValidatorSet, h *types.Header) error {
+	if vals == nil || len(vals.Validators) == 0 {
+		return fmt.Errorf("%w: no validators", ErrInvalidValidatorSet)
+	}
+	var total int64
+	seen := make(map[string]int, len(vals.Validators))
+	for i, val := range vals.Validators {
+		if val == nil {
+			return fmt.Errorf("%w: validator %d is nil", ErrInvalidValidatorSet, i)
+		}
+		if j, ok := seen[string(val.Address)]; ok {
+			return fmt.Errorf("%w: validator %X appears twice, at %d and %d", ErrInvalidValidatorSet, val.Address, j, i)
+		}
+		seen[string(val.Address)] = i
+		if val.VotingPower < 0 {
+			return fmt.Errorf("%w: validator %X has negative voting power %d", ErrInvalidValidatorSet, val.Address, val.VotingPower)
+		}
+		total += val.VotingPower
+		if total > types.MaxTotalVotingPower {
+			return fmt.Errorf("%w: total voting power exceeds the maximum %d", ErrInvalidValidatorSet, types.MaxTotalVotingPower)
+		}
+	}
+	if total == 0 {
+		return fmt.Errorf("%w: %d validators without voting power", ErrInvalidValidatorSet, len(vals.Validators))
+	}
+	if !bytes.Equal(vals.Hash(), h.ValidatorsHash) {
+		return fmt.Errorf("%w: hash %X does not match header validators hash %X", ErrInvalidValidatorSet, vals.Hash(), h.ValidatorsHash)
+	}
+	return nil
+}
+
+// VerifyHeader checks that the parts of block are consistent with its
+// header: the validator set passes VerifyValidatorSet, the
+// commit is for the header's height and hash, and dah, computed from the
+// block's data, hashes to the header's DataHash. Signatures are not
+// checked, see VerifyCommit. Every mismatch is reported in the returned
+// error rather than only the first.
+func VerifyHeader(block *SignedBlock, dah *da.DataAvailabilityHeader) error {
+	h := block.Header
+	if h == nil {
+		return errors.New("block is missing its header")
+	}
+	var errs []error
+	if vals := block.ValidatorSet; vals == nil {
+		errs = append(errs, fmt.Errorf("%w: block is missing its validator set", ErrInvalidValidatorSet))
+	} else if err := VerifyValidatorSet(vals, h); err != nil {
+		errs = append(errs, err)
+	}
+	if commit := block.Commit; commit == nil {
+		errs = append(errs, fmt.Errorf("%w: block is missing its commit", ErrCommitVerification))
+	} else {
+		if commit.Height != h.Height {
+			errs = append(errs, fmt.Errorf("%w: commit is for height %d, header is at height %d", ErrCommitVerification, commit.Height, h.Height))
+		}
+		if !bytes.Equal(commit.BlockID.Hash, h.Hash()) {
+			errs = append(errs, fmt.Errorf("%w: commit is for block %X, header hashes to %X", ErrCommitVerification, commit.BlockID.Hash, h.Hash()))
+		}
+	}
+	if err := VerifyDAH(h, dah); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,73 @@
+package stateless
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestVerifyValidatorSet(t *testing.T) {
+	vals, _ := types.RandValidatorSet(4, 10)
+	// copyOf returns a copy of vals's validators, changed by edit, as a set
+	// that hasn't been through NewValidatorSet's checks.
+	copyOf := func(edit func(validators []*types.Validator) []*types.Validator) *types.ValidatorSet {
+		validators := make([]*types.Validator, len(vals.Validators))
+		for i, val := range vals.Validators {
+			validators[i] = val.Copy()
+		}
+		return &types.ValidatorSet{Validators: edit(validators)}
+	}
+
+	for _, tc := range []struct {
+		name string
+		vals *types.ValidatorSet
+		// want is a substring of the error, empty if it is nil.
+		want string
+	}{
+		{"valid", vals, ""},
+		{"nil set", nil, "no validators"},
+		{"empty set", &types.ValidatorSet{}, "no validators"},
+		{"nil validator", copyOf(func(v []*types.Validator) []*types.Validator {
+			v[2] = nil
+			return v
+		}), "validator 2 is nil"},
+		{"duplicate validator", copyOf(func(v []*types.Validator) []*types.Validator {
+			return append(v, v[1].Copy())
+		}), "appears twice, at 1 and 4"},
+		{"negative voting power", copyOf(func(v []*types.Validator) []*types.Validator {
+			v[0].VotingPower = -1
+			return v
+		}), "negative voting power -1"},
+		{"no voting power", copyOf(func(v []*types.Validator) []*types.Validator {
+			for _, val := range v {
+				val.VotingPower = 0
+			}
+			return v
+		}), "4 validators without voting power"},
+		{"voting power overflow", copyOf(func(v []*types.Validator) []*types.Validator {
+			v[0].VotingPower = types.MaxTotalVotingPower
+			return v
+		}), "total voting power exceeds the maximum"},
+		{"another set", copyOf(func(v []*types.Validator) []*types.Validator {
+			return v[:3]
+		}), "does not match header validators hash"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := VerifyValidatorSet(tc.vals, &types.Header{ValidatorsHash: vals.Hash()})
+			if tc.want == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidValidatorSet) {
+				t.Fatalf("got error %v, want %v", err, ErrInvalidValidatorSet)
+			}
+			if !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("error %q does not contain %q", err, tc.want)
+			}
+		})
+	}
+}